@@ -2,6 +2,13 @@ module github.com/arewm/ai-shell
 
 go 1.25.5
 
+require (
+	github.com/spf13/viper v1.21.0
+	github.com/tailscale/hujson v0.0.0-20250605163823-992244df8c5a
+	go.yaml.in/yaml/v3 v3.0.4
+	golang.org/x/sys v0.40.0
+)
+
 require (
 	github.com/fsnotify/fsnotify v1.9.0 // indirect
 	github.com/go-viper/mapstructure/v2 v2.4.0 // indirect
@@ -13,10 +20,6 @@ require (
 	github.com/spf13/cast v1.10.0 // indirect
 	github.com/spf13/cobra v1.10.2 // indirect
 	github.com/spf13/pflag v1.0.10 // indirect
-	github.com/spf13/viper v1.21.0 // indirect
 	github.com/subosito/gotenv v1.6.0 // indirect
-	github.com/tailscale/hujson v0.0.0-20250605163823-992244df8c5a // indirect
-	go.yaml.in/yaml/v3 v3.0.4 // indirect
-	golang.org/x/sys v0.40.0 // indirect
 	golang.org/x/text v0.33.0 // indirect
 )
@@ -0,0 +1,158 @@
+// Package approval holds commands run in the container until the user
+// approves them on the host. Shims in front of the matching binaries send
+// each command line over a unix socket mounted into the container; the
+// Server matches it against the policy and waits for the decision of an
+// approver (ai-shell approve) connected to its control socket.
+package approval
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"github.com/arewm/ai-shell/internal/config"
+)
+
+const (
+	// MountTarget is where the container directory is mounted
+	MountTarget = "/run/ai-shell/approval"
+	// ShimPath is the shim in ai-shell's images the bin directory links to
+	ShimPath = "/usr/local/bin/approval-shim"
+
+	containerDir  = "container"
+	socketName    = "approval.sock"
+	controlSocket = "control.sock"
+)
+
+// Request is a command line a shim asks about.
+type Request struct {
+	Argv []string `json:"argv"`
+	Cwd  string   `json:"cwd"`
+	// StdinPipe is set when the command reads from a pipe
+	StdinPipe bool `json:"stdin_pipe,omitempty"`
+}
+
+// String is the command line, for prompts and messages.
+func (r Request) String() string {
+	return strings.Join(r.Argv, " ")
+}
+
+// Response is the decision sent back to the shim.
+type Response struct {
+	Allow  bool   `json:"allow"`
+	Reason string `json:"reason,omitempty"`
+}
+
+// RuntimeDir is the directory of the sockets of a project's container. It is
+// stable, so that an approver finds it and a restarted container its mount.
+func RuntimeDir(project string) string {
+	base := os.Getenv("XDG_RUNTIME_DIR")
+	if base == "" {
+		base = TempBase()
+	}
+	return filepath.Join(base, "ai-shell", project)
+}
+
+// TempBase holds the runtime directories without XDG_RUNTIME_DIR.
+func TempBase() string {
+	return filepath.Join(os.TempDir(), fmt.Sprintf("ai-shell-%d", os.Getuid()))
+}
+
+// ContainerDir is the part of dir that is mounted into the container.
+func ContainerDir(dir string) string {
+	return filepath.Join(dir, containerDir)
+}
+
+// WriteShims links the binaries that need approval to the shim, in the bin
+// directory the container puts first in its PATH.
+func WriteShims(dir string, binaries []string) error {
+	bin := filepath.Join(ContainerDir(dir), "bin")
+	if err := os.RemoveAll(bin); err != nil {
+		return err
+	}
+	if err := os.MkdirAll(bin, 0700); err != nil {
+		return err
+	}
+	for _, name := range binaries {
+		if err := os.Symlink(ShimPath, filepath.Join(bin, name)); err != nil {
+			return fmt.Errorf("failed to create the approval shim for %s: %w", name, err)
+		}
+	}
+	return nil
+}
+
+// Match returns the first rule req falls under.
+func Match(rules []config.CommandRule, workspace string, req Request) (config.CommandRule, bool) {
+	if len(req.Argv) == 0 {
+		return config.CommandRule{}, false
+	}
+	name := filepath.Base(req.Argv[0])
+	for _, r := range rules {
+		if r.Command() != name || !matchArgs(r.Args(), req.Argv[1:]) {
+			continue
+		}
+		if r.FromPipe && !req.StdinPipe {
+			continue
+		}
+		if r.OutsideWorkspace && !outsideWorkspace(workspace, req.Cwd, req.Argv[1:]) {
+			continue
+		}
+		return r, true
+	}
+	return config.CommandRule{}, false
+}
+
+// matchArgs matches the arguments against pattern, also after skipping
+// leading options and their values, so that "git push*" covers
+// git -C dir push and git --no-pager push.
+func matchArgs(pattern string, args []string) bool {
+	for i := 0; i <= len(args); i++ {
+		if globMatch(pattern, strings.Join(args[i:], " ")) {
+			return true
+		}
+		if i == len(args) || !strings.HasPrefix(args[i], "-") {
+			// Only an option can be skipped, or the value of one
+			if i == 0 || i == len(args) || !isOptionWithValue(args[i-1]) {
+				return false
+			}
+		}
+	}
+	return false
+}
+
+// isOptionWithValue reports whether a may be an option whose value is the
+// next argument.
+func isOptionWithValue(a string) bool {
+	return strings.HasPrefix(a, "-") && a != "-" && a != "--" && !strings.Contains(a, "=")
+}
+
+// globMatch matches s against a pattern where * matches anything, spaces
+// included.
+func globMatch(pattern, s string) bool {
+	parts := strings.Split(pattern, "*")
+	for i, p := range parts {
+		parts[i] = regexp.QuoteMeta(p)
+	}
+	return regexp.MustCompile("^" + strings.Join(parts, ".*") + "$").MatchString(s)
+}
+
+// outsideWorkspace reports whether an argument that is not an option names
+// a path outside the workspace.
+func outsideWorkspace(workspace, cwd string, args []string) bool {
+	for _, a := range args {
+		if a == "" || strings.HasPrefix(a, "-") {
+			continue
+		}
+		p := a
+		if !filepath.IsAbs(p) {
+			p = filepath.Join(cwd, p)
+		}
+		rel, err := filepath.Rel(workspace, filepath.Clean(p))
+		if err != nil || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,79 @@
+package approval
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/arewm/ai-shell/internal/config"
+)
+
+func TestMatch(t *testing.T) {
+	rules := []config.CommandRule{
+		{Pattern: "git push*"},
+		{Pattern: "kubectl delete *"},
+		{Pattern: "gh pr merge*"},
+		{Pattern: "rm -rf *", OutsideWorkspace: true},
+		{Pattern: "sh *", FromPipe: true},
+	}
+	tests := []struct {
+		req      Request
+		expected string
+	}{
+		{Request{Argv: []string{"git", "push", "origin", "main"}, Cwd: "/ws"}, "git push*"},
+		{Request{Argv: []string{"git", "push"}, Cwd: "/ws"}, "git push*"},
+		{Request{Argv: []string{"/usr/bin/git", "push", "--force"}, Cwd: "/ws"}, "git push*"},
+		{Request{Argv: []string{"git", "status"}, Cwd: "/ws"}, ""},
+		// Options before the subcommand
+		{Request{Argv: []string{"git", "-C", "dir", "push"}, Cwd: "/ws"}, "git push*"},
+		{Request{Argv: []string{"git", "--no-pager", "push", "origin"}, Cwd: "/ws"}, "git push*"},
+		{Request{Argv: []string{"git", "-c", "core.pager=", "--git-dir=x", "push"}, Cwd: "/ws"}, "git push*"},
+		{Request{Argv: []string{"git", "log", "--grep", "push"}, Cwd: "/ws"}, ""},
+		{Request{Argv: []string{"kubectl", "-n", "prod", "delete", "pod", "x"}, Cwd: "/ws"}, "kubectl delete *"},
+		{Request{Argv: []string{"kubectl", "delete", "pod", "x"}, Cwd: "/ws"}, "kubectl delete *"},
+		{Request{Argv: []string{"kubectl", "get", "pods"}, Cwd: "/ws"}, ""},
+		{Request{Argv: []string{"gh", "pr", "merge", "12"}, Cwd: "/ws"}, "gh pr merge*"},
+		// rm -rf only outside the workspace
+		{Request{Argv: []string{"rm", "-rf", "build"}, Cwd: "/ws"}, ""},
+		{Request{Argv: []string{"rm", "-rf", "/ws/build"}, Cwd: "/ws/src"}, ""},
+		{Request{Argv: []string{"rm", "-rf", "../.."}, Cwd: "/ws/src"}, "rm -rf *"},
+		{Request{Argv: []string{"rm", "-rf", "/etc"}, Cwd: "/ws"}, "rm -rf *"},
+		{Request{Argv: []string{"rm", "-rf", "/ws-other"}, Cwd: "/ws"}, "rm -rf *"},
+		// curl ... | sh
+		{Request{Argv: []string{"sh"}, Cwd: "/ws", StdinPipe: true}, "sh *"},
+		{Request{Argv: []string{"sh", "-s", "--", "-y"}, Cwd: "/ws", StdinPipe: true}, "sh *"},
+		{Request{Argv: []string{"sh", "build.sh"}, Cwd: "/ws"}, ""},
+		{Request{Cwd: "/ws"}, ""},
+	}
+	for _, tt := range tests {
+		rule, ok := Match(rules, "/ws", tt.req)
+		if (tt.expected != "") != ok || rule.Pattern != tt.expected {
+			t.Errorf("Input: %q, Expected: %q, Got: %q (%v)", tt.req, tt.expected, rule.Pattern, ok)
+		}
+	}
+}
+
+func TestWriteShims(t *testing.T) {
+	tmpDir, err := os.MkdirTemp("", "ai-shell-approval-test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() { _ = os.RemoveAll(tmpDir) }()
+
+	if err := WriteShims(tmpDir, []string{"git", "kubectl"}); err != nil {
+		t.Fatalf("WriteShims failed: %v", err)
+	}
+	// A new session replaces the shims
+	if err := WriteShims(tmpDir, []string{"git"}); err != nil {
+		t.Fatalf("WriteShims failed: %v", err)
+	}
+
+	bin := filepath.Join(ContainerDir(tmpDir), "bin")
+	target, err := os.Readlink(filepath.Join(bin, "git"))
+	if err != nil || target != ShimPath {
+		t.Errorf("Expected git to link to %s, Got: %q (%v)", ShimPath, target, err)
+	}
+	if _, err := os.Lstat(filepath.Join(bin, "kubectl")); !os.IsNotExist(err) {
+		t.Errorf("Expected the kubectl shim to be removed, Got: %v", err)
+	}
+}
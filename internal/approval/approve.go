@@ -0,0 +1,53 @@
+package approval
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net"
+	"path/filepath"
+	"strings"
+)
+
+// Approve connects to the server of dir as its approver and asks the user
+// about each command that waits, until the session ends.
+func Approve(dir string, in io.Reader, out io.Writer) error {
+	conn, err := net.Dial("unix", filepath.Join(dir, controlSocket))
+	if err != nil {
+		return fmt.Errorf("no ai-shell session with approval is running for this project: %w", err)
+	}
+	defer func() { _ = conn.Close() }()
+
+	fmt.Fprintln(out, "Waiting for commands to approve (Ctrl-C to stop)...")
+	answers := bufio.NewScanner(in)
+	enc := json.NewEncoder(conn)
+	dec := json.NewDecoder(conn)
+	for {
+		var p Prompt
+		if err := dec.Decode(&p); err != nil {
+			if err == io.EOF {
+				fmt.Fprintln(out, "The session ended.")
+				return nil
+			}
+			return err
+		}
+
+		fmt.Fprintf(out, "\n❓ %s\n   in %s (rule: %s)\n   Allow? [y/N] ", p.Request, p.Cwd, p.Rule)
+		if !answers.Scan() {
+			// No more input: deny rather than leave the command waiting
+			_ = enc.Encode(Decision{ID: p.ID})
+			return answers.Err()
+		}
+		answer := strings.ToLower(strings.TrimSpace(answers.Text()))
+		allow := answer == "y" || answer == "yes"
+		if err := enc.Encode(Decision{ID: p.ID, Allow: allow}); err != nil {
+			return fmt.Errorf("the session ended before the decision was sent: %w", err)
+		}
+		if allow {
+			fmt.Fprintln(out, "   ✅ Allowed")
+		} else {
+			fmt.Fprintln(out, "   ⛔ Denied")
+		}
+	}
+}
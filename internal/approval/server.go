@@ -0,0 +1,259 @@
+package approval
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"path/filepath"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/arewm/ai-shell/internal/config"
+)
+
+// Prompt is a command waiting for a decision, as sent to the approver.
+type Prompt struct {
+	ID   int    `json:"id"`
+	Rule string `json:"rule"`
+	Request
+}
+
+// Decision is the approver's answer to a prompt.
+type Decision struct {
+	ID    int  `json:"id"`
+	Allow bool `json:"allow"`
+}
+
+// Entry is a line of the audit trail.
+type Entry struct {
+	Time     time.Time `json:"time"`
+	Argv     []string  `json:"argv"`
+	Cwd      string    `json:"cwd"`
+	Rule     string    `json:"rule"`
+	Decision string    `json:"decision"` // allowed, denied or timeout
+}
+
+// Server answers the shims of a container.
+type Server struct {
+	Rules     []config.CommandRule
+	Workspace string
+	Timeout   time.Duration
+	// AuditLog is the JSON Lines file every decision is appended to
+	AuditLog string
+	// Notify tells the user that a command waits, e.g. when no approver is connected
+	Notify func(p Prompt, approver bool)
+
+	dir       string
+	listeners []net.Listener
+	wg        sync.WaitGroup
+
+	mu       sync.Mutex
+	nextID   int
+	pending  map[int]*pendingPrompt
+	approver net.Conn
+}
+
+type pendingPrompt struct {
+	prompt   Prompt
+	decision chan bool
+}
+
+// Listen creates the sockets below dir (see RuntimeDir) and serves them
+// until Close.
+func (s *Server) Listen(dir string) error {
+	if err := os.MkdirAll(ContainerDir(dir), 0700); err != nil {
+		return err
+	}
+	s.dir = dir
+	s.pending = make(map[int]*pendingPrompt)
+
+	shims, err := listenUnix(filepath.Join(ContainerDir(dir), socketName))
+	if err != nil {
+		return err
+	}
+	control, err := listenUnix(filepath.Join(dir, controlSocket))
+	if err != nil {
+		_ = shims.Close()
+		return err
+	}
+	s.listeners = []net.Listener{shims, control}
+
+	s.wg.Add(2)
+	go s.accept(shims, s.handleShim)
+	go s.accept(control, s.handleApprover)
+	return nil
+}
+
+// Close stops serving; waiting commands are denied.
+func (s *Server) Close() {
+	for _, l := range s.listeners {
+		_ = l.Close()
+	}
+	s.mu.Lock()
+	if s.approver != nil {
+		_ = s.approver.Close()
+	}
+	for _, p := range s.pending {
+		select {
+		case p.decision <- false:
+		default:
+		}
+	}
+	s.mu.Unlock()
+	s.wg.Wait()
+}
+
+// listenUnix listens on a socket only the user can connect to, replacing
+// the one a previous session left.
+func listenUnix(path string) (net.Listener, error) {
+	_ = os.Remove(path)
+	l, err := net.Listen("unix", path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to listen on %s: %w", path, err)
+	}
+	if err := os.Chmod(path, 0600); err != nil {
+		_ = l.Close()
+		return nil, err
+	}
+	return l, nil
+}
+
+func (s *Server) accept(l net.Listener, handle func(net.Conn)) {
+	defer s.wg.Done()
+	for {
+		conn, err := l.Accept()
+		if err != nil {
+			return
+		}
+		go handle(conn)
+	}
+}
+
+// handleShim answers a single request: commands no rule matches are
+// allowed right away, the others wait for the approver or the timeout.
+func (s *Server) handleShim(conn net.Conn) {
+	defer func() { _ = conn.Close() }()
+	var req Request
+	if err := json.NewDecoder(conn).Decode(&req); err != nil {
+		return
+	}
+
+	resp := Response{Allow: true}
+	if rule, ok := Match(s.Rules, s.Workspace, req); ok {
+		resp = s.ask(req, rule)
+	}
+	_ = json.NewEncoder(conn).Encode(resp)
+}
+
+func (s *Server) ask(req Request, rule config.CommandRule) Response {
+	s.mu.Lock()
+	s.nextID++
+	p := &pendingPrompt{
+		prompt:   Prompt{ID: s.nextID, Rule: rule.Pattern, Request: req},
+		decision: make(chan bool, 1),
+	}
+	s.pending[p.prompt.ID] = p
+	approver := s.approver
+	if approver != nil {
+		if err := json.NewEncoder(approver).Encode(p.prompt); err != nil {
+			approver = nil
+		}
+	}
+	s.mu.Unlock()
+	if s.Notify != nil {
+		s.Notify(p.prompt, approver != nil)
+	}
+
+	var resp Response
+	decision := "timeout"
+	select {
+	case allow := <-p.decision:
+		resp.Allow = allow
+		decision = "denied"
+		if allow {
+			decision = "allowed"
+		} else {
+			resp.Reason = "denied on the host"
+		}
+	case <-time.After(s.Timeout):
+		resp.Reason = fmt.Sprintf("no decision within %s", s.Timeout)
+	}
+	s.mu.Lock()
+	delete(s.pending, p.prompt.ID)
+	s.mu.Unlock()
+
+	if err := s.audit(Entry{Time: time.Now(), Argv: req.Argv, Cwd: req.Cwd, Rule: rule.Pattern, Decision: decision}); err != nil {
+		fmt.Fprintf(os.Stderr, "⚠️  Failed to write the approval audit log: %v\r\n", err)
+	}
+	return resp
+}
+
+// handleApprover makes conn the approver, replacing a previous one, sends it
+// the commands already waiting and reads its decisions.
+func (s *Server) handleApprover(conn net.Conn) {
+	s.mu.Lock()
+	if s.approver != nil {
+		_ = s.approver.Close()
+	}
+	s.approver = conn
+	enc := json.NewEncoder(conn)
+	for _, id := range sortedIDs(s.pending) {
+		_ = enc.Encode(s.pending[id].prompt)
+	}
+	s.mu.Unlock()
+
+	scanner := bufio.NewScanner(conn)
+	for scanner.Scan() {
+		var d Decision
+		if err := json.Unmarshal(scanner.Bytes(), &d); err != nil {
+			continue
+		}
+		s.mu.Lock()
+		if p, ok := s.pending[d.ID]; ok {
+			select {
+			case p.decision <- d.Allow:
+			default:
+			}
+		}
+		s.mu.Unlock()
+	}
+
+	s.mu.Lock()
+	if s.approver == conn {
+		s.approver = nil
+	}
+	s.mu.Unlock()
+	_ = conn.Close()
+}
+
+func (s *Server) audit(e Entry) error {
+	if s.AuditLog == "" {
+		return nil
+	}
+	data, err := json.Marshal(e)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(s.AuditLog), 0700); err != nil {
+		return err
+	}
+	f, err := os.OpenFile(s.AuditLog, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0600)
+	if err != nil {
+		return err
+	}
+	_, err = f.Write(append(data, '\n'))
+	return errors.Join(err, f.Close())
+}
+
+func sortedIDs(pending map[int]*pendingPrompt) []int {
+	ids := make([]int, 0, len(pending))
+	for id := range pending {
+		ids = append(ids, id)
+	}
+	slices.Sort(ids)
+	return ids
+}
@@ -0,0 +1,139 @@
+package approval
+
+import (
+	"bufio"
+	"encoding/json"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/arewm/ai-shell/internal/config"
+)
+
+// request sends req as a shim does and returns the response.
+func request(t *testing.T, dir string, req Request) Response {
+	t.Helper()
+	conn, err := net.Dial("unix", filepath.Join(ContainerDir(dir), socketName))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() { _ = conn.Close() }()
+	if err := json.NewEncoder(conn).Encode(req); err != nil {
+		t.Fatal(err)
+	}
+	var resp Response
+	if err := json.NewDecoder(conn).Decode(&resp); err != nil {
+		t.Fatal(err)
+	}
+	return resp
+}
+
+func TestServer(t *testing.T) {
+	tmpDir, err := os.MkdirTemp("", "ai-shell-approval-test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() { _ = os.RemoveAll(tmpDir) }()
+
+	notified := make(chan bool, 10)
+	s := &Server{
+		Rules:     []config.CommandRule{{Pattern: "git push*"}},
+		Workspace: "/ws",
+		Timeout:   200 * time.Millisecond,
+		AuditLog:  filepath.Join(tmpDir, "audit", "approvals.jsonl"),
+		Notify:    func(_ Prompt, approver bool) { notified <- approver },
+	}
+	if err := s.Listen(tmpDir); err != nil {
+		t.Fatalf("Listen failed: %v", err)
+	}
+	defer s.Close()
+
+	// Commands no rule matches run right away
+	if resp := request(t, tmpDir, Request{Argv: []string{"git", "status"}, Cwd: "/ws"}); !resp.Allow {
+		t.Errorf("Expected git status to be allowed, Got: %+v", resp)
+	}
+
+	// Without an approver, the command is denied after the timeout
+	resp := request(t, tmpDir, Request{Argv: []string{"git", "push"}, Cwd: "/ws"})
+	if resp.Allow || !strings.Contains(resp.Reason, "no decision") {
+		t.Errorf("Expected a timeout, Got: %+v", resp)
+	}
+	if approver := <-notified; approver {
+		t.Error("Expected the notification to say that no approver is connected")
+	}
+
+	// An approver decides
+	conn, err := net.Dial("unix", filepath.Join(tmpDir, controlSocket))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() { _ = conn.Close() }()
+	s.Timeout = 5 * time.Second
+	for _, allow := range []bool{true, false} {
+		done := make(chan Response)
+		go func() { done <- request(t, tmpDir, Request{Argv: []string{"git", "push", "origin"}, Cwd: "/ws"}) }()
+
+		var p Prompt
+		if err := json.NewDecoder(bufio.NewReader(conn)).Decode(&p); err != nil {
+			t.Fatal(err)
+		}
+		if p.Rule != "git push*" || p.String() != "git push origin" {
+			t.Errorf("Unexpected prompt: %+v", p)
+		}
+		if err := json.NewEncoder(conn).Encode(Decision{ID: p.ID, Allow: allow}); err != nil {
+			t.Fatal(err)
+		}
+		if resp := <-done; resp.Allow != allow {
+			t.Errorf("Input: allow %v, Got: %+v", allow, resp)
+		}
+	}
+
+	data, err := os.ReadFile(s.AuditLog)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var decisions []string
+	for _, line := range strings.Split(strings.TrimSpace(string(data)), "\n") {
+		var e Entry
+		if err := json.Unmarshal([]byte(line), &e); err != nil {
+			t.Fatal(err)
+		}
+		decisions = append(decisions, e.Decision)
+	}
+	if strings.Join(decisions, ",") != "timeout,allowed,denied" {
+		t.Errorf("Expected: timeout,allowed,denied, Got: %v", decisions)
+	}
+}
+
+func TestApprove(t *testing.T) {
+	tmpDir, err := os.MkdirTemp("", "ai-shell-approval-test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() { _ = os.RemoveAll(tmpDir) }()
+
+	s := &Server{Rules: []config.CommandRule{{Pattern: "gh pr merge*"}}, Workspace: "/ws", Timeout: 5 * time.Second}
+	if err := s.Listen(tmpDir); err != nil {
+		t.Fatalf("Listen failed: %v", err)
+	}
+	defer s.Close()
+
+	var out strings.Builder
+	approved := make(chan error)
+	go func() { approved <- Approve(tmpDir, strings.NewReader("y\n"), &out) }()
+
+	resp := request(t, tmpDir, Request{Argv: []string{"gh", "pr", "merge", "7"}, Cwd: "/ws"})
+	if !resp.Allow {
+		t.Errorf("Expected the approver's yes, Got: %+v", resp)
+	}
+	s.Close()
+	if err := <-approved; err != nil {
+		t.Errorf("Approve failed: %v", err)
+	}
+	if !strings.Contains(out.String(), "gh pr merge 7") {
+		t.Errorf("Expected the prompt to show the command, Got: %s", out.String())
+	}
+}
@@ -4,7 +4,9 @@ import (
 	"embed"
 	"fmt"
 	"os"
+	"path"
 	"path/filepath"
+	"strings"
 )
 
 //go:embed all:files
@@ -31,7 +33,7 @@ func WriteToDir(targetDir, subDir string) error {
 		path := filepath.Join(targetDir, entry.Name())
 
 		perm := os.FileMode(0644)
-		if entry.Name() == "configure.sh" {
+		if strings.HasSuffix(entry.Name(), ".sh") {
 			perm = 0755
 		}
 
@@ -41,3 +43,8 @@ func WriteToDir(targetDir, subDir string) error {
 	}
 	return nil
 }
+
+// ReadFile returns a single embedded asset (e.g., "seccomp/ai-shell.json").
+func ReadFile(name string) ([]byte, error) {
+	return files.ReadFile(path.Join("files", name))
+}
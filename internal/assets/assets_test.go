@@ -2,7 +2,9 @@ package assets
 
 import (
 	"os"
+	"os/exec"
 	"path/filepath"
+	"strings"
 	"testing"
 )
 
@@ -20,7 +22,15 @@ func TestWriteToDir(t *testing.T) {
 	expectedFiles := []string{
 		"Containerfile",
 		"configure.sh",
+		"lifecycle.sh",
 		"config.default.yaml",
+		"sandbox-exec.sh",
+		"sandbox-shell.sh",
+		"sandbox.sh",
+		"approval-shim.sh",
+		"approval-path.sh",
+		"audit.zsh",
+		"audit.bash",
 	}
 
 	for _, file := range expectedFiles {
@@ -34,12 +44,136 @@ func TestWriteToDir(t *testing.T) {
 			t.Errorf("File %s is empty", file)
 		}
 
-		if file == "configure.sh" {
+		if filepath.Ext(file) == ".sh" {
 			// Check executable permission (0755)
 			// Note: on some filesystems/OS this check might be tricky, but basic check:
 			if info.Mode()&0111 == 0 {
-				t.Errorf("configure.sh should be executable")
+				t.Errorf("%s should be executable", file)
 			}
 		}
 	}
 }
+
+func TestWriteToDirAdapter(t *testing.T) {
+	tmpDir, err := os.MkdirTemp("", "ai-shell-assets-test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() { _ = os.RemoveAll(tmpDir) }()
+
+	if err := WriteToDir(tmpDir, "adapter"); err != nil {
+		t.Fatalf("WriteToDir failed: %v", err)
+	}
+
+	data, err := os.ReadFile(filepath.Join(tmpDir, "Containerfile"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	// The adapter copies the entrypoint from the base assets
+	for _, expected := range []string{"ARG BASE_IMAGE", "COPY base/configure.sh", "ENTRYPOINT"} {
+		if !strings.Contains(string(data), expected) {
+			t.Errorf("Adapter Containerfile is missing %q", expected)
+		}
+	}
+}
+
+func TestWriteToDirProxy(t *testing.T) {
+	tmpDir, err := os.MkdirTemp("", "ai-shell-assets-test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() { _ = os.RemoveAll(tmpDir) }()
+
+	if err := WriteToDir(tmpDir, "proxy"); err != nil {
+		t.Fatalf("WriteToDir failed: %v", err)
+	}
+
+	data, err := os.ReadFile(filepath.Join(tmpDir, "Containerfile"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(data), "/etc/squid/ai-shell.conf") {
+		t.Error("Proxy Containerfile should run the config generated by ai-shell")
+	}
+}
+
+func TestWriteToDirMonitor(t *testing.T) {
+	tmpDir, err := os.MkdirTemp("", "ai-shell-assets-test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() { _ = os.RemoveAll(tmpDir) }()
+
+	if err := WriteToDir(tmpDir, "monitor"); err != nil {
+		t.Fatalf("WriteToDir failed: %v", err)
+	}
+
+	info, err := os.Stat(filepath.Join(tmpDir, "monitor.sh"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if info.Mode()&0111 == 0 {
+		t.Error("monitor.sh should be executable")
+	}
+}
+
+func TestReadFile(t *testing.T) {
+	data, err := ReadFile("seccomp/ai-shell.json")
+	if err != nil {
+		t.Fatalf("ReadFile failed: %v", err)
+	}
+	if !strings.Contains(string(data), `"ptrace"`) {
+		t.Error("The seccomp profile should block ptrace")
+	}
+}
+
+func TestSandboxExecHides(t *testing.T) {
+	if _, err := exec.LookPath("bash"); err != nil {
+		t.Skip("bash is not installed")
+	}
+	tmpDir := t.TempDir()
+	script := filepath.Join(tmpDir, "sandbox-exec")
+	data, err := ReadFile("base/sandbox-exec.sh")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(script, data, 0700); err != nil {
+		t.Fatal(err)
+	}
+	// bwrap prints its arguments instead
+	bin := filepath.Join(tmpDir, "bin")
+	if err := os.MkdirAll(bin, 0700); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(bin, "bwrap"), []byte("#!/bin/sh\nfor a; do echo \"$a\"; done\n"), 0700); err != nil {
+		t.Fatal(err)
+	}
+	home := filepath.Join(tmpDir, "home")
+	// The host's ~/.claude and the keys of ssh: true
+	for _, f := range []string{".claude.host/.credentials.json", ".ssh/id_ed25519", ".kube/config"} {
+		p := filepath.Join(home, f)
+		if err := os.MkdirAll(filepath.Dir(p), 0700); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(p, []byte("secret"), 0600); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	cmd := exec.Command("bash", script, "true")
+	cmd.Dir = home
+	cmd.Env = []string{"HOME=" + home, "PATH=" + bin + ":" + os.Getenv("PATH"), "AI_SHELL_WORKSPACE=" + home}
+	out, err := cmd.CombinedOutput()
+	if err != nil {
+		t.Fatalf("sandbox-exec failed: %v\n%s", err, out)
+	}
+	args := "\n" + string(out)
+	for _, dir := range []string{".claude.host", ".ssh"} {
+		if !strings.Contains(args, "\n--tmpfs\n"+filepath.Join(home, dir)+"\n") {
+			t.Errorf("Expected %s to be hidden, Got:%s", dir, args)
+		}
+	}
+	if strings.Contains(args, filepath.Join(home, ".kube")) {
+		t.Errorf("Expected .kube to stay visible, Got:%s", args)
+	}
+}
@@ -0,0 +1,10 @@
+# approval-path.sh: sourced by the global zshenv and bashrc. Puts the
+# approval shims ai-shell mounts first in the PATH, also for the shells
+# `podman exec` starts, which do not go through configure.sh.
+
+if [ -d /run/ai-shell/approval/bin ]; then
+    case ":$PATH:" in
+        *:/run/ai-shell/approval/bin:*) ;;
+        *) export PATH="/run/ai-shell/approval/bin:$PATH" ;;
+    esac
+fi
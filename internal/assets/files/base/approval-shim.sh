@@ -0,0 +1,45 @@
+#!/bin/bash
+
+# approval-shim: ai-shell links the commands listed in approval.commands to
+# this script, in a directory first in the PATH. It asks the approver on the
+# host over the mounted socket and only runs the real command if allowed.
+# Without an answer the command is denied.
+
+SOCKET="/run/ai-shell/approval/approval.sock"
+NAME="$(basename "$0")"
+SHIM_DIR="$(cd "$(dirname "$0")" && pwd)"
+
+# The real command is the next one in the PATH
+REAL=""
+IFS=: read -ra DIRS <<< "$PATH"
+for D in "${DIRS[@]}"; do
+    if [ "$D" = "$SHIM_DIR" ] || [ -z "$D" ]; then continue; fi
+    if [ -x "$D/$NAME" ] && [ ! -d "$D/$NAME" ]; then
+        REAL="$D/$NAME"
+        break
+    fi
+done
+if [ -z "$REAL" ]; then
+    echo "ai-shell: $NAME: command not found" >&2
+    exit 127
+fi
+
+STDIN_PIPE=false
+if [ -p /dev/stdin ]; then
+    STDIN_PIPE=true
+fi
+
+REQUEST=$(jq -cn --arg cwd "$PWD" --argjson pipe "$STDIN_PIPE" \
+    '{argv: $ARGS.positional, cwd: $cwd, stdin_pipe: $pipe}' --args "$NAME" "$@")
+# The host answers within approval.timeout; socat must not give up before
+RESPONSE=$(printf '%s\n' "$REQUEST" | socat -t 86400 - "UNIX-CONNECT:$SOCKET" 2>/dev/null)
+
+if [ "$(jq -r '.allow' <<< "$RESPONSE" 2>/dev/null)" = "true" ]; then
+    exec "$REAL" "$@"
+fi
+REASON=$(jq -r '.reason // empty' <<< "$RESPONSE" 2>/dev/null)
+echo "ai-shell: '$NAME $*' was not approved${REASON:+ ($REASON)}." >&2
+if [ -z "$RESPONSE" ]; then
+    echo "ai-shell: the approval server on the host is not reachable." >&2
+fi
+exit 126
@@ -0,0 +1,48 @@
+# audit.bash: sourced by the global bashrc and, through BASH_ENV, by
+# non-interactive bash. The bash counterpart of audit.zsh.
+
+# Once per shell: bashrc files source each other
+if [ -z "$_ai_shell_audit_loaded" ] && [ -n "$AI_SHELL_AUDIT_FILE" ] && [ -w "$(dirname "$AI_SHELL_AUDIT_FILE")" ] && command -v jq >/dev/null 2>&1; then
+    _ai_shell_audit_loaded=1
+
+    _ai_shell_audit_start() {
+        _ai_shell_audit_id="$BASHPID-$EPOCHREALTIME"
+        local who=agent parent=""
+        if [ -t 0 ]; then who=tty; fi
+        if [ -r "/proc/$PPID/comm" ]; then parent="$(< "/proc/$PPID/comm")"; fi
+        jq -cn --arg id "$_ai_shell_audit_id" --arg time "$EPOCHREALTIME" --arg who "$who" \
+            --arg parent "$parent" --arg cwd "$PWD" --arg command "$1" \
+            '{event: "start", id: $id, time: ($time | tonumber), who: $who, parent: $parent, shell: "bash", cwd: $cwd, command: $command}' \
+            >> "$AI_SHELL_AUDIT_FILE" 2>/dev/null
+    }
+
+    _ai_shell_audit_end() {
+        if [ -z "$_ai_shell_audit_id" ]; then return; fi
+        jq -cn --arg id "$_ai_shell_audit_id" --arg time "$EPOCHREALTIME" --argjson exit "$1" \
+            '{event: "end", id: $id, time: ($time | tonumber), exit: $exit}' \
+            >> "$AI_SHELL_AUDIT_FILE" 2>/dev/null
+        _ai_shell_audit_id=""
+    }
+
+    if [ -n "$BASH_EXECUTION_STRING" ]; then
+        _ai_shell_audit_start "$BASH_EXECUTION_STRING"
+        trap '_ai_shell_audit_end $?' EXIT
+    elif [[ $- == *i* ]]; then
+        # The DEBUG trap starts the first command after each prompt, whose
+        # line is the last history entry
+        _ai_shell_audit_debug() {
+            if [ -z "$_ai_shell_audit_armed" ]; then return; fi
+            _ai_shell_audit_armed=""
+            local line
+            line="$(HISTTIMEFORMAT='' builtin history 1)"
+            _ai_shell_audit_start "$(sed 's/^ *[0-9]* *//' <<< "$line")"
+        }
+        _ai_shell_audit_status() { _ai_shell_audit_ret=$?; }
+        _ai_shell_audit_prompt() {
+            _ai_shell_audit_end "${_ai_shell_audit_ret:-0}"
+            _ai_shell_audit_armed=1
+        }
+        trap '_ai_shell_audit_debug' DEBUG
+        PROMPT_COMMAND="_ai_shell_audit_status${PROMPT_COMMAND:+;$PROMPT_COMMAND};_ai_shell_audit_prompt"
+    fi
+fi
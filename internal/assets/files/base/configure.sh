@@ -2,9 +2,33 @@
 set -e
 
 # configure.sh: Handles authentication and setup.
-# Runs first as root to setup paths, then drops to 'ai' user.
+# Runs first as root to setup paths, then drops to the 'ai' user (or the
+# devcontainer remoteUser passed as AI_SHELL_USER).
+
+AI_USER="${AI_SHELL_USER:-ai}"
+
+# security.level: strict and paranoid drop sudo, paranoid also runs with a
+# read-only root where only the volumes and /tmp are writable.
+SECURITY="${AI_SHELL_SECURITY:-standard}"
+READ_ONLY=false
+if [ "$SECURITY" = "paranoid" ]; then
+    READ_ONLY=true
+fi
 
 if [ "$(id -u)" = "0" ]; then
+    if ! id "$AI_USER" >/dev/null 2>&1; then
+        echo "❌ User '$AI_USER' does not exist in this image." >&2
+        exit 1
+    fi
+    # VS Code: a generated devcontainer.json carries the merged config in
+    # customizations.ai-shell, in place of the file ai-shell mounts
+    if [ -f /etc/ai-shell/devcontainer.json ] && [ "$READ_ONLY" = false ]; then
+        if CUSTOM=$(yq -p json -o json '.customizations."ai-shell" // {}' /etc/ai-shell/devcontainer.json 2>/dev/null); then
+            printf '%s\n' "$CUSTOM" > /etc/ai-shell/config.yaml
+        else
+            echo "⚠️  Could not read customizations.ai-shell from devcontainer.json, using the default config." >&2
+        fi
+    fi
     # 1. Runtime Path Fidelity
     if [ -n "$HOST_HOME_ROOT" ] && [ -n "$HOST_USER" ]; then
         HOST_HOME="${HOST_HOME_ROOT}/${HOST_USER}"
@@ -15,20 +39,25 @@ if [ "$(id -u)" = "0" ]; then
         fi
         
         # Always enforce ownership and path mapping
-        # Match 'ai' UID to the volume owner (handled by keep-id)
+        # Match the user's UID to the volume owner (handled by keep-id)
+        # (With a read-only root, ai-shell maps the host user to the user's UID instead)
         TARGET_UID=$(stat -c %u "$HOST_HOME")
-        if [ "$TARGET_UID" != "0" ] && [ "$TARGET_UID" != "$(id -u ai)" ]; then
-            usermod -u "$TARGET_UID" ai 2>/dev/null || true
+        if [ "$READ_ONLY" = false ] && [ "$TARGET_UID" != "0" ] && [ "$TARGET_UID" != "$(id -u "$AI_USER")" ]; then
+            usermod -u "$TARGET_UID" "$AI_USER" 2>/dev/null || true
         fi
 
         # Ensure permissions on the volume
-        chown -R ai:ai "$HOST_HOME" 2>/dev/null || true
+        chown -R "$AI_USER:" "$HOST_HOME" 2>/dev/null || true
         
-        # Update user home to the host-mirrored path
-        usermod -d "$HOST_HOME" ai
+        # Update user home to the host-mirrored path (passed as HOME on a read-only root)
+        if [ "$READ_ONLY" = false ]; then
+            usermod -d "$HOST_HOME" "$AI_USER"
+        fi
         
         # Link legacy home for tools hardcoded to /home/ai
-        if [ ! -L "/home/ai" ]; then
+        if [ "$AI_USER" = "ai" ] && [ "$READ_ONLY" = true ]; then
+            cp -rn /home/ai/. "$HOST_HOME/" 2>/dev/null || true
+        elif [ "$AI_USER" = "ai" ] && [ ! -L "/home/ai" ]; then
             # Move default files (like .zshrc) if target is empty?
             # Or just overwrite.
             cp -rn /home/ai/. "$HOST_HOME/" 2>/dev/null || true
@@ -54,14 +83,36 @@ if [ "$(id -u)" = "0" ]; then
         echo "  helper = store" >> "$HOST_HOME/.gitconfig"
         
         # Best effort chown (might fail on RO mounts)
-        chown ai:ai "$HOST_HOME/.gitconfig" "$HOST_HOME/.gitconfig.host" 2>/dev/null || true
+        chown "$AI_USER:" "$HOST_HOME/.gitconfig" "$HOST_HOME/.gitconfig.host" 2>/dev/null || true
+    fi
+
+    # 2. CA Certificates (ca_certs): add them to the system trust store
+    # (a read-only root gets a combined bundle in the user phase instead)
+    if [ "$READ_ONLY" = false ] && [ -s "/etc/ai-shell/ca-certs.pem" ]; then
+        if command -v update-ca-trust >/dev/null 2>&1; then
+            cp /etc/ai-shell/ca-certs.pem /etc/pki/ca-trust/source/anchors/ai-shell.pem
+            update-ca-trust extract
+        elif command -v update-ca-certificates >/dev/null 2>&1; then
+            cp /etc/ai-shell/ca-certs.pem /usr/local/share/ca-certificates/ai-shell.crt
+            update-ca-certificates >/dev/null
+        else
+            echo "⚠️  No CA trust store tool in this image, ca_certs are only used by Node.js." >&2
+        fi
+    fi
+
+    # 3. Hardening: the user loses sudo (no-new-privileges disables it on a read-only root)
+    if [ "$SECURITY" != "standard" ] && [ "$READ_ONLY" = false ]; then
+        rm -f /etc/sudoers.d/*
     fi
 
     # Drop privileges and re-run this script
-    exec runuser -u ai -- "$0" "$@"
+    if [ "$READ_ONLY" = true ] && [ -n "$HOST_HOME" ]; then
+        exec runuser -u "$AI_USER" -- env HOME="$HOST_HOME" "$0" "$@"
+    fi
+    exec runuser -u "$AI_USER" -- "$0" "$@"
 fi
 
-# --- Running as User 'ai' ---
+# --- Running as User 'ai' (or AI_SHELL_USER) ---
 
 CONFIG_FILE="/etc/ai-shell/config.yaml"
 
@@ -69,13 +120,34 @@ CONFIG_FILE="/etc/ai-shell/config.yaml"
 # 1. Registry Login
 # -----------------------------------------------------------------------------
 # Ensure consistent auth file for skopeo, oras, and cosign
-export DOCKER_CONFIG="/home/ai/.docker"
+export DOCKER_CONFIG="$HOME/.docker"
 export REGISTRY_AUTH_FILE="$DOCKER_CONFIG/config.json"
 mkdir -p "$DOCKER_CONFIG"
 if [ ! -f "$REGISTRY_AUTH_FILE" ]; then
     echo '{"auths":{}}' > "$REGISTRY_AUTH_FILE"
 fi
 
+# -----------------------------------------------------------------------------
+# 1b. CA Certificates
+# -----------------------------------------------------------------------------
+# pip, requests and Python's ssl bring their own bundles; point them at the
+# system one, which now includes ca_certs.
+if [ -s "/etc/ai-shell/ca-certs.pem" ]; then
+    for BUNDLE in /etc/pki/tls/certs/ca-bundle.crt /etc/ssl/certs/ca-certificates.crt; do
+        if [ -f "$BUNDLE" ]; then
+            if [ "$READ_ONLY" = true ]; then
+                cat "$BUNDLE" /etc/ai-shell/ca-certs.pem > /tmp/ai-shell-ca-bundle.pem
+                BUNDLE=/tmp/ai-shell-ca-bundle.pem
+            fi
+            export SSL_CERT_FILE="$BUNDLE"
+            export REQUESTS_CA_BUNDLE="$BUNDLE"
+            export PIP_CERT="$BUNDLE"
+            export GIT_SSL_CAINFO="$BUNDLE"
+            break
+        fi
+    done
+fi
+
 # -----------------------------------------------------------------------------
 # 2. Claude Config Import (Surgical Symlinking)
 # -----------------------------------------------------------------------------
@@ -117,7 +189,13 @@ fi
 
 # Use yq to iterate over registries. 
 # Output format: "registry|username_env|token_env"
-REGISTRIES=$(yq -r '.registries[] | "\(.registry)|\(.username_env)|\(.token_env)"' "$CONFIG_FILE")
+# Offline mode (AI_SHELL_OFFLINE) skips the logins, the registries are unreachable.
+if [ -n "$AI_SHELL_OFFLINE" ]; then
+    echo "   Offline mode: skipping registry logins."
+    REGISTRIES=""
+else
+    REGISTRIES=$(yq -r '.registries[] | "\(.registry)|\(.username_env)|\(.token_env)"' "$CONFIG_FILE")
+fi
 
 while IFS='|' read -r REG USER_VAR TOKEN_VAR; do
     # Skip empty lines
@@ -147,7 +225,13 @@ fi
 # 3. SCM Configuration
 # -----------------------------------------------------------------------------
 # Output format: "host|token_env|username_env"
-SCMS=$(yq -r '.scms[] | "\(.host)|\(.token_env)|\(.username_env)"' "$CONFIG_FILE")
+# Offline mode skips the HTTPS rewrites and gh setup, there is nothing to reach.
+if [ -n "$AI_SHELL_OFFLINE" ]; then
+    echo "   Offline mode: skipping SCM configuration."
+    SCMS=""
+else
+    SCMS=$(yq -r '.scms[] | "\(.host)|\(.token_env)|\(.username_env)"' "$CONFIG_FILE")
+fi
 GIT_CONFIG_IDX=0
 
 while IFS='|' read -r HOST TOKEN_VAR USER_VAR; do
@@ -199,5 +283,44 @@ if [ "$GIT_CONFIG_IDX" -gt 0 ]; then
     export GIT_CONFIG_COUNT=$GIT_CONFIG_IDX
 fi
 
+# -----------------------------------------------------------------------------
+# 3b. remoteEnv referring to the container's environment (remote_env)
+# -----------------------------------------------------------------------------
+# ${VAR} and ${VAR:-default} are expanded against the environment the image
+# and the steps above set up, e.g. PATH=${PATH}:/extra. Nothing else is
+# evaluated.
+expand_env() {
+    local rest="$1" out="" ref name def
+    while [[ "$rest" =~ \$\{([A-Za-z_][A-Za-z0-9_]*)(:-([^}]*))?\} ]]; do
+        ref="${BASH_REMATCH[0]}"
+        name="${BASH_REMATCH[1]}"
+        def="${BASH_REMATCH[3]}"
+        out+="${rest%%"$ref"*}"
+        if [ -n "${!name}" ]; then out+="${!name}"; else out+="$def"; fi
+        rest="${rest#*"$ref"}"
+    done
+    printf '%s' "$out$rest"
+}
+
+while IFS= read -r ENTRY; do
+    if [ -z "$ENTRY" ]; then continue; fi
+    export "${ENTRY%%=*}=$(expand_env "${ENTRY#*=}")"
+done < <(yq -r '.remote_env[]?' "$CONFIG_FILE" 2>/dev/null)
+
+# -----------------------------------------------------------------------------
+# 4. Lifecycle Commands (onCreate, postStart, ...)
+# -----------------------------------------------------------------------------
+if ! /usr/local/bin/lifecycle.sh start; then
+    echo "⚠️  Lifecycle commands failed, continuing into the shell." >&2
+fi
+
+# -----------------------------------------------------------------------------
+# 5. Approval (approval.commands)
+# -----------------------------------------------------------------------------
+# After the lifecycle commands, which run before anyone can approve: the
+# shims ai-shell mounts go first in the PATH of the shell. The global zshenv
+# and bashrc do the same for the shells of podman exec (approval-path.sh).
+. /usr/local/lib/ai-shell/approval-path.sh
+
 # Execute the command (usually zsh)
 exec "$@"
\ No newline at end of file
@@ -0,0 +1,79 @@
+#!/bin/bash
+set -e
+
+# lifecycle.sh: Runs the devcontainer lifecycle commands from the ai-shell config.
+# Usage: lifecycle.sh start|attach
+#   start:  on_create, update_content and post_create (once per home volume),
+#           then post_start and post_attach
+#   attach: post_attach only (used when exec'ing into a running container)
+
+CONFIG_FILE="/etc/ai-shell/config.yaml"
+STATE_DIR="$HOME/.local/state/ai-shell/lifecycle"
+
+# run_stage <stage> [once]
+# Commands of a stage run in parallel; the stage fails if any of them fails.
+run_stage() {
+    local stage="$1" once="$2"
+    local marker="$STATE_DIR/$stage"
+
+    if [ "$once" = "once" ] && [ -f "$marker" ]; then
+        return 0
+    fi
+
+    local commands
+    commands=$(yq -o=json -I=0 ".lifecycle.${stage} // [] | .[]" "$CONFIG_FILE" 2>/dev/null || true)
+    if [ -z "$commands" ]; then
+        return 0
+    fi
+
+    local pids=() names=()
+    while IFS= read -r cmd; do
+        [ -z "$cmd" ] && continue
+        local name
+        name=$(jq -r '.name // ""' <<< "$cmd")
+        echo "   Running ${stage}${name:+ ($name)}..."
+        if [ "$(jq '.args // [] | length' <<< "$cmd")" -gt 0 ]; then
+            local argv=()
+            mapfile -t argv < <(jq -r '.args[]' <<< "$cmd")
+            "${argv[@]}" &
+        else
+            /bin/sh -c "$(jq -r '.shell' <<< "$cmd")" &
+        fi
+        pids+=("$!")
+        names+=("${name:-$stage}")
+    done <<< "$commands"
+
+    local failed=0 i
+    for i in "${!pids[@]}"; do
+        if ! wait "${pids[$i]}"; then
+            echo "   ⚠️  Lifecycle command ${names[$i]} failed" >&2
+            failed=1
+        fi
+    done
+    if [ "$failed" -ne 0 ]; then
+        return 1
+    fi
+
+    if [ "$once" = "once" ]; then
+        mkdir -p "$STATE_DIR"
+        touch "$marker"
+    fi
+}
+
+case "$1" in
+    start)
+        # A failing stage stops the ones after it, like the devcontainer CLI
+        run_stage on_create once &&
+            run_stage update_content once &&
+            run_stage post_create once &&
+            run_stage post_start &&
+            run_stage post_attach
+        ;;
+    attach)
+        run_stage post_attach
+        ;;
+    *)
+        echo "Usage: $0 start|attach" >&2
+        exit 1
+        ;;
+esac
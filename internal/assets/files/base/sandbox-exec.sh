@@ -0,0 +1,116 @@
+#!/bin/bash
+set -e
+
+# sandbox-exec: runs a command in a bubblewrap sandbox inside the container.
+# The sandbox sees the root read-only, the workspace, the home volume and
+# /tmp read-write, but not the credentials: they are hidden from the home
+# volume and stripped from the environment. Agents listed in sandbox.agents
+# run their commands through it (see sandbox.sh).
+
+CONFIG_FILE="/etc/ai-shell/config.yaml"
+
+if [ $# -eq 0 ]; then
+    echo "Usage: sandbox-exec COMMAND [ARG...]" >&2
+    exit 2
+fi
+if ! command -v bwrap >/dev/null 2>&1; then
+    echo "❌ bwrap is not installed in this image." >&2
+    exit 1
+fi
+
+WORKSPACE="${AI_SHELL_WORKSPACE:-$PWD}"
+
+# 1. Credentials in the home volume and the host directories mounted there
+# (files are replaced with an empty one, directories with an empty tmpfs)
+HIDE=(
+    .docker/config.json
+    .git-credentials
+    .netrc
+    .config/gh/hosts.yml
+    .config/containers/auth.json
+    .config/gcloud
+    .claude/.credentials.json
+    .claude.host
+    .ssh
+    .gitconfig.host
+)
+if [ -f "$CONFIG_FILE" ]; then
+    while IFS= read -r P; do
+        if [ -n "$P" ]; then HIDE+=("$P"); fi
+    done < <(yq -r '.sandbox.hide[]?' "$CONFIG_FILE" 2>/dev/null)
+fi
+
+ARGS=(
+    --ro-bind / /
+    --dev-bind /dev /dev
+    --proc /proc
+    --bind /tmp /tmp
+    --bind "$HOME" "$HOME"
+    --unshare-all --share-net
+    --die-with-parent
+)
+# ai-shell unmasks /proc in the container so that bwrap can mount a new one;
+# the sandbox gets podman's masks back
+for P in /proc/kcore /proc/keys /proc/latency_stats /proc/sched_debug /proc/timer_list /proc/timer_stats; do
+    if [ -e "$P" ]; then ARGS+=(--ro-bind /dev/null "$P"); fi
+done
+for P in /proc/acpi /proc/scsi; do
+    if [ -d "$P" ]; then ARGS+=(--tmpfs "$P"); fi
+done
+for P in /proc/asound /proc/bus /proc/fs /proc/irq /proc/sys /proc/sysrq-trigger; do
+    if [ -e "$P" ]; then ARGS+=(--ro-bind "$P" "$P"); fi
+done
+if [ "$WORKSPACE" != "$HOME" ]; then
+    ARGS+=(--bind "$WORKSPACE" "$WORKSPACE")
+fi
+# The command audit logs the sandboxed commands too
+if [ -n "$AI_SHELL_AUDIT_FILE" ] && [ -d "$(dirname "$AI_SHELL_AUDIT_FILE")" ]; then
+    AUDIT_DIR="$(dirname "$AI_SHELL_AUDIT_FILE")"
+    ARGS+=(--bind "$AUDIT_DIR" "$AUDIT_DIR")
+fi
+for P in "${HIDE[@]}"; do
+    TARGET="$HOME/$P"
+    if [ -d "$TARGET" ] && [ ! -L "$TARGET" ]; then
+        ARGS+=(--tmpfs "$TARGET")
+    elif [ -e "$TARGET" ] || [ -L "$TARGET" ]; then
+        ARGS+=(--ro-bind /dev/null "$TARGET")
+    fi
+done
+# The host git config is mounted read-only outside the home volume
+if [ -e /etc/ai-shell/gitconfig.host ]; then
+    ARGS+=(--ro-bind /dev/null /etc/ai-shell/gitconfig.host)
+fi
+
+# 2. The git config can hold tokens; the sandbox only keeps the identity
+GIT_NAME=$(git config --global user.name 2>/dev/null || true)
+GIT_EMAIL=$(git config --global user.email 2>/dev/null || true)
+if [ -e "$HOME/.gitconfig" ]; then
+    ARGS+=(--ro-bind-data 3 "$HOME/.gitconfig")
+fi
+
+# 3. Credential environment variables: the ones the config names, the ones
+# configure.sh derives from them, and anything that looks like a secret
+STRIP=(GIT_CONFIG_COUNT GOOGLE_APPLICATION_CREDENTIALS SSH_AUTH_SOCK)
+if [ -f "$CONFIG_FILE" ]; then
+    while IFS= read -r V; do
+        if [ -n "$V" ] && [ "$V" != "null" ]; then STRIP+=("$V"); fi
+    done < <(yq -r '(.registries[]? | .username_env, .token_env), (.scms[]? | .token_env, .username_env), .sandbox.env[]?' "$CONFIG_FILE" 2>/dev/null)
+fi
+for V in $(compgen -e); do
+    case "$V" in
+        GIT_CONFIG_KEY_*|GIT_CONFIG_VALUE_*|*TOKEN*|*SECRET*|*PASSWORD*|*PASSWD*|*API_KEY*|*ACCESS_KEY*|*CREDENTIALS*)
+            STRIP+=("$V") ;;
+    esac
+done
+for V in "${STRIP[@]}"; do
+    unset "$V"
+done
+
+export AI_SHELL_SANDBOXED=1
+exec bwrap "${ARGS[@]}" -- "$@" 3< <(
+    if [ -n "$GIT_NAME$GIT_EMAIL" ]; then
+        printf '[user]\n'
+        if [ -n "$GIT_NAME" ]; then printf '\tname = %s\n' "$GIT_NAME"; fi
+        if [ -n "$GIT_EMAIL" ]; then printf '\temail = %s\n' "$GIT_EMAIL"; fi
+    fi
+)
@@ -0,0 +1,13 @@
+#!/bin/bash
+
+# sandbox-shell: installed as .../sandbox/zsh and .../sandbox/bash. Agents
+# listed in sandbox.agents get it as $SHELL, so the commands they run go
+# through sandbox-exec while the agent itself keeps its credentials.
+
+REAL_SHELL="/bin/$(basename "$0")"
+
+# Already inside the sandbox: nested user namespaces would only fail
+if [ -n "$AI_SHELL_SANDBOXED" ]; then
+    exec "$REAL_SHELL" "$@"
+fi
+exec /usr/local/bin/sandbox-exec "$REAL_SHELL" "$@"
@@ -0,0 +1,12 @@
+# sandbox.sh: sourced by the global zshrc and bashrc. Wraps the agents listed
+# in sandbox.agents so that the commands they run go through sandbox-exec.
+
+if [ -z "$AI_SHELL_SANDBOXED" ] && command -v yq >/dev/null 2>&1 && [ -f /etc/ai-shell/config.yaml ]; then
+    _ai_shell_sh=bash
+    if [ -n "$ZSH_VERSION" ]; then _ai_shell_sh=zsh; fi
+    for _ai_shell_agent in $(yq -r '.sandbox.agents[]?' /etc/ai-shell/config.yaml 2>/dev/null); do
+        # Names are validated by ai-shell ([A-Za-z0-9._-])
+        eval "${_ai_shell_agent}() { SHELL=/usr/local/lib/ai-shell/sandbox/${_ai_shell_sh} command ${_ai_shell_agent} \"\$@\"; }"
+    done
+    unset _ai_shell_agent _ai_shell_sh
+fi
@@ -0,0 +1,36 @@
+#!/bin/sh
+set -e
+
+# monitor.sh: Runs dnsmasq as the resolver of the shared network namespace
+# and logs outbound connection attempts with tcpdump. Logs go to LOG_DIR,
+# which ai-shell mounts from the session directory on the host.
+
+LOG_DIR=/var/log/ai-shell
+
+# 1. Resolver: forward to the servers podman configured, then take their place
+UPSTREAM=$(awk '/^nameserver/ && $2 !~ /^127\./ { print "server=" $2 }' /etc/resolv.conf)
+cat > /etc/dnsmasq.conf <<CONF
+listen-address=127.0.0.1
+bind-interfaces
+no-resolv
+log-queries
+log-facility=$LOG_DIR/dns.log
+$UPSTREAM
+CONF
+
+OPTIONS=$(grep -v '^nameserver' /etc/resolv.conf || true)
+printf 'nameserver 127.0.0.1\n%s\n' "$OPTIONS" > /etc/resolv.conf
+
+dnsmasq --keep-in-foreground &
+
+# 2. Connections: TCP SYNs and UDP datagrams leaving the namespace. Only the
+# queries dnsmasq forwards to its upstream servers are skipped (they are
+# already in dns.log), so DNS sent directly to another server is logged
+DNS_FILTER=$(awk '/^server=/ { sub(/^server=/, ""); printf "%s(udp port 53 and host %s)", sep, $0; sep = " or " }' /etc/dnsmasq.conf)
+UDP_FILTER=udp
+if [ -n "$DNS_FILTER" ]; then
+    UDP_FILTER="udp and not ($DNS_FILTER)"
+fi
+exec tcpdump -l -n -tttt -i any \
+    "not net 127.0.0.0/8 and ((tcp[tcpflags] & (tcp-syn|tcp-ack) == tcp-syn) or ($UDP_FILTER))" \
+    > "$LOG_DIR/connections.log" 2>/dev/null
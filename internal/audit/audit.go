@@ -0,0 +1,286 @@
+// Package audit collects the commands run in a project's container into a
+// JSON Lines log per session, and queries them for ai-shell audit log.
+//
+// The shell hooks of the image (audit.zsh, audit.bash) append a start and an
+// end event per command to a spool the host mounts into the container; the
+// Collector turns them into one Record per command in the session directory.
+package audit
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+const (
+	// LogFile is the command log in a session directory.
+	LogFile = "commands.jsonl"
+	// MountTarget is where the spool directory is mounted in the container.
+	MountTarget = "/run/ai-shell/audit"
+	// SpoolFile is the file the hooks append to, below the spool directory.
+	SpoolFile = "spool.jsonl"
+	// BashEnv runs the bash hook in non-interactive shells.
+	BashEnv = "/usr/local/lib/ai-shell/audit.bash"
+)
+
+// Who ran a command: an agent or a human at the terminal.
+const (
+	WhoAgent = "agent"
+	WhoTTY   = "tty"
+)
+
+// DefaultInterval is how often the collector reads the spool.
+const DefaultInterval = 500 * time.Millisecond
+
+// maxRunning bounds the commands the collector tracks. The container writes
+// the spool, so past it the oldest command is logged as unfinished.
+const maxRunning = 10000
+
+// Record is a line of the command log.
+type Record struct {
+	Time    time.Time `json:"time"`
+	Session string    `json:"session"`
+	Who     string    `json:"who"`
+	// Parent is the process that started the shell, e.g. node for an agent
+	Parent  string `json:"parent,omitempty"`
+	Shell   string `json:"shell"`
+	Cwd     string `json:"cwd"`
+	Command string `json:"command"`
+	// Exit is nil when the command did not finish: killed, exec'd, or still
+	// running when the session ended
+	Exit       *int  `json:"exit"`
+	DurationMS int64 `json:"duration_ms"`
+}
+
+// event is a line of the spool, as written by the shell hooks.
+type event struct {
+	Event   string  `json:"event"` // start or end
+	ID      string  `json:"id"`
+	Time    float64 `json:"time"` // seconds since the epoch
+	Who     string  `json:"who"`
+	Parent  string  `json:"parent"`
+	Shell   string  `json:"shell"`
+	Cwd     string  `json:"cwd"`
+	Command string  `json:"command"`
+	Exit    *int    `json:"exit"`
+}
+
+// Collector copies the commands of the spool to the command log of a session
+// while the session runs.
+type Collector struct {
+	// Spool is the directory mounted at MountTarget
+	Spool string
+	// Log is the command log, LogFile in the session directory
+	Log string
+	// Interval is how often the spool is read, DefaultInterval if zero
+	Interval time.Duration
+	// Warn reports problems with the spool, which the container can write
+	Warn func(msg string)
+
+	mu      sync.Mutex
+	offset  int64
+	partial []byte
+	running map[string]*Record
+	stop    chan struct{}
+	done    chan struct{}
+}
+
+// Start collects until Close. The container is not running yet, so the
+// spool starts empty: what a previous session of the container wrote was
+// collected by that session, and the spool does not grow across restarts.
+// The spool is created anew, as the container could have replaced it with a
+// link to a host file.
+func (c *Collector) Start() error {
+	if err := os.MkdirAll(c.Spool, 0700); err != nil {
+		return err
+	}
+	spool := filepath.Join(c.Spool, SpoolFile)
+	if err := os.RemoveAll(spool); err != nil {
+		return fmt.Errorf("failed to remove the command audit spool: %w", err)
+	}
+	f, err := os.OpenFile(spool, os.O_CREATE|os.O_EXCL|os.O_WRONLY|spoolFlags, 0600) //nolint:gosec
+	if err != nil {
+		return fmt.Errorf("failed to create the command audit spool: %w", err)
+	}
+	if err := f.Close(); err != nil {
+		return err
+	}
+
+	c.running = make(map[string]*Record)
+	c.stop = make(chan struct{})
+	c.done = make(chan struct{})
+	interval := c.Interval
+	if interval == 0 {
+		interval = DefaultInterval
+	}
+	go func() {
+		defer close(c.done)
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-c.stop:
+				return
+			case <-ticker.C:
+				c.report(c.collect())
+			}
+		}
+	}()
+	return nil
+}
+
+// Close collects what is left and logs the commands that did not finish.
+func (c *Collector) Close() {
+	if c.stop == nil {
+		return
+	}
+	close(c.stop)
+	<-c.done
+	c.report(c.collect())
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	var unfinished []Record
+	for _, r := range c.running {
+		unfinished = append(unfinished, *r)
+	}
+	sortRecords(unfinished)
+	c.report(c.write(unfinished...))
+	c.running = nil
+}
+
+func (c *Collector) report(err error) {
+	if err != nil && c.Warn != nil {
+		c.Warn(err.Error())
+	}
+}
+
+// collect reads the events appended to the spool since the last call.
+func (c *Collector) collect() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	f, err := os.OpenFile(filepath.Join(c.Spool, SpoolFile), os.O_RDONLY|spoolFlags, 0) //nolint:gosec
+	if err != nil {
+		return fmt.Errorf("command audit spool: %w", err)
+	}
+	defer func() { _ = f.Close() }()
+	fi, err := f.Stat()
+	if err != nil {
+		return err
+	}
+	if !fi.Mode().IsRegular() {
+		return fmt.Errorf("the command audit spool was replaced in the container, it is not read")
+	}
+	var truncated error
+	if fi.Size() < c.offset {
+		// Only the container writes to the spool
+		truncated = fmt.Errorf("the command audit spool was truncated in the container, commands may be missing")
+		c.offset = 0
+		c.partial = nil
+	}
+	if _, err := f.Seek(c.offset, io.SeekStart); err != nil {
+		return err
+	}
+	data, err := io.ReadAll(f)
+	if err != nil {
+		return err
+	}
+	c.offset += int64(len(data))
+	data = append(c.partial, data...)
+
+	// A line without its newline is still being written
+	last := bytes.LastIndexByte(data, '\n')
+	c.partial = append([]byte(nil), data[last+1:]...)
+	var done []Record
+	for _, line := range bytes.Split(data[:last+1], []byte("\n")) {
+		done = append(done, c.apply(line)...)
+	}
+	if err := c.write(done...); err != nil {
+		return err
+	}
+	return truncated
+}
+
+// apply adds an event to the running commands and returns the commands it
+// finished or evicted, if any. Lines that are not events are skipped.
+func (c *Collector) apply(line []byte) []Record {
+	var e event
+	if len(bytes.TrimSpace(line)) == 0 || json.Unmarshal(line, &e) != nil || e.ID == "" {
+		return nil
+	}
+	switch e.Event {
+	case "start":
+		var evicted []Record
+		if _, ok := c.running[e.ID]; !ok && len(c.running) >= maxRunning {
+			evicted = append(evicted, c.evictOldest())
+		}
+		c.running[e.ID] = &Record{
+			Time:    epochTime(e.Time),
+			Session: filepath.Base(filepath.Dir(c.Log)),
+			Who:     e.Who,
+			Parent:  e.Parent,
+			Shell:   e.Shell,
+			Cwd:     e.Cwd,
+			Command: e.Command,
+		}
+		return evicted
+	case "end":
+		r, ok := c.running[e.ID]
+		if !ok {
+			return nil
+		}
+		delete(c.running, e.ID)
+		r.Exit = e.Exit
+		if d := epochTime(e.Time).Sub(r.Time); d > 0 {
+			r.DurationMS = d.Milliseconds()
+		}
+		return []Record{*r}
+	}
+	return nil
+}
+
+// evictOldest stops tracking the oldest running command and returns it,
+// without an exit status.
+func (c *Collector) evictOldest() Record {
+	var oldest string
+	for id, r := range c.running {
+		if oldest == "" || r.Time.Before(c.running[oldest].Time) {
+			oldest = id
+		}
+	}
+	r := *c.running[oldest]
+	delete(c.running, oldest)
+	return r
+}
+
+func (c *Collector) write(records ...Record) error {
+	if len(records) == 0 {
+		return nil
+	}
+	f, err := os.OpenFile(c.Log, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0600) //nolint:gosec
+	if err != nil {
+		return err
+	}
+	enc := json.NewEncoder(f)
+	enc.SetEscapeHTML(false)
+	for _, r := range records {
+		if err := enc.Encode(r); err != nil {
+			_ = f.Close()
+			return err
+		}
+	}
+	return f.Close()
+}
+
+// epochTime converts the seconds of $EPOCHREALTIME, to the microsecond.
+func epochTime(seconds float64) time.Time {
+	sec, frac := math.Modf(seconds)
+	return time.Unix(int64(sec), int64(math.Round(frac*1e6))*1000)
+}
@@ -0,0 +1,285 @@
+package audit
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"testing"
+	"time"
+)
+
+func appendSpool(t *testing.T, spool, lines string) {
+	t.Helper()
+	f, err := os.OpenFile(filepath.Join(spool, SpoolFile), os.O_WRONLY|os.O_APPEND, 0600)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() { _ = f.Close() }()
+	if _, err := f.WriteString(lines); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func readLog(t *testing.T, path string) []Record {
+	t.Helper()
+	f, err := os.Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() { _ = f.Close() }()
+	records, err := Read(f)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return records
+}
+
+func TestCollector(t *testing.T) {
+	tmpDir := t.TempDir()
+	sessionDir := filepath.Join(tmpDir, "20260101-120000")
+	if err := os.MkdirAll(sessionDir, 0700); err != nil {
+		t.Fatal(err)
+	}
+	var warnings []string
+	c := &Collector{
+		Spool:    filepath.Join(tmpDir, ".audit-spool"),
+		Log:      filepath.Join(sessionDir, LogFile),
+		Interval: time.Hour,
+		Warn:     func(msg string) { warnings = append(warnings, msg) },
+	}
+	if err := c.Start(); err != nil {
+		t.Fatal(err)
+	}
+
+	// An end without its start, garbage and a line still being written
+	appendSpool(t, c.Spool, `{"event":"start","id":"1-a","time":1767268800.5,"who":"agent","parent":"node","shell":"zsh","cwd":"/src","command":"make test"}
+{"event":"end","id":"9-z","time":1767268801,"exit":0}
+not json
+{"event":"start","id":"2-b","time":1767268801.25,"who":"tty","shell":"bash","cwd":"/src","command":"sleep 100"}
+{"event":"end","id":"1-a","time":1767268802.75,"ex`)
+	if err := c.collect(); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(c.Log); !os.IsNotExist(err) {
+		t.Fatal("Expected no record before the first command ends")
+	}
+
+	appendSpool(t, c.Spool, "it\":2}\n")
+	if err := c.collect(); err != nil {
+		t.Fatal(err)
+	}
+	records := readLog(t, c.Log)
+	if len(records) != 1 {
+		t.Fatalf("Expected 1 record, Got: %+v", records)
+	}
+	r := records[0]
+	if r.Command != "make test" || r.Who != WhoAgent || r.Parent != "node" || r.Cwd != "/src" || r.Session != "20260101-120000" {
+		t.Errorf("Unexpected record: %+v", r)
+	}
+	if r.Exit == nil || *r.Exit != 2 || r.DurationMS != 2250 {
+		t.Errorf("Expected exit 2 after 2250ms, Got: %v after %dms", r.Exit, r.DurationMS)
+	}
+	if !r.Time.Equal(time.Unix(1767268800, 500000000)) {
+		t.Errorf("Expected the start time, Got: %s", r.Time)
+	}
+
+	// The container cannot hide commands by truncating the spool
+	if err := os.Truncate(filepath.Join(c.Spool, SpoolFile), 0); err != nil {
+		t.Fatal(err)
+	}
+	appendSpool(t, c.Spool, `{"event":"start","id":"3-c","time":1767268803,"who":"agent","shell":"zsh","cwd":"/","command":"true"}`+"\n")
+	if err := c.collect(); err == nil {
+		t.Error("Expected an error for a truncated spool")
+	}
+
+	// Commands that did not finish are logged on Close
+	c.Close()
+	records = readLog(t, c.Log)
+	if len(records) != 3 {
+		t.Fatalf("Expected 3 records, Got: %+v", records)
+	}
+	for _, r := range records[1:] {
+		if r.Exit != nil {
+			t.Errorf("Expected no exit status for %q, Got: %d", r.Command, *r.Exit)
+		}
+	}
+	if records[1].Command != "sleep 100" || records[2].Command != "true" {
+		t.Errorf("Expected the unfinished commands oldest first, Got: %+v", records[1:])
+	}
+	if len(warnings) != 0 {
+		t.Errorf("Expected no warnings, Got: %v", warnings)
+	}
+
+	// A restarted container starts with an empty spool, private to the user
+	c = &Collector{Spool: c.Spool, Log: filepath.Join(tmpDir, LogFile), Interval: time.Hour}
+	if err := c.Start(); err != nil {
+		t.Fatal(err)
+	}
+	c.Close()
+	if _, err := os.Stat(c.Log); !os.IsNotExist(err) {
+		t.Error("Expected the restarted collector to skip the previous commands")
+	}
+	for path, mode := range map[string]os.FileMode{c.Spool: 0700, filepath.Join(c.Spool, SpoolFile): 0600} {
+		fi, err := os.Stat(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if fi.Mode().Perm() != mode {
+			t.Errorf("Expected %s to be %o, Got: %o", path, mode, fi.Mode().Perm())
+		}
+		if !fi.IsDir() && fi.Size() != 0 {
+			t.Errorf("Expected the spool to be emptied, Got: %d bytes", fi.Size())
+		}
+	}
+}
+
+func TestCollectorSpoolLink(t *testing.T) {
+	tmpDir := t.TempDir()
+	target := filepath.Join(tmpDir, ".bashrc")
+	if err := os.WriteFile(target, []byte("export PATH\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	c := &Collector{Spool: filepath.Join(tmpDir, ".audit-spool"), Log: filepath.Join(tmpDir, LogFile), Interval: time.Hour}
+	if err := c.Start(); err != nil {
+		t.Fatal(err)
+	}
+
+	// The container replaces the spool with a link to a host file
+	spool := filepath.Join(c.Spool, SpoolFile)
+	if err := os.Remove(spool); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Symlink(target, spool); err != nil {
+		t.Fatal(err)
+	}
+	if err := c.collect(); err == nil {
+		t.Error("Expected an error for a linked spool")
+	}
+	c.Close()
+
+	c = &Collector{Spool: c.Spool, Log: c.Log, Interval: time.Hour}
+	if err := c.Start(); err != nil {
+		t.Fatal(err)
+	}
+	c.Close()
+	if data, err := os.ReadFile(target); err != nil || string(data) != "export PATH\n" {
+		t.Errorf("Expected the linked file to be left alone, Got: %q (%v)", data, err)
+	}
+	if fi, err := os.Lstat(spool); err != nil || !fi.Mode().IsRegular() {
+		t.Errorf("Expected a new spool file, Got: %v (%v)", fi, err)
+	}
+}
+
+func TestCollectorMaxRunning(t *testing.T) {
+	c := &Collector{Log: filepath.Join(t.TempDir(), LogFile), running: make(map[string]*Record)}
+	var evicted []Record
+	for i := 0; i <= maxRunning; i++ {
+		line := fmt.Sprintf(`{"event":"start","id":"%d","time":%d,"command":"sleep %d"}`, i, 1767268800+i, i)
+		evicted = append(evicted, c.apply([]byte(line))...)
+	}
+	if len(c.running) != maxRunning {
+		t.Errorf("Expected %d running commands, Got: %d", maxRunning, len(c.running))
+	}
+	if len(evicted) != 1 || evicted[0].Command != "sleep 0" || evicted[0].Exit != nil {
+		t.Errorf("Expected the oldest command to be evicted unfinished, Got: %+v", evicted)
+	}
+}
+
+func TestQuery(t *testing.T) {
+	tmpDir := t.TempDir()
+	base := time.Date(2026, 1, 1, 12, 0, 0, 0, time.UTC)
+	exit := 0
+	sessions := map[string][]Record{
+		"s1": {
+			{Time: base, Who: WhoTTY, Command: "git status", Exit: &exit},
+			{Time: base.Add(2 * time.Hour), Who: WhoAgent, Command: "git push --force"},
+		},
+		"s2": {
+			{Time: base.Add(time.Hour), Who: WhoAgent, Command: "npm test", Exit: &exit},
+		},
+	}
+	var dirs []string
+	for id, records := range sessions {
+		dir := filepath.Join(tmpDir, id)
+		if err := os.MkdirAll(dir, 0700); err != nil {
+			t.Fatal(err)
+		}
+		c := &Collector{Log: filepath.Join(dir, LogFile)}
+		if err := c.write(records...); err != nil {
+			t.Fatal(err)
+		}
+		dirs = append(dirs, dir)
+	}
+	// A session without a command log
+	dirs = append(dirs, filepath.Join(tmpDir, "s3"))
+
+	tests := []struct {
+		name     string
+		filter   Filter
+		expected []string
+	}{
+		{"all, oldest first", Filter{}, []string{"git status", "npm test", "git push --force"}},
+		{"pattern", Filter{Pattern: regexp.MustCompile(`^git (push|reset)`)}, []string{"git push --force"}},
+		{"since", Filter{Since: base.Add(30 * time.Minute)}, []string{"npm test", "git push --force"}},
+		{"until", Filter{Until: base.Add(time.Hour)}, []string{"git status", "npm test"}},
+		{"who", Filter{Who: WhoTTY}, []string{"git status"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			records, err := Query(dirs, tt.filter)
+			if err != nil {
+				t.Fatal(err)
+			}
+			var got []string
+			for _, r := range records {
+				got = append(got, r.Command)
+			}
+			if strings.Join(got, "|") != strings.Join(tt.expected, "|") {
+				t.Errorf("Expected: %v, Got: %v", tt.expected, got)
+			}
+		})
+	}
+}
+
+func TestParseTime(t *testing.T) {
+	now := time.Date(2026, 1, 1, 12, 0, 0, 0, time.UTC)
+	tests := []struct {
+		input    string
+		expected time.Time
+	}{
+		{"2h", now.Add(-2 * time.Hour)},
+		{"2025-12-31", time.Date(2025, 12, 31, 0, 0, 0, 0, time.UTC)},
+		{"2025-12-31 08:30", time.Date(2025, 12, 31, 8, 30, 0, 0, time.UTC)},
+		{"2025-12-31T08:30:00Z", time.Date(2025, 12, 31, 8, 30, 0, 0, time.UTC)},
+	}
+	for _, tt := range tests {
+		got, err := ParseTime(tt.input, now)
+		if err != nil || !got.Equal(tt.expected) {
+			t.Errorf("Input: %s, Expected: %s, Got: %s (%v)", tt.input, tt.expected, got, err)
+		}
+	}
+	if _, err := ParseTime("yesterday", now); err == nil {
+		t.Error("Expected an error for an invalid time")
+	}
+}
+
+func TestWrite(t *testing.T) {
+	exit := 1
+	var sb strings.Builder
+	err := Write(&sb, []Record{
+		{Time: time.Now(), Who: WhoAgent, Cwd: "/src", Command: "make\n  test", Exit: &exit, DurationMS: 1500},
+		{Time: time.Now(), Who: WhoTTY, Cwd: "/src", Command: "sleep 100"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	lines := strings.Split(strings.TrimSpace(sb.String()), "\n")
+	if len(lines) != 3 {
+		t.Fatalf("Expected a header and 2 lines, Got: %q", sb.String())
+	}
+	if !strings.Contains(lines[1], "1.5s") || !strings.Contains(lines[1], "make test") {
+		t.Errorf("Expected the duration and the command on one line, Got: %q", lines[1])
+	}
+}
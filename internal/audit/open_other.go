@@ -0,0 +1,7 @@
+//go:build !linux && !darwin
+
+package audit
+
+// spoolFlags are empty where the container runs in a VM, which cannot link
+// to host files.
+const spoolFlags = 0
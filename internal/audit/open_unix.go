@@ -0,0 +1,9 @@
+//go:build linux || darwin
+
+package audit
+
+import "syscall"
+
+// spoolFlags keep the host from following a link the container put in place
+// of the spool, or blocking on a FIFO.
+const spoolFlags = syscall.O_NOFOLLOW | syscall.O_NONBLOCK
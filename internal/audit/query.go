@@ -0,0 +1,137 @@
+package audit
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+	"time"
+)
+
+// Filter selects records for ai-shell audit log. Zero values match all.
+type Filter struct {
+	Since time.Time
+	Until time.Time
+	// Pattern is a regular expression matched anywhere in the command
+	Pattern *regexp.Regexp
+	// Who is WhoAgent or WhoTTY
+	Who string
+}
+
+// Match reports whether the filter selects r.
+func (f Filter) Match(r Record) bool {
+	if !f.Since.IsZero() && r.Time.Before(f.Since) {
+		return false
+	}
+	if !f.Until.IsZero() && r.Time.After(f.Until) {
+		return false
+	}
+	if f.Who != "" && r.Who != f.Who {
+		return false
+	}
+	return f.Pattern == nil || f.Pattern.MatchString(r.Command)
+}
+
+// Read returns the records of a command log.
+func Read(r io.Reader) ([]Record, error) {
+	var records []Record
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), 16*1024*1024)
+	for scanner.Scan() {
+		line := scanner.Bytes()
+		if len(line) == 0 {
+			continue
+		}
+		var rec Record
+		if err := json.Unmarshal(line, &rec); err != nil {
+			return nil, fmt.Errorf("invalid command log line: %w", err)
+		}
+		records = append(records, rec)
+	}
+	return records, scanner.Err()
+}
+
+// Query returns the records of the given session directories that match
+// the filter, oldest first. Sessions without a command log are skipped.
+func Query(dirs []string, filter Filter) ([]Record, error) {
+	var matched []Record
+	for _, dir := range dirs {
+		f, err := os.Open(filepath.Join(dir, LogFile)) //nolint:gosec
+		if os.IsNotExist(err) {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		records, err := Read(f)
+		_ = f.Close()
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", dir, err)
+		}
+		for _, r := range records {
+			if filter.Match(r) {
+				matched = append(matched, r)
+			}
+		}
+	}
+	sortRecords(matched)
+	return matched, nil
+}
+
+// ParseTime parses the --since and --until values: an RFC 3339 time, a
+// local date or date and time, or a duration back from now such as 2h.
+func ParseTime(s string, now time.Time) (time.Time, error) {
+	if d, err := time.ParseDuration(s); err == nil {
+		return now.Add(-d), nil
+	}
+	if t, err := time.Parse(time.RFC3339, s); err == nil {
+		return t, nil
+	}
+	for _, layout := range []string{"2006-01-02 15:04:05", "2006-01-02 15:04", "2006-01-02"} {
+		if t, err := time.ParseInLocation(layout, s, now.Location()); err == nil {
+			return t, nil
+		}
+	}
+	return time.Time{}, fmt.Errorf("invalid time %q: use a duration such as 2h, a date (2006-01-02) or an RFC 3339 time", s)
+}
+
+// Write prints records as a table, for ai-shell audit log.
+func Write(w io.Writer, records []Record) error {
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "TIME\tWHO\tEXIT\tDURATION\tCWD\tCOMMAND")
+	for _, r := range records {
+		exit, duration := "-", "-"
+		if r.Exit != nil {
+			exit = strconv.Itoa(*r.Exit)
+			duration = (time.Duration(r.DurationMS) * time.Millisecond).String()
+		}
+		// One line per command
+		command := strings.Join(strings.Fields(r.Command), " ")
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t%s\t%s\n",
+			r.Time.Local().Format("2006-01-02 15:04:05"), r.Who, exit, duration, r.Cwd, command)
+	}
+	return tw.Flush()
+}
+
+// WriteJSON prints records as JSON Lines.
+func WriteJSON(w io.Writer, records []Record) error {
+	enc := json.NewEncoder(w)
+	enc.SetEscapeHTML(false)
+	for _, r := range records {
+		if err := enc.Encode(r); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func sortRecords(records []Record) {
+	sort.SliceStable(records, func(i, j int) bool { return records[i].Time.Before(records[j].Time) })
+}
@@ -0,0 +1,88 @@
+package config
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+)
+
+// DefaultApprovalTimeout is how long a command waits for a decision, in
+// seconds, unless approval.timeout is set.
+const DefaultApprovalTimeout = 120
+
+// Approval lists commands that wait for the user's approval on the host
+// before they run in the container.
+type Approval struct {
+	Commands []CommandRule `mapstructure:"commands" yaml:"commands,omitempty" json:"commands,omitempty"`
+	// Timeout is in seconds; a command without a decision by then is denied
+	Timeout int `mapstructure:"timeout" yaml:"timeout,omitempty" json:"timeout,omitempty"`
+}
+
+// CommandRule matches command lines. Pattern starts with the command name,
+// the rest is matched against its arguments, where * matches anything
+// (e.g. "git push*" or "kubectl delete *").
+type CommandRule struct {
+	Pattern string `mapstructure:"pattern" yaml:"pattern" json:"pattern"`
+	// OutsideWorkspace only matches when an argument is a path outside the workspace
+	OutsideWorkspace bool `mapstructure:"outside_workspace" yaml:"outside_workspace,omitempty" json:"outside_workspace,omitempty"`
+	// FromPipe only matches when the command reads from a pipe (curl ... | sh)
+	FromPipe bool `mapstructure:"from_pipe" yaml:"from_pipe,omitempty" json:"from_pipe,omitempty"`
+}
+
+// Command is the name of the command the rule is about.
+func (r CommandRule) Command() string {
+	if fields := strings.Fields(r.Pattern); len(fields) > 0 {
+		return fields[0]
+	}
+	return ""
+}
+
+// Args is the pattern of the arguments, with its whitespace normalized.
+func (r CommandRule) Args() string {
+	if fields := strings.Fields(r.Pattern); len(fields) > 1 {
+		return strings.Join(fields[1:], " ")
+	}
+	return ""
+}
+
+// Enabled reports whether any command needs approval.
+func (a Approval) Enabled() bool {
+	return len(a.Commands) > 0
+}
+
+// Binaries returns the names of the commands that need a shim, sorted.
+func (a Approval) Binaries() []string {
+	var names []string
+	for _, r := range a.Commands {
+		if name := r.Command(); !slices.Contains(names, name) {
+			names = append(names, name)
+		}
+	}
+	slices.Sort(names)
+	return names
+}
+
+// EffectiveTimeout returns the timeout in seconds.
+func (a Approval) EffectiveTimeout() int {
+	if a.Timeout == 0 {
+		return DefaultApprovalTimeout
+	}
+	return a.Timeout
+}
+
+// Validate checks the rules and the timeout.
+func (a Approval) Validate() error {
+	for _, r := range a.Commands {
+		name := r.Command()
+		if name == "" {
+			return fmt.Errorf("approval.commands: empty pattern")
+		}
+		if !commandPattern.MatchString(name) {
+			return fmt.Errorf("approval.commands: %q must start with a command name", r.Pattern)
+		}
+	}
+	if a.Timeout < 0 {
+		return fmt.Errorf("approval.timeout: must be positive")
+	}
+	return nil
+}
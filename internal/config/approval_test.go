@@ -0,0 +1,47 @@
+package config
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestApprovalValidate(t *testing.T) {
+	valid := Approval{Commands: []CommandRule{{Pattern: "git push*"}, {Pattern: "rm -rf *", OutsideWorkspace: true}}, Timeout: 60}
+	if err := valid.Validate(); err != nil {
+		t.Errorf("Expected valid, Got: %v", err)
+	}
+	invalid := []Approval{
+		{Commands: []CommandRule{{Pattern: "  "}}},
+		{Commands: []CommandRule{{Pattern: "/usr/bin/git push"}}},
+		{Commands: []CommandRule{{Pattern: "git;rm push"}}},
+		{Timeout: -1},
+	}
+	for _, a := range invalid {
+		if err := a.Validate(); err == nil {
+			t.Errorf("Input: %+v, Expected: error", a)
+		}
+	}
+}
+
+func TestApprovalRules(t *testing.T) {
+	r := CommandRule{Pattern: "  kubectl   delete  * "}
+	if r.Command() != "kubectl" || r.Args() != "delete *" {
+		t.Errorf("Input: %q, Got: %q %q", r.Pattern, r.Command(), r.Args())
+	}
+
+	a := Approval{Commands: []CommandRule{{Pattern: "git push*"}, {Pattern: "gh pr merge*"}, {Pattern: "git reset --hard*"}}}
+	if got := a.Binaries(); !slices.Equal(got, []string{"gh", "git"}) {
+		t.Errorf("Expected: [gh git], Got: %v", got)
+	}
+	if a.EffectiveTimeout() != DefaultApprovalTimeout {
+		t.Errorf("Expected the default timeout, Got: %d", a.EffectiveTimeout())
+	}
+}
+
+func TestMergeConfigApproval(t *testing.T) {
+	base := &Config{Approval: Approval{Commands: []CommandRule{{Pattern: "git push*"}}}}
+	mergeConfig(base, &Config{Approval: Approval{Commands: []CommandRule{{Pattern: "git push*"}, {Pattern: "kubectl delete *"}}, Timeout: 30}})
+	if len(base.Approval.Commands) != 2 || base.Approval.Timeout != 30 {
+		t.Errorf("Unexpected approval settings: %+v", base.Approval)
+	}
+}
@@ -1,28 +1,279 @@
 package config
 
+import (
+	"fmt"
+	"net"
+	"path"
+	"slices"
+	"strings"
+)
+
+// DefaultEnvVars are passed from the host when the config lists no variables.
+var DefaultEnvVars = []string{"CLAUDE_CODE_USE_VERTEX", "CLOUD_ML_REGION", "ANTHROPIC_VERTEX_PROJECT_ID", "GOOGLE_CLOUD_PROJECT", "GEMINI_API_KEY", "GH_TOKEN"}
+
 // Config represents the structure of ai-shell.yaml or config.yaml
 type Config struct {
-	EnvVars    []string   `mapstructure:"env_vars" yaml:"env_vars"`
-	Mounts     []Mount    `mapstructure:"mounts" yaml:"mounts"`
-	PodmanArgs []string   `mapstructure:"podman_args" yaml:"podman_args"`
-	Registries []Registry `mapstructure:"registries" yaml:"registries"`
-	SCMs       []SCM      `mapstructure:"scms" yaml:"scms"`
+	EnvVars    []string   `mapstructure:"env_vars" yaml:"env_vars" json:"env_vars"`
+	Mounts     []Mount    `mapstructure:"mounts" yaml:"mounts" json:"mounts"`
+	PodmanArgs []string   `mapstructure:"podman_args" yaml:"podman_args" json:"podman_args"`
+	Registries []Registry `mapstructure:"registries" yaml:"registries" json:"registries"`
+	SCMs       []SCM      `mapstructure:"scms" yaml:"scms" json:"scms"`
+
+	// RemoteEnv are NAME=value pairs the entrypoint sets, expanding ${VAR}
+	// and ${VAR:-default} against the container's environment, e.g.
+	// PATH=${PATH}:/extra. They come from ${containerEnv:VAR} in remoteEnv.
+	RemoteEnv []string `mapstructure:"remote_env" yaml:"remote_env" json:"remote_env,omitempty"`
+
+	// Profile, SSH and NetHost are defaults for the --profile, --ssh and
+	// --net-host flags; the flags can only turn SSH and host networking on.
+	Profile string `mapstructure:"profile" yaml:"profile" json:"profile,omitempty"`
+	SSH     bool   `mapstructure:"ssh" yaml:"ssh" json:"ssh,omitempty"`
+	NetHost bool   `mapstructure:"net_host" yaml:"net_host" json:"net_host,omitempty"`
+
+	// Offline cuts the container off the network (like the --offline flag).
+	// With ModelEgress, the model APIs stay reachable through the egress proxy.
+	Offline     bool `mapstructure:"offline" yaml:"offline" json:"offline,omitempty"`
+	ModelEgress bool `mapstructure:"model_egress" yaml:"model_egress" json:"model_egress,omitempty"`
+	// ModelHosts replaces DefaultModelHosts, in the format of Network.Allow.
+	ModelHosts []string `mapstructure:"model_hosts" yaml:"model_hosts" json:"model_hosts,omitempty"`
+
+	// HostPorts are forwarded from the container's loopback to the host, e.g.
+	// to reach a KinD API server without host networking.
+	HostPorts []int `mapstructure:"host_ports" yaml:"host_ports" json:"host_ports,omitempty"`
+
+	// Ports are published on the host's loopback interface.
+	Ports []Port `mapstructure:"ports" yaml:"ports" json:"ports,omitempty"`
+
+	// DNS replaces the container's nameservers and ExtraHosts adds "name:ip"
+	// entries to its /etc/hosts, e.g. for internal services.
+	DNS        []string `mapstructure:"dns" yaml:"dns" json:"dns,omitempty"`
+	ExtraHosts []string `mapstructure:"extra_hosts" yaml:"extra_hosts" json:"extra_hosts,omitempty"`
+
+	// Proxy is set for git, npm, pip and the agents alike.
+	Proxy Proxy `mapstructure:"proxy" yaml:"proxy" json:"proxy"`
+
+	// CACerts are PEM files on the host that the entrypoint adds to the
+	// container's trust store.
+	CACerts []string `mapstructure:"ca_certs" yaml:"ca_certs" json:"ca_certs,omitempty"`
+
+	// Network filters the egress of the container, see Network.Allow.
+	Network Network `mapstructure:"network" yaml:"network" json:"network"`
+
+	// Security hardens the container, see SecurityStrict and SecurityParanoid.
+	Security Security `mapstructure:"security" yaml:"security" json:"security"`
+
+	// Record records each interactive session in asciicast format, like the
+	// --record flag
+	Record bool `mapstructure:"record" yaml:"record" json:"record,omitempty"`
+
+	// CommandAudit logs each command run in the container, by an agent or at
+	// the terminal, to the session directory
+	CommandAudit bool `mapstructure:"command_audit" yaml:"command_audit" json:"command_audit,omitempty"`
+
+	// Approval holds commands back until the user approves them on the host
+	Approval Approval `mapstructure:"approval" yaml:"approval" json:"approval"`
+
+	// Sandbox runs the commands of agents in a bubblewrap sandbox
+	Sandbox Sandbox `mapstructure:"sandbox" yaml:"sandbox" json:"sandbox"`
+
+	// Resources limits CPU, memory, processes and disk of the container. An
+	// organization policy (PolicyPath) can cap them.
+	Resources Resources `mapstructure:"resources" yaml:"resources" json:"resources"`
+
+	// ProtectedPaths replaces the built-in list of host paths that config mounts
+	// may not expose. It is only honored from the global config.
+	ProtectedPaths []ProtectedPath `mapstructure:"protected_paths" yaml:"protected_paths" json:"protected_paths"`
+
+	Lifecycle Lifecycle `mapstructure:"lifecycle" yaml:"lifecycle" json:"lifecycle"`
+
+	// Image replaces the profile image; Build builds the image from a Dockerfile instead
+	Image string `mapstructure:"image" yaml:"image" json:"image,omitempty"`
+	Build *Build `mapstructure:"build" yaml:"build" json:"build,omitempty"`
+
+	// WorkspaceMount replaces the mirrored bind mount of the project and
+	// WorkspaceFolder the working directory; by default both are the host path.
+	WorkspaceFolder string `mapstructure:"workspace_folder" yaml:"workspace_folder" json:"workspace_folder,omitempty"`
+	WorkspaceMount  *Mount `mapstructure:"workspace_mount" yaml:"workspace_mount" json:"workspace_mount,omitempty"`
+
+	// RemoteUser is the user the shell runs as ('ai' by default). ContainerUser
+	// is only validated: the entrypoint always starts as root.
+	RemoteUser    string `mapstructure:"remote_user" yaml:"remote_user" json:"remote_user,omitempty"`
+	ContainerUser string `mapstructure:"container_user" yaml:"container_user" json:"container_user,omitempty"`
+
+	// Features are only read from devcontainer.json and baked into a derived image
+	Features []Feature `mapstructure:"-" yaml:"-" json:"-"`
 }
 
+// Mount types
+const (
+	MountTypeBind   = "bind"
+	MountTypeVolume = "volume"
+	MountTypeTmpfs  = "tmpfs"
+)
+
 type Mount struct {
-	Source  string `mapstructure:"source" yaml:"source"`
-	Target  string `mapstructure:"target" yaml:"target"`
-	Options string `mapstructure:"options" yaml:"options"`
+	// Type is bind (the default), volume or tmpfs. The source of a volume
+	// mount is a podman volume name; tmpfs mounts have no source.
+	Type    string `mapstructure:"type" yaml:"type" json:"type,omitempty"`
+	Source  string `mapstructure:"source" yaml:"source" json:"source"`
+	Target  string `mapstructure:"target" yaml:"target" json:"target"`
+	Options string `mapstructure:"options" yaml:"options" json:"options"`
+
+	// Required makes a missing bind source an error instead of skipping the
+	// mount; Create creates it as a directory.
+	Required bool `mapstructure:"required" yaml:"required" json:"required,omitempty"`
+	Create   bool `mapstructure:"create" yaml:"create" json:"create,omitempty"`
+	// ReadOnly wins over ro/rw in Options when set.
+	ReadOnly *bool `mapstructure:"readonly" yaml:"readonly" json:"readonly,omitempty"`
+	// Relabel is "shared" (:z) or "private" (:Z) SELinux relabeling of a bind source.
+	Relabel string `mapstructure:"relabel" yaml:"relabel" json:"relabel,omitempty"`
+	// IDMap maps the owner of the files between host and container.
+	IDMap *IDMap `mapstructure:"idmap" yaml:"idmap" json:"idmap,omitempty"`
+}
+
+// IDMap is an idmapped mount in podman's syntax: "container-host-size"
+// ranges separated by '#', e.g. "0-1000-1".
+type IDMap struct {
+	UIDs string `mapstructure:"uids" yaml:"uids" json:"uids,omitempty"`
+	GIDs string `mapstructure:"gids" yaml:"gids" json:"gids,omitempty"`
+}
+
+// Build describes how to build the project image.
+type Build struct {
+	Dockerfile string            `mapstructure:"dockerfile" yaml:"dockerfile" json:"dockerfile"`
+	Context    string            `mapstructure:"context" yaml:"context" json:"context"`
+	Args       map[string]string `mapstructure:"args" yaml:"args" json:"args"`
+}
+
+// ProtectedPath is a host path that config mounts are not allowed to expose.
+type ProtectedPath struct {
+	Path string `mapstructure:"path" yaml:"path" json:"path"`
+	// Action is "deny" (refuse the mount, the default) or "readonly" (force ro).
+	Action string `mapstructure:"action" yaml:"action" json:"action"`
+	// Exact only matches the path itself and its ancestors, so that e.g. $HOME
+	// can be protected without blocking mounts of its subdirectories.
+	Exact bool `mapstructure:"exact" yaml:"exact" json:"exact"`
+}
+
+// Lifecycle holds the devcontainer lifecycle commands. Commands of the same
+// stage run in parallel. Initialize runs on the host before the home volume is
+// created, OnCreate, UpdateContent and PostCreate run once per home volume,
+// PostStart on every container start and PostAttach on every attach.
+type Lifecycle struct {
+	Initialize    []Command `mapstructure:"initialize" yaml:"initialize" json:"initialize"`
+	OnCreate      []Command `mapstructure:"on_create" yaml:"on_create" json:"on_create"`
+	UpdateContent []Command `mapstructure:"update_content" yaml:"update_content" json:"update_content"`
+	PostCreate    []Command `mapstructure:"post_create" yaml:"post_create" json:"post_create"`
+	PostStart     []Command `mapstructure:"post_start" yaml:"post_start" json:"post_start"`
+	PostAttach    []Command `mapstructure:"post_attach" yaml:"post_attach" json:"post_attach"`
+}
+
+// Command is a single lifecycle command, either a shell string or an argv list.
+type Command struct {
+	Name  string   `mapstructure:"name" yaml:"name" json:"name,omitempty"`
+	Shell string   `mapstructure:"shell" yaml:"shell" json:"shell,omitempty"`
+	Args  []string `mapstructure:"args" yaml:"args" json:"args,omitempty"`
 }
 
 type Registry struct {
-	Registry    string `mapstructure:"registry" yaml:"registry"`
-	UsernameEnv string `mapstructure:"username_env" yaml:"username_env"`
-	TokenEnv    string `mapstructure:"token_env" yaml:"token_env"`
+	Registry    string `mapstructure:"registry" yaml:"registry" json:"registry"`
+	UsernameEnv string `mapstructure:"username_env" yaml:"username_env" json:"username_env"`
+	TokenEnv    string `mapstructure:"token_env" yaml:"token_env" json:"token_env"`
 }
 
 type SCM struct {
-	Host        string `mapstructure:"host" yaml:"host"`
-	TokenEnv    string `mapstructure:"token_env" yaml:"token_env"`
-	UsernameEnv string `mapstructure:"username_env" yaml:"username_env"`
+	Host        string `mapstructure:"host" yaml:"host" json:"host"`
+	TokenEnv    string `mapstructure:"token_env" yaml:"token_env" json:"token_env"`
+	UsernameEnv string `mapstructure:"username_env" yaml:"username_env" json:"username_env"`
+}
+
+// EffectiveProfile returns the profile selected on the command line, falling
+// back to the one from the config.
+func (c *Config) EffectiveProfile(flag string) string {
+	if flag != "" || c == nil {
+		return flag
+	}
+	return c.Profile
+}
+
+// Validate checks the mounts and network settings of the config.
+func (c *Config) Validate() error {
+	for i, m := range c.Mounts {
+		if err := m.Validate(); err != nil {
+			return fmt.Errorf("mounts[%d]: %w", i, err)
+		}
+	}
+	if c.WorkspaceMount != nil {
+		if err := c.WorkspaceMount.Validate(); err != nil {
+			return fmt.Errorf("workspace_mount: %w", err)
+		}
+		if c.WorkspaceMount.Type == MountTypeTmpfs {
+			return fmt.Errorf("workspace_mount: the workspace cannot be a tmpfs")
+		}
+	}
+	for _, a := range slices.Concat(c.ModelHosts, c.Network.Allow) {
+		if _, err := ParseAllowRule(a); err != nil {
+			return err
+		}
+	}
+	if c.NetHost && c.Network.Filtered() {
+		return fmt.Errorf("net_host and network.allow cannot be combined")
+	}
+	if c.Offline && c.NetHost {
+		return fmt.Errorf("offline and net_host cannot be combined")
+	}
+	for _, p := range c.HostPorts {
+		if p < 1 || p > 65535 {
+			return fmt.Errorf("host_ports: invalid port %d", p)
+		}
+	}
+	for _, p := range c.Ports {
+		if err := p.Validate(); err != nil {
+			return fmt.Errorf("ports: %w", err)
+		}
+	}
+	if len(c.HostPorts) > 0 && c.Network.Filtered() {
+		return fmt.Errorf("host_ports and network.allow cannot be combined")
+	}
+	for _, d := range c.DNS {
+		if net.ParseIP(d) == nil {
+			return fmt.Errorf("dns: invalid nameserver %q", d)
+		}
+	}
+	for _, h := range c.ExtraHosts {
+		if err := validateExtraHost(h); err != nil {
+			return err
+		}
+	}
+	if err := c.Proxy.Validate(); err != nil {
+		return err
+	}
+	if err := c.Approval.Validate(); err != nil {
+		return err
+	}
+	if err := c.Sandbox.Validate(); err != nil {
+		return err
+	}
+	if err := c.Resources.Validate(); err != nil {
+		return err
+	}
+	if err := c.Security.Validate(); err != nil {
+		return err
+	}
+	if c.WorkspaceFolder != "" && !path.IsAbs(c.WorkspaceFolder) {
+		return fmt.Errorf("workspace_folder %s is not an absolute path", c.WorkspaceFolder)
+	}
+	for _, v := range slices.Concat(c.EnvVars, c.RemoteEnv) {
+		if name, _, _ := strings.Cut(v, "="); IsReservedEnv(name) {
+			return fmt.Errorf("env_vars: %s is set by ai-shell", name)
+		}
+	}
+	return nil
+}
+
+// IsReservedEnv reports whether name is one of the variables ai-shell sets
+// in the container to run its entrypoint and hooks. The config env comes
+// later on the podman command line, where the last value wins.
+func IsReservedEnv(name string) bool {
+	return strings.HasPrefix(name, "AI_SHELL_") || strings.HasPrefix(name, "HOST_") || name == "BASH_ENV"
 }
@@ -3,6 +3,7 @@ package config
 import (
 	"os"
 	"path/filepath"
+	"strings"
 	"testing"
 )
 
@@ -55,3 +56,40 @@ func TestFindUpward(t *testing.T) {
 		t.Errorf("Expected empty string, got %s", found)
 	}
 }
+
+func TestValidateReservedEnv(t *testing.T) {
+	tests := []struct {
+		cfg   Config
+		valid bool
+	}{
+		{Config{EnvVars: []string{"GH_TOKEN", "FOO=bar"}}, true},
+		{Config{EnvVars: []string{"AI_SHELL_USER=root"}}, false},
+		{Config{EnvVars: []string{"AI_SHELL_SECURITY"}}, false},
+		{Config{EnvVars: []string{"HOST_USER=other"}}, false},
+		{Config{EnvVars: []string{"BASH_ENV=/tmp/x"}}, false},
+		{Config{RemoteEnv: []string{"AI_SHELL_WORKSPACE=/"}}, false},
+		{Config{RemoteEnv: []string{"PATH=${PATH}:/extra"}}, true},
+	}
+	for _, tt := range tests {
+		if err := tt.cfg.Validate(); (err == nil) != tt.valid {
+			t.Errorf("Input: %v %v, Expected valid: %v, Got: %v", tt.cfg.EnvVars, tt.cfg.RemoteEnv, tt.valid, err)
+		}
+	}
+}
+
+func TestTrustWarning(t *testing.T) {
+	cfg := &Config{
+		Lifecycle: Lifecycle{Initialize: []Command{{Shell: "make prepare"}, {Args: []string{"./init.sh", "--fast"}}}},
+		Build:     &Build{Dockerfile: "/src/.devcontainer/Dockerfile"},
+		Features:  []Feature{{ID: "node"}, {ID: "linters"}},
+	}
+	warning := strings.Join(trustWarning(cfg), "\n")
+	for _, expected := range []string{"run commands on your host", "$ make prepare", "$ ./init.sh --fast", "builds /src/.devcontainer/Dockerfile", "features node, linters"} {
+		if !strings.Contains(warning, expected) {
+			t.Errorf("Expected %q in the warning, Got:\n%s", expected, warning)
+		}
+	}
+	if len(trustWarning(nil)) == 0 {
+		t.Error("Expected a warning for a config that did not load")
+	}
+}
@@ -0,0 +1,133 @@
+package config
+
+import (
+	"fmt"
+	"os"
+	"path"
+	"runtime"
+)
+
+// Container paths of ai-shell's own mounts
+const (
+	ConfigTarget    = "/etc/ai-shell/config.yaml"
+	GitConfigTarget = "/etc/ai-shell/gitconfig.host"
+	CACertsTarget   = "/etc/ai-shell/ca-certs.pem"
+	// DevContainerTarget is where VS Code mounts a generated devcontainer.json
+	DevContainerTarget = "/etc/ai-shell/devcontainer.json"
+	// RuntimeTarget holds the approval shims and the command audit spool
+	RuntimeTarget = "/run/ai-shell"
+)
+
+// imageScripts are the files of ai-shell's images that run its entrypoint
+// and hooks; a mount over one of them would turn a gate off.
+var imageScripts = []string{
+	"/usr/local/bin/configure.sh",
+	"/usr/local/bin/lifecycle.sh",
+	"/usr/local/bin/sandbox-exec",
+	"/usr/local/bin/approval-shim",
+	"/usr/local/lib/ai-shell",
+	"/etc/zshenv",
+	"/etc/zsh/zshenv",
+	"/etc/zshrc",
+	"/etc/bashrc",
+	"/etc/bash.bashrc",
+}
+
+// ReservedTarget is a container path that ai-shell mounts itself.
+type ReservedTarget struct {
+	Path string
+	Name string
+	// Tree reserves everything below Path too
+	Tree bool
+}
+
+// HostHomeRoot is the parent of home directories on the host; the home volume
+// is mounted at the same path in the container.
+func HostHomeRoot() string {
+	if runtime.GOOS == "darwin" {
+		return "/Users"
+	}
+	return "/home"
+}
+
+// HomeTarget is where the home volume is mounted.
+func HomeTarget() string {
+	return fmt.Sprintf("%s/%s", HostHomeRoot(), os.Getenv("USER"))
+}
+
+// WorkspaceTarget is where the project in pwd is mounted.
+func (c *Config) WorkspaceTarget(pwd string) string {
+	if c != nil && c.WorkspaceMount != nil {
+		return c.WorkspaceMount.Target
+	}
+	if c != nil && c.WorkspaceFolder != "" {
+		return c.WorkspaceFolder
+	}
+	return pwd
+}
+
+// ReservedTargets lists the mounts every ai-shell container has.
+func (c *Config) ReservedTargets(pwd string) []ReservedTarget {
+	reserved := []ReservedTarget{
+		{Path: c.WorkspaceTarget(pwd), Name: "the workspace"},
+		{Path: HomeTarget(), Name: "the home volume"},
+		// The config, the host git config, the CA certificates, the
+		// kubeconfig and a generated devcontainer.json
+		{Path: "/etc/ai-shell", Name: "ai-shell's configuration", Tree: true},
+		{Path: RuntimeTarget, Name: "ai-shell's approval and audit mounts", Tree: true},
+	}
+	for _, p := range imageScripts {
+		reserved = append(reserved, ReservedTarget{Path: p, Name: "ai-shell's scripts", Tree: true})
+	}
+	return reserved
+}
+
+// CheckMountConflicts reports config mounts that would shadow one of
+// ai-shell's own mounts, i.e. whose target is a reserved path or one of its
+// parents, or below a reserved tree. Mounts below the workspace or the home
+// directory are fine.
+func (c *Config) CheckMountConflicts(pwd string) error {
+	reserved := c.ReservedTargets(pwd)
+	for _, m := range c.Mounts {
+		target := path.Clean(os.ExpandEnv(m.Target))
+		for _, r := range reserved {
+			if isPathWithin(r.Path, target) || (r.Tree && isPathWithin(target, r.Path)) {
+				return fmt.Errorf("mount %s would shadow %s at %s", m.Target, r.Name, r.Path)
+			}
+		}
+	}
+	return nil
+}
+
+// dedupeMounts keeps the last mount for each target, in the position of
+// that last mount, and warns about the ones it drops. Targets are compared
+// after expansion, as Run mounts them.
+func dedupeMounts(mounts []Mount) []Mount {
+	key := func(m Mount) string { return path.Clean(os.ExpandEnv(m.Target)) }
+	last := make(map[string]int, len(mounts))
+	for i, m := range mounts {
+		last[key(m)] = i
+	}
+	if len(last) == len(mounts) {
+		return mounts
+	}
+
+	deduped := make([]Mount, 0, len(last))
+	for i, m := range mounts {
+		if last[key(m)] != i {
+			fmt.Printf("⚠️  Mount of %s at %s is replaced by a later mount with the same target.\n", m.Source, m.Target)
+			continue
+		}
+		deduped = append(deduped, m)
+	}
+	return deduped
+}
+
+// isPathWithin reports whether p is dir or below it.
+func isPathWithin(p, dir string) bool {
+	p, dir = path.Clean(p), path.Clean(dir)
+	if dir == "/" || p == dir {
+		return true
+	}
+	return len(p) > len(dir) && p[:len(dir)] == dir && p[len(dir)] == '/'
+}
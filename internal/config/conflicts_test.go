@@ -0,0 +1,82 @@
+package config
+
+import "testing"
+
+func TestCheckMountConflicts(t *testing.T) {
+	t.Setenv("USER", "me")
+	t.Setenv("DATA", "/data")
+	home := HomeTarget()
+	pwd := home + "/src/project"
+
+	tests := []struct {
+		target   string
+		conflict bool
+	}{
+		{"/data", false},
+		{"$DATA", false},
+		{home + "/.kube", false},
+		{pwd + "/node_modules", false},
+		{pwd, true},
+		{pwd + "/", true},
+		{home, true},
+		{HostHomeRoot(), true},
+		{"/", true},
+		{"/etc/ai-shell", true},
+		{ConfigTarget, true},
+		{GitConfigTarget, true},
+		{"/etc/ai-shell/other", true},
+		{DevContainerTarget, true},
+		{"/run", true},
+		{"/run/ai-shell/approval", true},
+		{"/run/ai-shell/audit", true},
+		{"/run/other", false},
+		{"/usr/local/bin/configure.sh", true},
+		{"/usr/local/bin/approval-shim", true},
+		{"/usr/local/lib/ai-shell/approval-path.sh", true},
+		{"/usr/local/bin/kubectl", false},
+		{"/etc/zshenv", true},
+	}
+
+	for _, tt := range tests {
+		cfg := &Config{Mounts: []Mount{{Source: "/src", Target: tt.target}}}
+		err := cfg.CheckMountConflicts(pwd)
+		if (err != nil) != tt.conflict {
+			t.Errorf("Input: %s, Expected conflict: %v, Got: %v", tt.target, tt.conflict, err)
+		}
+	}
+
+	// A moved workspace frees its host path and reserves the new one
+	cfg := &Config{WorkspaceFolder: "/workspace", Mounts: []Mount{{Source: "/src", Target: pwd}}}
+	if err := cfg.CheckMountConflicts(pwd); err != nil {
+		t.Errorf("Expected the host path of the workspace to be free, got: %v", err)
+	}
+	cfg = &Config{WorkspaceFolder: "/workspace", Mounts: []Mount{{Source: "/src", Target: "/workspace"}}}
+	if err := cfg.CheckMountConflicts(pwd); err == nil {
+		t.Error("Expected a conflict with the moved workspace")
+	}
+}
+
+func TestMergeConfigDedupesMounts(t *testing.T) {
+	t.Setenv("AI_SHELL_TEST_TARGET", "/cfg")
+	base := &Config{Mounts: []Mount{
+		{Source: "/global/kube", Target: "/kube"},
+		{Source: "/global/data", Target: "/data"},
+		{Source: "/global/cfg", Target: "$AI_SHELL_TEST_TARGET"},
+	}}
+	override := &Config{Mounts: []Mount{
+		{Source: "/project/kube", Target: "/kube/"},
+		{Source: "/project/cache", Target: "/cache"},
+		{Source: "/project/cfg", Target: "/cfg"},
+	}}
+	mergeConfig(base, override)
+
+	expected := []string{"/global/data", "/project/kube", "/project/cache", "/project/cfg"}
+	if len(base.Mounts) != len(expected) {
+		t.Fatalf("Expected %d mounts, got %+v", len(expected), base.Mounts)
+	}
+	for i, src := range expected {
+		if base.Mounts[i].Source != src {
+			t.Errorf("Mount %d: Expected: %s, Got: %s", i, src, base.Mounts[i].Source)
+		}
+	}
+}
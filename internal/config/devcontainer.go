@@ -1,22 +1,66 @@
 package config
 
 import (
+	"encoding/csv"
 	"encoding/json"
 	"fmt"
 	"os"
+	"path"
+	"path/filepath"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
 
 	"github.com/tailscale/hujson"
 )
 
 // DevContainerConfig represents the subset of devcontainer.json we support.
 type DevContainerConfig struct {
+	Name         string             `json:"name,omitempty"`
 	Image        string             `json:"image,omitempty"`
 	Build        *DevContainerBuild `json:"build,omitempty"`
 	RunArgs      []string           `json:"runArgs,omitempty"`
 	Mounts       []string           `json:"mounts,omitempty"` // Strings like "source=...,target=..."
 	ContainerEnv map[string]string  `json:"containerEnv,omitempty"`
 	RemoteEnv    map[string]string  `json:"remoteEnv,omitempty"`
-	// We could add 'features' later if we support them
+	// Features maps a feature reference to its options. Only local features
+	// ("./features/foo") are supported.
+	Features map[string]json.RawMessage `json:"features,omitempty"`
+
+	InitializeCommand    LifecycleCommand `json:"initializeCommand,omitempty"`
+	OnCreateCommand      LifecycleCommand `json:"onCreateCommand,omitempty"`
+	UpdateContentCommand LifecycleCommand `json:"updateContentCommand,omitempty"`
+	PostCreateCommand    LifecycleCommand `json:"postCreateCommand,omitempty"`
+	PostStartCommand     LifecycleCommand `json:"postStartCommand,omitempty"`
+	PostAttachCommand    LifecycleCommand `json:"postAttachCommand,omitempty"`
+
+	// WorkspaceMount and WorkspaceFolder replace path mirroring. RemoteUser is
+	// the user of the shell; ContainerUser is only checked, as ai-shell's
+	// entrypoint has to start as root.
+	WorkspaceMount  string `json:"workspaceMount,omitempty"`
+	WorkspaceFolder string `json:"workspaceFolder,omitempty"`
+	RemoteUser      string `json:"remoteUser,omitempty"`
+	ContainerUser   string `json:"containerUser,omitempty"`
+
+	// ForwardPorts are published like Config.Ports, labeled by PortsAttributes
+	ForwardPorts    []any                     `json:"forwardPorts,omitempty"`
+	PortsAttributes map[string]PortAttributes `json:"portsAttributes,omitempty"`
+
+	// Only written by GenerateDevContainer, so VS Code runs ai-shell's entrypoint
+	OverrideCommand *bool `json:"overrideCommand,omitempty"`
+
+	// Customizations["ai-shell"] holds a full ai-shell config (same keys as
+	// config.yaml), so one file can serve both VS Code and ai-shell.
+	Customizations map[string]json.RawMessage `json:"customizations,omitempty"`
+
+	// path is the file the config was read from, used for variable substitution
+	path string
+}
+
+// PortAttributes is the subset of a portsAttributes entry we support.
+type PortAttributes struct {
+	Label string `json:"label,omitempty"`
 }
 
 type DevContainerBuild struct {
@@ -25,6 +69,73 @@ type DevContainerBuild struct {
 	Args       map[string]string `json:"args,omitempty"`
 }
 
+// LifecycleCommand accepts the three devcontainer forms of a lifecycle
+// command: a shell string, an argv array, or an object of named commands
+// (each a string or array) that run in parallel.
+type LifecycleCommand []Command
+
+func (lc *LifecycleCommand) UnmarshalJSON(data []byte) error {
+	cmd, err := parseCommand("", data)
+	if err == nil {
+		*lc = LifecycleCommand{cmd}
+		return nil
+	}
+
+	var parallel map[string]json.RawMessage
+	if json.Unmarshal(data, &parallel) != nil {
+		return fmt.Errorf("lifecycle command must be a string, an array or an object: %w", err)
+	}
+	res := LifecycleCommand{}
+	for _, name := range sortedKeys(parallel) {
+		cmd, err := parseCommand(name, parallel[name])
+		if err != nil {
+			return fmt.Errorf("lifecycle command %q: %w", name, err)
+		}
+		res = append(res, cmd)
+	}
+	*lc = res
+	return nil
+}
+
+// MarshalJSON writes the shortest form that represents the commands.
+func (lc LifecycleCommand) MarshalJSON() ([]byte, error) {
+	if len(lc) == 1 && lc[0].Name == "" {
+		return commandJSON(lc[0])
+	}
+	parallel := make(map[string]json.RawMessage, len(lc))
+	for i, c := range lc {
+		name := c.Name
+		if name == "" {
+			name = fmt.Sprintf("command-%d", i)
+		}
+		data, err := commandJSON(c)
+		if err != nil {
+			return nil, err
+		}
+		parallel[name] = data
+	}
+	return json.Marshal(parallel)
+}
+
+func commandJSON(c Command) ([]byte, error) {
+	if len(c.Args) > 0 {
+		return json.Marshal(c.Args)
+	}
+	return json.Marshal(c.Shell)
+}
+
+func parseCommand(name string, data []byte) (Command, error) {
+	var shell string
+	if err := json.Unmarshal(data, &shell); err == nil {
+		return Command{Name: name, Shell: shell}, nil
+	}
+	var args []string
+	if err := json.Unmarshal(data, &args); err == nil {
+		return Command{Name: name, Args: args}, nil
+	}
+	return Command{}, fmt.Errorf("expected a string or an array of strings")
+}
+
 // ParseDevContainer reads and parses a JSONC file.
 func ParseDevContainer(path string) (*DevContainerConfig, error) {
 	data, err := os.ReadFile(path)
@@ -42,113 +153,360 @@ func ParseDevContainer(path string) (*DevContainerConfig, error) {
 	if err := json.Unmarshal(stdData, &cfg); err != nil {
 		return nil, fmt.Errorf("failed to unmarshal devcontainer: %w", err)
 	}
+	cfg.path = path
 
 	return &cfg, nil
 }
 
-// ConvertDevContainerToConfig adapts the standard format to our internal Config.
-func (dc *DevContainerConfig) ToConfig() *Config {
+// ToConfig adapts the standard format to our internal Config, resolving
+// devcontainer variables like ${localEnv:VAR} on the way.
+func (dc *DevContainerConfig) ToConfig() (*Config, error) {
 	c := &Config{}
+	sub := newSubstitution(dc.path)
+	// A generated file (see GenerateDevContainer) starts the container the
+	// way Run does; Run adds those settings itself
+	generated := dc.OverrideCommand != nil && !*dc.OverrideCommand
+	workspaceMount, workspaceFolder := dc.WorkspaceMount, dc.WorkspaceFolder
+	if generated && (workspaceMount == mirroredWorkspaceMount || workspaceMount == relabeledWorkspaceMount) && workspaceFolder == mirroredWorkspaceFolder {
+		workspaceMount, workspaceFolder = "", ""
+	}
 
-	// Env Vars (Combine ContainerEnv and RemoteEnv)
-	// Our Config uses a list of keys to pass through OR key=value?
-	// Currently Config.EnvVars is []string.
-	// If the user provided a map in devcontainer, we assume they want to SET values.
-	// But our current logic is "Pass these vars from host".
-	// DevContainer logic is "Set these vars in container".
+	// Workspace
+	// workspaceFolder decides ${containerWorkspaceFolder}, so it goes first
+	if workspaceMount != "" {
+		m, err := parseMountString(sub.replace(workspaceMount))
+		if err != nil {
+			return nil, fmt.Errorf("invalid workspaceMount: %w", err)
+		}
+		c.WorkspaceMount = m
+	}
+	if workspaceFolder != "" {
+		c.WorkspaceFolder = sub.replace(workspaceFolder)
+		sub.containerWorkspaceFolder = c.WorkspaceFolder
+	} else if c.WorkspaceMount != nil {
+		sub.containerWorkspaceFolder = c.WorkspaceMount.Target
+	}
+	c.RemoteUser = sub.replace(dc.RemoteUser)
+	c.ContainerUser = sub.replace(dc.ContainerUser)
 
-	// Hybrid Approach:
-	// If value is empty or matches key ("VAR": "${localEnv:VAR}"), we treat it as pass-through.
-	// If value is set, we might need to handle it.
-	// For now, we only support passing variables listed as keys.
+	// Env Vars (Combine ContainerEnv and RemoteEnv)
+	// Config.EnvVars holds either a name to pass through from the host or a
+	// NAME=value pair to set. A value that only references the host variable of
+	// the same name ("VAR": "${localEnv:VAR}") is treated as pass-through, so the
+	// secret does not end up on the podman command line.
+	sub.containerEnv = make(map[string]string, len(dc.ContainerEnv))
+	for k, v := range dc.ContainerEnv {
+		sub.containerEnv[k] = sub.replace(v)
+	}
 
-	seen := make(map[string]bool)
-	for k := range dc.ContainerEnv {
-		if !seen[k] {
-			c.EnvVars = append(c.EnvVars, k)
-			seen[k] = true
+	env := make(map[string]string)
+	passThrough := make(map[string]bool)
+	addEnv := func(k, v string) {
+		if v == fmt.Sprintf("${localEnv:%s}", k) {
+			passThrough[k] = true
+			delete(env, k)
+			return
 		}
+		delete(passThrough, k)
+		env[k] = sub.replace(v)
 	}
-	for k := range dc.RemoteEnv {
-		if !seen[k] {
+	for _, k := range sortedKeys(dc.ContainerEnv) {
+		if generated && slices.Contains(builtinEnv, k) {
+			continue
+		}
+		addEnv(k, dc.ContainerEnv[k])
+	}
+	// remoteEnv may refer to ${containerEnv:VAR} and wins over containerEnv.
+	// References to the image's environment are expanded by the entrypoint.
+	sub.deferContainerEnv = true
+	remote := make(map[string]bool)
+	for _, k := range sortedKeys(dc.RemoteEnv) {
+		sub.deferred = false
+		addEnv(k, dc.RemoteEnv[k])
+		if sub.deferred {
+			remote[k] = true
+		}
+	}
+	sub.deferContainerEnv = false
+	for _, k := range sortedKeys(dc.ContainerEnv, dc.RemoteEnv) {
+		if remote[k] {
+			c.RemoteEnv = append(c.RemoteEnv, k+"="+env[k])
+		} else if passThrough[k] {
 			c.EnvVars = append(c.EnvVars, k)
-			seen[k] = true
+		} else if v, ok := env[k]; ok {
+			c.EnvVars = append(c.EnvVars, k+"="+v)
 		}
 	}
 
 	// Podman Args
-	c.PodmanArgs = dc.RunArgs
+	runArgs := dc.RunArgs
+	if generated {
+		runArgs = withoutBuiltinRunArgs(runArgs)
+	}
+	c.PodmanArgs = sub.replaceAll(runArgs)
 
 	// Mounts
 	// DevContainer format: "source=${localWorkspaceFolder},target=/workspace,type=bind"
 	// Our format: Mount struct { Source, Target, Options }
 	for _, mStr := range dc.Mounts {
-		m := parseMountString(mStr)
-		if m != nil {
-			c.Mounts = append(c.Mounts, *m)
+		m, err := parseMountString(sub.replace(mStr))
+		if err != nil {
+			return nil, err
+		}
+		if generated && isBuiltinMount(*m) {
+			continue
+		}
+		c.Mounts = append(c.Mounts, *m)
+	}
+
+	// Ports
+	for _, v := range dc.ForwardPorts {
+		p, err := parseForwardPort(v)
+		if err != nil {
+			fmt.Printf("⚠️  Ignoring forwardPorts entry %v: %v\n", v, err)
+			continue
+		}
+		p.Label = portLabel(dc.PortsAttributes, p.Container)
+		c.Ports = append(c.Ports, p)
+	}
+
+	// Image
+	// Dockerfile and context are relative to the devcontainer.json
+	c.Image = sub.replace(dc.Image)
+	if dc.Build != nil && dc.Build.Dockerfile != "" {
+		dir := filepath.Dir(dc.path)
+		if abs, err := filepath.Abs(dir); err == nil {
+			dir = abs
+		}
+		context := dc.Build.Context
+		if context == "" {
+			context = "."
+		}
+		c.Build = &Build{
+			Dockerfile: filepath.Join(dir, sub.replace(dc.Build.Dockerfile)),
+			Context:    filepath.Join(dir, sub.replace(context)),
+		}
+		if len(dc.Build.Args) > 0 {
+			c.Build.Args = make(map[string]string, len(dc.Build.Args))
+			for k, v := range dc.Build.Args {
+				c.Build.Args[k] = sub.replace(v)
+			}
+		}
+	}
+
+	// Features (their mounts come after the ones from the config itself)
+	if len(dc.Features) > 0 {
+		features, mounts, err := resolveFeatures(filepath.Dir(dc.path), dc.Features, sub)
+		if err != nil {
+			return nil, err
 		}
+		c.Features = features
+		c.Mounts = append(c.Mounts, mounts...)
+	}
+
+	// Lifecycle Commands
+	c.Lifecycle = Lifecycle{
+		Initialize:    sub.replaceCommands(dc.InitializeCommand),
+		OnCreate:      sub.replaceCommands(dc.OnCreateCommand),
+		UpdateContent: sub.replaceCommands(dc.UpdateContentCommand),
+		PostCreate:    sub.replaceCommands(dc.PostCreateCommand),
+		PostStart:     sub.replaceCommands(dc.PostStartCommand),
+		PostAttach:    sub.replaceCommands(dc.PostAttachCommand),
 	}
 
-	return c
+	// ai-shell Customizations
+	if raw, ok := dc.Customizations["ai-shell"]; ok {
+		data, err := sub.replaceJSON(raw)
+		if err != nil {
+			return nil, fmt.Errorf("invalid customizations.ai-shell: %w", err)
+		}
+		custom, err := loadJSON(data)
+		if err != nil {
+			return nil, fmt.Errorf("invalid customizations.ai-shell: %w", err)
+		}
+		mergeConfig(c, custom)
+		// mergeConfig drops what only the global config may set; keep it so
+		// that the loader warns about it, as for .ai-shell.yaml
+		c.ProtectedPaths = custom.ProtectedPaths
+		c.Security.LabelDisable = custom.Security.LabelDisable
+		c.Security.Seccomp.Allow = custom.Security.Seccomp.Allow
+		c.Security.Seccomp.Audit = custom.Security.Seccomp.Audit
+		c.Security.Seccomp.Disable = custom.Security.Seccomp.Disable
+	}
+	if generated {
+		c.PodmanArgs = withoutArgs(c.PodmanArgs, limitArgs(c))
+	}
+
+	return c, nil
 }
 
-func parseMountString(s string) *Mount {
-	// Very basic parser for "source=S,target=T,type=bind"
-	// This is fragile but sufficient for a start.
-	// We assume comma separation.
+// withoutBuiltinRunArgs removes builtinRunArgs from runArgs.
+func withoutBuiltinRunArgs(runArgs []string) []string {
+	var out []string
+	for i := 0; i < len(runArgs); i++ {
+		switch {
+		case runArgs[i] == "--security-opt" && i+1 < len(runArgs) && runArgs[i+1] == "label=disable":
+			i++
+		case runArgs[i] == "--userns=keep-id" || runArgs[i] == "--hostname=ai-box":
+		default:
+			out = append(out, runArgs[i])
+		}
+	}
+	return out
+}
 
-	m := &Mount{Options: "rw"} // Default to rw if not specified? Or ro? DevContainer default is rw.
+// withoutArgs removes the first run of args from runArgs.
+func withoutArgs(runArgs, args []string) []string {
+	if len(args) == 0 {
+		return runArgs
+	}
+	for i := 0; i+len(args) <= len(runArgs); i++ {
+		if slices.Equal(runArgs[i:i+len(args)], args) {
+			return slices.Concat(runArgs[:i], runArgs[i+len(args):])
+		}
+	}
+	return runArgs
+}
 
-	parts := splitComma(s)
-	for _, p := range parts {
-		kv := splitEquals(p)
-		if len(kv) != 2 {
-			continue
+// isBuiltinMount reports whether m is one of the mounts GenerateDevContainer
+// adds for ai-shell's own paths: the home volume and /etc/ai-shell.
+func isBuiltinMount(m Mount) bool {
+	target := path.Clean(m.Target)
+	return (m.Type == MountTypeVolume && target == HomeTarget()) || isPathWithin(target, "/etc/ai-shell")
+}
+
+// sortedKeys returns the union of the keys of all maps in a stable order.
+func sortedKeys[V any](maps ...map[string]V) []string {
+	seen := make(map[string]bool)
+	var keys []string
+	for _, m := range maps {
+		for k := range m {
+			if !seen[k] {
+				keys = append(keys, k)
+				seen[k] = true
+			}
 		}
-		k, v := kv[0], kv[1]
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// parseMountString parses the Docker --mount grammar used by devcontainer.json,
+// e.g. "type=volume,source=node_modules,target=/workspace/node_modules".
+// The string is a CSV record, so fields containing commas can be quoted.
+func parseMountString(s string) (*Mount, error) {
+	r := csv.NewReader(strings.NewReader(s))
+	r.TrimLeadingSpace = true
+	fields, err := r.Read()
+	if err != nil {
+		return nil, fmt.Errorf("invalid mount %q: %w", s, err)
+	}
+
+	m := &Mount{Type: MountTypeBind}
+	readonly := false
+	var opts []string
+
+	for _, field := range fields {
+		k, v, hasValue := strings.Cut(field, "=")
+		k = strings.ToLower(strings.TrimSpace(k))
 
 		switch k {
+		case "type":
+			m.Type = v
 		case "source", "src":
 			m.Source = v
 		case "target", "dst", "destination":
 			m.Target = v
-		case "type":
-			// ignore for now, assume bind
-		case "readonly":
-			if v == "true" {
-				m.Options = "ro"
+		case "readonly", "ro":
+			if !hasValue {
+				readonly = true
+				break
+			}
+			b, err := strconv.ParseBool(v)
+			if err != nil {
+				return nil, fmt.Errorf("invalid mount %q: invalid value for %s: %s", s, k, v)
+			}
+			readonly = b
+		case "consistency":
+			// Only meaningful for Docker Desktop on macOS
+			switch v {
+			case "consistent", "cached", "delegated", "default":
+			default:
+				return nil, fmt.Errorf("invalid mount %q: invalid consistency: %s", s, v)
+			}
+		case "bind-propagation":
+			switch v {
+			case "shared", "slave", "private", "rshared", "rslave", "rprivate":
+				opts = append(opts, v)
+			default:
+				return nil, fmt.Errorf("invalid mount %q: invalid bind-propagation: %s", s, v)
+			}
+		case "volume-nocopy":
+			if !hasValue || v == "true" || v == "1" {
+				opts = append(opts, "nocopy")
+			}
+		case "relabel":
+			// podman extension
+			m.Relabel = v
+		case "idmap":
+			// podman extension
+			idmap, err := parseIDMap(v)
+			if err != nil {
+				return nil, fmt.Errorf("invalid mount %q: %w", s, err)
 			}
+			m.IDMap = idmap
+		case "tmpfs-size":
+			opts = append(opts, "size="+v)
+		case "tmpfs-mode":
+			opts = append(opts, "mode="+v)
+		default:
+			return nil, fmt.Errorf("invalid mount %q: unsupported option %s", s, k)
 		}
 	}
 
-	if m.Source == "" || m.Target == "" {
-		return nil
-	}
-	return m
-}
-
-func splitComma(s string) []string {
-	// naive split, doesn't handle escaped commas
-	// TODO: Improve if needed
-	var res []string
-	start := 0
-	for i := 0; i < len(s); i++ {
-		if s[i] == ',' {
-			res = append(res, s[start:i])
-			start = i + 1
+	switch m.Type {
+	case MountTypeBind:
+		if m.Source == "" {
+			return nil, fmt.Errorf("invalid mount %q: bind mounts need a source", s)
 		}
+	case MountTypeVolume:
+		// An empty source is an anonymous volume
+	case MountTypeTmpfs:
+		if m.Source != "" {
+			return nil, fmt.Errorf("invalid mount %q: tmpfs mounts do not take a source", s)
+		}
+	default:
+		return nil, fmt.Errorf("invalid mount %q: unsupported type %s", s, m.Type)
 	}
-	if start < len(s) {
-		res = append(res, s[start:])
+	if m.Target == "" {
+		return nil, fmt.Errorf("invalid mount %q: missing target", s)
 	}
-	return res
-}
 
-func splitEquals(s string) []string {
-	for i := 0; i < len(s); i++ {
-		if s[i] == '=' {
-			return []string{s[:i], s[i+1:]}
+	// Validate option/type combinations
+	for _, o := range opts {
+		switch {
+		case strings.HasPrefix(o, "size=") || strings.HasPrefix(o, "mode="):
+			if m.Type != MountTypeTmpfs {
+				return nil, fmt.Errorf("invalid mount %q: tmpfs options on a %s mount", s, m.Type)
+			}
+		case o == "nocopy":
+			if m.Type != MountTypeVolume {
+				return nil, fmt.Errorf("invalid mount %q: volume-nocopy on a %s mount", s, m.Type)
+			}
+		default:
+			if m.Type != MountTypeBind {
+				return nil, fmt.Errorf("invalid mount %q: bind-propagation on a %s mount", s, m.Type)
+			}
 		}
 	}
-	return nil
+
+	mode := "rw" // DevContainer default is rw
+	if readonly {
+		mode = "ro"
+	}
+	m.Options = strings.Join(append([]string{mode}, opts...), ",")
+	if err := m.Validate(); err != nil {
+		return nil, fmt.Errorf("invalid mount %q: %w", s, err)
+	}
+	return m, nil
 }
@@ -3,6 +3,8 @@ package config
 import (
 	"os"
 	"path/filepath"
+	"reflect"
+	"strings"
 	"testing"
 )
 
@@ -36,13 +38,16 @@ func TestParseDevContainer(t *testing.T) {
 		t.Fatalf("Parse failed: %v", err)
 	}
 
-	cfg := dc.ToConfig()
+	cfg, err := dc.ToConfig()
+	if err != nil {
+		t.Fatalf("ToConfig failed: %v", err)
+	}
 
 	if len(cfg.PodmanArgs) != 1 || cfg.PodmanArgs[0] != "--network=host" {
 		t.Errorf("RunArgs mismatch: %v", cfg.PodmanArgs)
 	}
 
-	if len(cfg.EnvVars) != 1 || cfg.EnvVars[0] != "MY_VAR" {
+	if len(cfg.EnvVars) != 1 || cfg.EnvVars[0] != "MY_VAR=value" {
 		t.Errorf("EnvVars mismatch: %v", cfg.EnvVars)
 	}
 
@@ -50,8 +55,393 @@ func TestParseDevContainer(t *testing.T) {
 		t.Errorf("Mounts mismatch: %v", cfg.Mounts)
 	} else {
 		m := cfg.Mounts[0]
-		if m.Source != "${localWorkspaceFolder}" || m.Target != "/workspace" {
+		if m.Source != tmpDir || m.Target != "/workspace" {
 			t.Errorf("Mount parsing failed: %+v", m)
 		}
 	}
 }
+
+func TestDevContainerEnv(t *testing.T) {
+	tmpDir, err := os.MkdirTemp("", "devcontainer-test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() { _ = os.RemoveAll(tmpDir) }()
+
+	t.Setenv("AI_SHELL_TEST_HOST", "from-host")
+	t.Setenv("EMPTY", "from-host")
+
+	jsonContent := `
+	{
+		"containerEnv": {
+			"GH_TOKEN": "${localEnv:GH_TOKEN}",
+			"FROM_HOST": "${localEnv:AI_SHELL_TEST_HOST}",
+			"PROJECT": "${localWorkspaceFolderBasename}",
+			"EMPTY": ""
+		},
+		"remoteEnv": {
+			"DERIVED": "${containerEnv:FROM_HOST}-remote",
+			"PROJECT": "overridden",
+			"PATH": "${containerEnv:PATH}:/extra",
+			"LANG": "${containerEnv:LANG:C.UTF-8}"
+		}
+	}
+	`
+	dcDir := filepath.Join(tmpDir, "my-project", ".devcontainer")
+	if err = os.MkdirAll(dcDir, 0755); err != nil {
+		t.Fatal(err)
+	}
+	path := filepath.Join(dcDir, "devcontainer.json")
+	if err = os.WriteFile(path, []byte(jsonContent), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	dc, err := ParseDevContainer(path)
+	if err != nil {
+		t.Fatalf("Parse failed: %v", err)
+	}
+	cfg, err := dc.ToConfig()
+	if err != nil {
+		t.Fatalf("ToConfig failed: %v", err)
+	}
+
+	// An empty value sets the variable to empty, it does not pass it through
+	expected := []string{"DERIVED=from-host-remote", "EMPTY=", "FROM_HOST=from-host", "GH_TOKEN", "PROJECT=overridden"}
+	if strings.Join(cfg.EnvVars, " ") != strings.Join(expected, " ") {
+		t.Errorf("EnvVars mismatch. Expected: %v, Got: %v", expected, cfg.EnvVars)
+	}
+	// The image's PATH is only known in the container, the entrypoint expands it
+	expected = []string{"LANG=${LANG:-C.UTF-8}", "PATH=${PATH}:/extra"}
+	if strings.Join(cfg.RemoteEnv, " ") != strings.Join(expected, " ") {
+		t.Errorf("RemoteEnv mismatch. Expected: %v, Got: %v", expected, cfg.RemoteEnv)
+	}
+}
+
+func TestDevContainerLifecycle(t *testing.T) {
+	tmpDir, err := os.MkdirTemp("", "devcontainer-test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() { _ = os.RemoveAll(tmpDir) }()
+
+	jsonContent := `
+	{
+		"initializeCommand": "echo ${localWorkspaceFolderBasename}",
+		"onCreateCommand": ["npm", "ci"],
+		"postCreateCommand": {
+			"pre-commit": "pre-commit install",
+			"deps": ["go", "mod", "download"]
+		}
+	}
+	`
+	path := filepath.Join(tmpDir, "devcontainer.json")
+	if err = os.WriteFile(path, []byte(jsonContent), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	dc, err := ParseDevContainer(path)
+	if err != nil {
+		t.Fatalf("Parse failed: %v", err)
+	}
+	cfg, err := dc.ToConfig()
+	if err != nil {
+		t.Fatalf("ToConfig failed: %v", err)
+	}
+	lc := cfg.Lifecycle
+
+	expectedInit := "echo " + filepath.Base(tmpDir)
+	if len(lc.Initialize) != 1 || lc.Initialize[0].Shell != expectedInit {
+		t.Errorf("initializeCommand mismatch: %+v", lc.Initialize)
+	}
+	if len(lc.OnCreate) != 1 || strings.Join(lc.OnCreate[0].Args, " ") != "npm ci" {
+		t.Errorf("onCreateCommand mismatch: %+v", lc.OnCreate)
+	}
+	// Parallel commands are sorted by name
+	if len(lc.PostCreate) != 2 ||
+		lc.PostCreate[0].Name != "deps" || strings.Join(lc.PostCreate[0].Args, " ") != "go mod download" ||
+		lc.PostCreate[1].Name != "pre-commit" || lc.PostCreate[1].Shell != "pre-commit install" {
+		t.Errorf("postCreateCommand mismatch: %+v", lc.PostCreate)
+	}
+	if lc.PostStart != nil || lc.PostAttach != nil {
+		t.Errorf("Unset commands should be empty: %+v", lc)
+	}
+
+	invalid := filepath.Join(tmpDir, "invalid.json")
+	if err = os.WriteFile(invalid, []byte(`{"postStartCommand": 42}`), 0600); err != nil {
+		t.Fatal(err)
+	}
+	if _, err = ParseDevContainer(invalid); err == nil {
+		t.Error("Expected an error for an invalid lifecycle command")
+	}
+}
+
+func TestDevContainerImage(t *testing.T) {
+	tmpDir, err := os.MkdirTemp("", "devcontainer-test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() { _ = os.RemoveAll(tmpDir) }()
+
+	dcDir := filepath.Join(tmpDir, ".devcontainer")
+	if err = os.MkdirAll(dcDir, 0755); err != nil {
+		t.Fatal(err)
+	}
+	jsonContent := `
+	{
+		"image": "ignored:latest",
+		"build": {
+			"dockerfile": "Dockerfile",
+			"context": "..",
+			"args": {"PROJECT": "${localWorkspaceFolderBasename}"}
+		}
+	}
+	`
+	path := filepath.Join(dcDir, "devcontainer.json")
+	if err = os.WriteFile(path, []byte(jsonContent), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	dc, err := ParseDevContainer(path)
+	if err != nil {
+		t.Fatalf("Parse failed: %v", err)
+	}
+	cfg, err := dc.ToConfig()
+	if err != nil {
+		t.Fatalf("ToConfig failed: %v", err)
+	}
+
+	if cfg.Build == nil {
+		t.Fatal("Build should be set")
+	}
+	if cfg.Build.Dockerfile != filepath.Join(dcDir, "Dockerfile") || cfg.Build.Context != tmpDir {
+		t.Errorf("Build paths should be relative to devcontainer.json: %+v", cfg.Build)
+	}
+	if cfg.Build.Args["PROJECT"] != filepath.Base(tmpDir) {
+		t.Errorf("Build args mismatch: %v", cfg.Build.Args)
+	}
+
+	base := &Config{Image: "global:latest"}
+	mergeConfig(base, cfg)
+	if base.Build == nil || base.Image != "ignored:latest" {
+		t.Errorf("Project image settings should override the global ones: %+v", base)
+	}
+}
+
+func TestDevContainerWorkspace(t *testing.T) {
+	tmpDir, err := os.MkdirTemp("", "devcontainer-test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() { _ = os.RemoveAll(tmpDir) }()
+
+	jsonContent := `
+	{
+		"workspaceMount": "source=${localWorkspaceFolder},target=/workspace,type=bind",
+		"workspaceFolder": "/workspace",
+		"remoteUser": "dev",
+		"containerEnv": {"SRC": "${containerWorkspaceFolder}/src"}
+	}
+	`
+	path := filepath.Join(tmpDir, "devcontainer.json")
+	if err = os.WriteFile(path, []byte(jsonContent), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	dc, err := ParseDevContainer(path)
+	if err != nil {
+		t.Fatalf("Parse failed: %v", err)
+	}
+	cfg, err := dc.ToConfig()
+	if err != nil {
+		t.Fatalf("ToConfig failed: %v", err)
+	}
+
+	if cfg.WorkspaceMount == nil || cfg.WorkspaceMount.Source != tmpDir || cfg.WorkspaceMount.Target != "/workspace" {
+		t.Errorf("Unexpected workspace mount: %+v", cfg.WorkspaceMount)
+	}
+	if cfg.WorkspaceFolder != "/workspace" || cfg.RemoteUser != "dev" {
+		t.Errorf("Unexpected workspace folder or user: %q %q", cfg.WorkspaceFolder, cfg.RemoteUser)
+	}
+	if len(cfg.EnvVars) != 1 || cfg.EnvVars[0] != "SRC=/workspace/src" {
+		t.Errorf("containerWorkspaceFolder should follow workspaceFolder: %v", cfg.EnvVars)
+	}
+}
+
+func TestDevContainerPorts(t *testing.T) {
+	tmpDir, err := os.MkdirTemp("", "devcontainer-test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() { _ = os.RemoveAll(tmpDir) }()
+
+	jsonContent := `
+	{
+		"forwardPorts": [5173, "localhost:8888", "db:5432", 9000],
+		"portsAttributes": {
+			"5173": {"label": "Vite"},
+			"8000-8999": {"label": "Jupyter"},
+			"9000": {"label": "Exact"},
+			"9000-9100": {"label": "Range"}
+		},
+		"customizations": {"ai-shell": {"ports": [{"container": 5173, "host": 3000}]}}
+	}
+	`
+	path := filepath.Join(tmpDir, "devcontainer.json")
+	if err = os.WriteFile(path, []byte(jsonContent), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	dc, err := ParseDevContainer(path)
+	if err != nil {
+		t.Fatalf("Parse failed: %v", err)
+	}
+	cfg, err := dc.ToConfig()
+	if err != nil {
+		t.Fatalf("ToConfig failed: %v", err)
+	}
+
+	// Other hosts are skipped, the customization wins for 5173
+	expected := []Port{
+		{Container: 5173, Host: 3000},
+		{Container: 8888, Label: "Jupyter"},
+		{Container: 9000, Label: "Exact"},
+	}
+	if !reflect.DeepEqual(cfg.Ports, expected) {
+		t.Errorf("Expected: %+v, Got: %+v", expected, cfg.Ports)
+	}
+}
+
+func TestDevContainerCustomizations(t *testing.T) {
+	tmpDir, err := os.MkdirTemp("", "devcontainer-test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() { _ = os.RemoveAll(tmpDir) }()
+
+	jsonContent := `
+	{
+		"containerEnv": {"FROM_DEVCONTAINER": "1"},
+		"customizations": {
+			"vscode": {"extensions": ["golang.go"]},
+			"ai-shell": {
+				"profile": "claude",
+				"ssh": true,
+				"net_host": true,
+				"env_vars": ["GH_TOKEN"],
+				"mounts": [{"source": "${localWorkspaceFolder}/.cache", "target": "/cache", "options": "rw"}],
+				"registries": [{"registry": "quay.io", "username_env": "QUAY_USER", "token_env": "QUAY_TOKEN"}],
+				"scms": [{"host": "gitlab.com", "token_env": "GITLAB_TOKEN"}],
+				"protected_paths": [{"path": "/srv"}],
+				"security": {"label_disable": true, "seccomp": {"allow": ["ptrace"], "disable": true}}
+			}
+		}
+	}
+	`
+	path := filepath.Join(tmpDir, "devcontainer.json")
+	if err = os.WriteFile(path, []byte(jsonContent), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	dc, err := ParseDevContainer(path)
+	if err != nil {
+		t.Fatalf("Parse failed: %v", err)
+	}
+	cfg, err := dc.ToConfig()
+	if err != nil {
+		t.Fatalf("ToConfig failed: %v", err)
+	}
+
+	if cfg.Profile != "claude" || !cfg.SSH || !cfg.NetHost {
+		t.Errorf("Defaults mismatch: profile=%q ssh=%v net_host=%v", cfg.Profile, cfg.SSH, cfg.NetHost)
+	}
+	if strings.Join(cfg.EnvVars, " ") != "FROM_DEVCONTAINER=1 GH_TOKEN" {
+		t.Errorf("EnvVars mismatch: %v", cfg.EnvVars)
+	}
+	if len(cfg.Mounts) != 1 || cfg.Mounts[0].Source != tmpDir+"/.cache" || cfg.Mounts[0].Options != "rw" {
+		t.Errorf("Mounts mismatch: %+v", cfg.Mounts)
+	}
+	if len(cfg.Registries) != 1 || cfg.Registries[0].TokenEnv != "QUAY_TOKEN" {
+		t.Errorf("Registries mismatch: %+v", cfg.Registries)
+	}
+	if len(cfg.SCMs) != 1 || cfg.SCMs[0].Host != "gitlab.com" {
+		t.Errorf("SCMs mismatch: %+v", cfg.SCMs)
+	}
+
+	// Kept for the loader, which ignores them with a warning
+	if len(cfg.ProtectedPaths) != 1 || !cfg.Security.LabelDisable || !cfg.Security.Seccomp.Weakened() {
+		t.Errorf("Global-only settings dropped: protected_paths=%+v security=%+v", cfg.ProtectedPaths, cfg.Security)
+	}
+
+	if p := cfg.EffectiveProfile("gemini"); p != "gemini" {
+		t.Errorf("The flag should win, got %q", p)
+	}
+	if p := cfg.EffectiveProfile(""); p != "claude" {
+		t.Errorf("Expected the config profile, got %q", p)
+	}
+	var none *Config
+	if p := none.EffectiveProfile(""); p != "" {
+		t.Errorf("Expected no profile, got %q", p)
+	}
+}
+
+func TestParseMountString(t *testing.T) {
+	tests := []struct {
+		in      string
+		want    Mount
+		wantErr bool
+	}{
+		{
+			in:   "source=/src,target=/dst,type=bind",
+			want: Mount{Type: MountTypeBind, Source: "/src", Target: "/dst", Options: "rw"},
+		},
+		{
+			in:   "type=volume,source=node_modules,target=/workspace/node_modules",
+			want: Mount{Type: MountTypeVolume, Source: "node_modules", Target: "/workspace/node_modules", Options: "rw"},
+		},
+		{
+			in:   "type=volume,dst=/cache,volume-nocopy",
+			want: Mount{Type: MountTypeVolume, Target: "/cache", Options: "rw,nocopy"},
+		},
+		{
+			in:   "type=tmpfs,destination=/scratch,tmpfs-size=64m,tmpfs-mode=1777",
+			want: Mount{Type: MountTypeTmpfs, Target: "/scratch", Options: "rw,size=64m,mode=1777"},
+		},
+		{
+			in:   "src=/src,dst=/dst,readonly",
+			want: Mount{Type: MountTypeBind, Source: "/src", Target: "/dst", Options: "ro"},
+		},
+		{
+			in:   "src=/src,dst=/dst,ro=false,consistency=cached,bind-propagation=rslave",
+			want: Mount{Type: MountTypeBind, Source: "/src", Target: "/dst", Options: "rw,rslave"},
+		},
+		{
+			in:   `"source=/path/with,comma",target=/dst,readonly=true`,
+			want: Mount{Type: MountTypeBind, Source: "/path/with,comma", Target: "/dst", Options: "ro"},
+		},
+		{in: "type=npipe,source=/a,target=/b", wantErr: true},
+		{in: "source=/a", wantErr: true},
+		{in: "target=/b", wantErr: true},
+		{in: "type=tmpfs,source=/a,target=/b", wantErr: true},
+		{in: "type=volume,source=v,target=/b,bind-propagation=shared", wantErr: true},
+		{in: "source=/a,target=/b,tmpfs-size=1m", wantErr: true},
+		{in: "source=/a,target=/b,readonly=maybe", wantErr: true},
+		{in: "source=/a,target=/b,bogus=1", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		m, err := parseMountString(tt.in)
+		if tt.wantErr {
+			if err == nil {
+				t.Errorf("Input: %s, Expected an error, got %+v", tt.in, m)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("Input: %s, Unexpected error: %v", tt.in, err)
+			continue
+		}
+		if *m != tt.want {
+			t.Errorf("Input: %s, Expected: %+v, Got: %+v", tt.in, tt.want, *m)
+		}
+	}
+}
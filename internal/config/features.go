@@ -0,0 +1,217 @@
+package config
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"github.com/tailscale/hujson"
+)
+
+// Feature is a local devcontainer Feature that is layered onto the image.
+type Feature struct {
+	ID  string
+	Dir string
+	// Options are passed to install.sh as environment variables, keyed by the
+	// already converted variable name.
+	Options      map[string]string
+	ContainerEnv map[string]string
+
+	// settings is the value from devcontainer.json, kept to write it back
+	settings json.RawMessage
+}
+
+// featureMetadata is the subset of devcontainer-feature.json we support.
+type featureMetadata struct {
+	ID      string `json:"id"`
+	Version string `json:"version"`
+	Options map[string]struct {
+		Default any `json:"default"`
+	} `json:"options"`
+	InstallsAfter []string          `json:"installsAfter"`
+	ContainerEnv  map[string]string `json:"containerEnv"`
+	Mounts        []json.RawMessage `json:"mounts"`
+}
+
+var (
+	featureOptionPattern = regexp.MustCompile(`[^\w_]`)
+	featureOptionPrefix  = regexp.MustCompile(`^[\d_]+`)
+)
+
+// resolveFeatures loads the local features referenced by a devcontainer.json
+// in baseDir and returns them in install order, along with the mounts they
+// contribute.
+func resolveFeatures(baseDir string, refs map[string]json.RawMessage, sub *substitution) ([]Feature, []Mount, error) {
+	var features []Feature
+	var mounts []Mount
+	installsAfter := make(map[string][]string)
+
+	for _, ref := range sortedKeys(refs) {
+		if !strings.HasPrefix(ref, "./") && !strings.HasPrefix(ref, "../") {
+			fmt.Printf("⚠️  Skipping feature %s: only local features are supported.\n", ref)
+			continue
+		}
+
+		dir := filepath.Join(baseDir, ref)
+		meta, err := readFeatureMetadata(dir)
+		if err != nil {
+			return nil, nil, fmt.Errorf("feature %s: %w", ref, err)
+		}
+		if _, err := os.Stat(filepath.Join(dir, "install.sh")); err != nil {
+			return nil, nil, fmt.Errorf("feature %s: missing install.sh", ref)
+		}
+
+		opts, err := featureOptions(meta, refs[ref])
+		if err != nil {
+			return nil, nil, fmt.Errorf("feature %s: %w", ref, err)
+		}
+
+		env := make(map[string]string, len(meta.ContainerEnv))
+		for k, v := range meta.ContainerEnv {
+			env[k] = sub.replace(v)
+		}
+
+		for _, raw := range meta.Mounts {
+			m, err := parseFeatureMount(raw, sub)
+			if err != nil {
+				return nil, nil, fmt.Errorf("feature %s: %w", ref, err)
+			}
+			mounts = append(mounts, *m)
+		}
+
+		id := meta.ID
+		if id == "" {
+			id = filepath.Base(dir)
+		}
+		features = append(features, Feature{ID: id, Dir: dir, Options: opts, ContainerEnv: env, settings: refs[ref]})
+		installsAfter[id] = meta.InstallsAfter
+	}
+
+	ordered, err := orderFeatures(features, installsAfter)
+	if err != nil {
+		return nil, nil, err
+	}
+	return ordered, mounts, nil
+}
+
+func readFeatureMetadata(dir string) (*featureMetadata, error) {
+	data, err := os.ReadFile(filepath.Join(dir, "devcontainer-feature.json"))
+	if err != nil {
+		return nil, err
+	}
+	stdData, err := hujson.Standardize(data)
+	if err != nil {
+		return nil, fmt.Errorf("invalid devcontainer-feature.json: %w", err)
+	}
+	var meta featureMetadata
+	if err := json.Unmarshal(stdData, &meta); err != nil {
+		return nil, fmt.Errorf("invalid devcontainer-feature.json: %w", err)
+	}
+	return &meta, nil
+}
+
+// featureOptions merges the user provided options with the defaults from the
+// feature metadata. The value in devcontainer.json is either an object of
+// options, or a plain string/bool which carries no options.
+func featureOptions(meta *featureMetadata, raw json.RawMessage) (map[string]string, error) {
+	var given map[string]any
+	if len(raw) > 0 && raw[0] == '{' {
+		if err := json.Unmarshal(raw, &given); err != nil {
+			return nil, fmt.Errorf("invalid options: %w", err)
+		}
+	}
+
+	opts := make(map[string]string)
+	for name, o := range meta.Options {
+		if o.Default != nil {
+			opts[featureOptionName(name)] = fmt.Sprint(o.Default)
+		}
+	}
+	for name, v := range given {
+		if _, ok := meta.Options[name]; !ok {
+			return nil, fmt.Errorf("unknown option %q", name)
+		}
+		opts[featureOptionName(name)] = fmt.Sprint(v)
+	}
+	return opts, nil
+}
+
+// featureOptionName converts an option id to the environment variable name
+// install.sh receives, following the devcontainer Features spec.
+func featureOptionName(name string) string {
+	name = featureOptionPattern.ReplaceAllString(name, "_")
+	name = featureOptionPrefix.ReplaceAllString(name, "_")
+	return strings.ToUpper(name)
+}
+
+// parseFeatureMount accepts both the string and the object form of a mount.
+func parseFeatureMount(raw json.RawMessage, sub *substitution) (*Mount, error) {
+	var s string
+	if err := json.Unmarshal(raw, &s); err == nil {
+		return parseMountString(sub.replace(s))
+	}
+	var obj struct {
+		Type   string `json:"type"`
+		Source string `json:"source"`
+		Target string `json:"target"`
+	}
+	if err := json.Unmarshal(raw, &obj); err != nil {
+		return nil, fmt.Errorf("invalid mount: %w", err)
+	}
+	m := &Mount{Type: obj.Type, Source: sub.replace(obj.Source), Target: sub.replace(obj.Target), Options: "rw"}
+	if m.Type == "" {
+		m.Type = MountTypeBind
+	}
+	return m, nil
+}
+
+// orderFeatures sorts features so that each one is installed after the
+// features listed in its installsAfter. Ties keep the given order; references
+// to features that are not part of the config are ignored.
+func orderFeatures(features []Feature, installsAfter map[string][]string) ([]Feature, error) {
+	index := make(map[string]int, len(features))
+	for i, f := range features {
+		index[f.ID] = i
+	}
+
+	done := make(map[string]bool, len(features))
+	var ordered []Feature
+	for len(ordered) < len(features) {
+		progress := false
+		for _, f := range features {
+			if done[f.ID] {
+				continue
+			}
+			ready := true
+			for _, dep := range installsAfter[f.ID] {
+				dep = featureIDFromRef(dep)
+				if _, ok := index[dep]; ok && !done[dep] && dep != f.ID {
+					ready = false
+					break
+				}
+			}
+			if ready {
+				ordered = append(ordered, f)
+				done[f.ID] = true
+				progress = true
+				break
+			}
+		}
+		if !progress {
+			return nil, fmt.Errorf("features have circular installsAfter dependencies")
+		}
+	}
+	return ordered, nil
+}
+
+// featureIDFromRef turns "ghcr.io/devcontainers/features/node:1" into "node".
+func featureIDFromRef(ref string) string {
+	ref = ref[strings.LastIndex(ref, "/")+1:]
+	if i := strings.Index(ref, ":"); i >= 0 {
+		ref = ref[:i]
+	}
+	return ref
+}
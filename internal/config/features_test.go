@@ -0,0 +1,122 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func writeFeature(t *testing.T, dir, metadata string) {
+	t.Helper()
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "devcontainer-feature.json"), []byte(metadata), 0600); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "install.sh"), []byte("#!/bin/sh\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestDevContainerFeatures(t *testing.T) {
+	tmpDir, err := os.MkdirTemp("", "devcontainer-features-test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() { _ = os.RemoveAll(tmpDir) }()
+
+	dcDir := filepath.Join(tmpDir, ".devcontainer")
+	writeFeature(t, filepath.Join(dcDir, "features", "node"), `{
+		"id": "node",
+		"options": {
+			"version": {"type": "string", "default": "lts"},
+			"install-yarn": {"type": "boolean", "default": false}
+		},
+		"containerEnv": {"PATH": "/usr/local/node/bin:${PATH}"},
+		"mounts": [{"source": "node-cache-${devcontainerId}", "target": "/cache", "type": "volume"}]
+	}`)
+	// Installed after node, although it sorts first
+	writeFeature(t, filepath.Join(dcDir, "features", "linters"), `{
+		"id": "linters",
+		"installsAfter": ["ghcr.io/devcontainers/features/node:1"]
+	}`)
+
+	jsonContent := `{
+		"features": {
+			"./features/node": {"version": "20", "install-yarn": true},
+			"./features/linters": {},
+			"ghcr.io/devcontainers/features/go:1": {}
+		}
+	}`
+	path := filepath.Join(dcDir, "devcontainer.json")
+	if err = os.WriteFile(path, []byte(jsonContent), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	dc, err := ParseDevContainer(path)
+	if err != nil {
+		t.Fatalf("Parse failed: %v", err)
+	}
+	cfg, err := dc.ToConfig()
+	if err != nil {
+		t.Fatalf("ToConfig failed: %v", err)
+	}
+
+	if len(cfg.Features) != 2 || cfg.Features[0].ID != "node" || cfg.Features[1].ID != "linters" {
+		t.Fatalf("Features mismatch: %+v", cfg.Features)
+	}
+	node := cfg.Features[0]
+	if node.Options["VERSION"] != "20" || node.Options["INSTALL_YARN"] != "true" {
+		t.Errorf("Options mismatch: %v", node.Options)
+	}
+	if node.ContainerEnv["PATH"] != "/usr/local/node/bin:${PATH}" {
+		t.Errorf("ContainerEnv mismatch: %v", node.ContainerEnv)
+	}
+	if len(cfg.Mounts) != 1 || cfg.Mounts[0].Target != "/cache" || cfg.Mounts[0].Source == "node-cache-${devcontainerId}" {
+		t.Errorf("Feature mounts mismatch: %+v", cfg.Mounts)
+	}
+
+	// Unknown options are rejected
+	if err = os.WriteFile(path, []byte(`{"features": {"./features/node": {"nope": 1}}}`), 0600); err != nil {
+		t.Fatal(err)
+	}
+	dc, err = ParseDevContainer(path)
+	if err != nil {
+		t.Fatalf("Parse failed: %v", err)
+	}
+	if _, err = dc.ToConfig(); err == nil {
+		t.Error("Expected an error for an unknown feature option")
+	}
+}
+
+func TestOrderFeatures(t *testing.T) {
+	features := []Feature{{ID: "a"}, {ID: "b"}, {ID: "c"}}
+
+	ordered, err := orderFeatures(features, map[string][]string{"a": {"c"}, "b": {"a"}})
+	if err != nil {
+		t.Fatalf("orderFeatures failed: %v", err)
+	}
+	got := ordered[0].ID + ordered[1].ID + ordered[2].ID
+	if got != "cab" {
+		t.Errorf("Expected order cab, got %s", got)
+	}
+
+	if _, err = orderFeatures(features, map[string][]string{"a": {"b"}, "b": {"a"}}); err == nil {
+		t.Error("Expected an error for circular dependencies")
+	}
+}
+
+func TestFeatureOptionName(t *testing.T) {
+	tests := map[string]string{
+		"version":      "VERSION",
+		"install-yarn": "INSTALL_YARN",
+		"9lives":       "_LIVES",
+		"node.version": "NODE_VERSION",
+	}
+	for in, expected := range tests {
+		if got := featureOptionName(in); got != expected {
+			t.Errorf("featureOptionName(%q): Expected %q, Got %q", in, expected, got)
+		}
+	}
+}
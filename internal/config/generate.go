@@ -0,0 +1,370 @@
+package config
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// DevContainerOptions describes the environment a generated devcontainer.json
+// is meant for.
+type DevContainerOptions struct {
+	Name string
+	// Image is the profile image, used unless the config selects its own
+	Image string
+	// Dir is where the file will be written; paths are made relative to it
+	Dir string
+	// HomeVolume is the persistent home volume; it defaults to one per dev container
+	HomeVolume string
+	// HomeRoot is /home on Linux and /Users on macOS
+	HomeRoot string
+}
+
+// builtinRunArgs start the container the way Run does. ToConfig drops them
+// from generated files, Run passes its own. label=disable is only added when
+// the config sets security.label_disable; otherwise the builtin mounts are
+// relabeled like Run does.
+var builtinRunArgs = []string{"--userns=keep-id", "--hostname=ai-box"}
+
+// builtinEnv are set by Run from the host.
+var builtinEnv = []string{"HOST_USER", "HOST_HOME_ROOT"}
+
+// Path mirroring, like Run
+const (
+	mirroredWorkspaceMount  = "source=${localWorkspaceFolder},target=${localWorkspaceFolder},type=bind"
+	mirroredWorkspaceFolder = "${localWorkspaceFolder}"
+	// relabeledWorkspaceMount is the mirrored mount with SELinux labeling
+	relabeledWorkspaceMount = mirroredWorkspaceMount + ",relabel=" + RelabelShared
+)
+
+// customizationKeys are the config keys without a devcontainer.json
+// equivalent; they are written to customizations.ai-shell.
+var customizationKeys = map[string]bool{
+	"registries":    true,
+	"scms":          true,
+	"profile":       true,
+	"ssh":           true,
+	"net_host":      true,
+	"network":       true,
+	"host_ports":    true,
+	"offline":       true,
+	"model_egress":  true,
+	"model_hosts":   true,
+	"ports":         true,
+	"dns":           true,
+	"extra_hosts":   true,
+	"proxy":         true,
+	"ca_certs":      true,
+	"security":      true,
+	"resources":     true,
+	"sandbox":       true,
+	"approval":      true,
+	"record":        true,
+	"command_audit": true,
+}
+
+// GenerateDevContainer is the inverse of ToConfig: it describes the merged
+// config as a devcontainer.json that starts ai-shell's image the way Run does.
+// Host values are referenced with ${localEnv:...} so that no secrets are
+// written to the file; that includes the values of NAME=value env vars,
+// which have to be exported on the host instead.
+func GenerateDevContainer(c *Config, opts DevContainerOptions) (*DevContainerConfig, error) {
+	if c == nil {
+		c = &Config{}
+	}
+	noOverride := false
+	homeVolume := opts.HomeVolume
+	if homeVolume == "" {
+		homeVolume = "ai-home-${devcontainerId}"
+	}
+
+	dc := &DevContainerConfig{
+		Name:            opts.Name,
+		Image:           opts.Image,
+		OverrideCommand: &noOverride,
+		WorkspaceMount:  relabeledWorkspaceMount,
+		WorkspaceFolder: mirroredWorkspaceFolder,
+		RemoteUser:      "ai",
+		RunArgs:         slices.Clone(builtinRunArgs),
+	}
+
+	relabel := RelabelShared
+	if c.Security.LabelDisable {
+		dc.WorkspaceMount = mirroredWorkspaceMount
+		dc.RunArgs = append(dc.RunArgs, "--security-opt", "label=disable")
+		relabel = ""
+	}
+
+	// Workspace & User
+	if c.WorkspaceMount != nil {
+		dc.WorkspaceMount = formatMountString(*c.WorkspaceMount)
+		dc.WorkspaceFolder = c.WorkspaceMount.Target
+	}
+	if c.WorkspaceFolder != "" {
+		dc.WorkspaceFolder = c.WorkspaceFolder
+	}
+	if c.RemoteUser != "" {
+		dc.RemoteUser = c.RemoteUser
+	}
+	dc.ContainerUser = c.ContainerUser
+
+	// Image
+	if c.Image != "" {
+		dc.Image = c.Image
+	}
+	if c.Build != nil && c.Build.Dockerfile != "" {
+		dc.Image = ""
+		dc.Build = &DevContainerBuild{
+			Dockerfile: relativeTo(opts.Dir, c.Build.Dockerfile),
+			Context:    relativeTo(opts.Dir, c.Build.Context),
+			Args:       c.Build.Args,
+		}
+	}
+
+	// VS Code does not read customizations.ai-shell, so the limits Run
+	// applies are repeated as runArgs
+	dc.RunArgs = append(dc.RunArgs, limitArgs(c)...)
+	if c.NetHost {
+		dc.RunArgs = append(dc.RunArgs, "--network=host")
+	}
+	for _, a := range c.PodmanArgs {
+		dc.RunArgs = append(dc.RunArgs, toLocalEnv(a))
+	}
+
+	// Ports: VS Code picks the host ports itself, so only a fixed host port
+	// is kept for ai-shell, in customizations
+	var fixedPorts []Port
+	for _, p := range c.Ports {
+		dc.ForwardPorts = append(dc.ForwardPorts, p.Container)
+		if p.Label != "" {
+			if dc.PortsAttributes == nil {
+				dc.PortsAttributes = make(map[string]PortAttributes)
+			}
+			dc.PortsAttributes[strconv.Itoa(p.Container)] = PortAttributes{Label: p.Label}
+		}
+		if p.Host != 0 && p.Host != p.Container {
+			fixedPorts = append(fixedPorts, p)
+		}
+	}
+
+	// Environment
+	dc.ContainerEnv = map[string]string{
+		"HOST_USER":      "${localEnv:USER}",
+		"HOST_HOME_ROOT": opts.HomeRoot,
+	}
+	var names, literal []string
+	for _, v := range c.EnvVars {
+		k, _, ok := strings.Cut(v, "=")
+		if ok {
+			literal = append(literal, k)
+		}
+		names = append(names, k)
+	}
+	if len(names) == 0 {
+		names = DefaultEnvVars
+	}
+	for _, k := range names {
+		dc.ContainerEnv[k] = fmt.Sprintf("${localEnv:%s}", k)
+	}
+	if len(literal) > 0 {
+		fmt.Printf("⚠️  Not writing the values of %s to the devcontainer.json; they are taken from the host environment.\n", strings.Join(literal, ", "))
+	}
+
+	// Entries that reference the container's environment go back to remoteEnv
+	for _, v := range c.RemoteEnv {
+		if dc.RemoteEnv == nil {
+			dc.RemoteEnv = make(map[string]string)
+		}
+		k, val, _ := strings.Cut(v, "=")
+		dc.RemoteEnv[k] = containerEnvPattern.ReplaceAllStringFunc(val, toContainerEnv)
+	}
+
+	// Mounts: home volume, host git config and this file, which carries the
+	// merged config in customizations, then the config mounts
+	targetHome := opts.HomeRoot + "/${localEnv:USER}"
+	self := "${localWorkspaceFolder}/.devcontainer.json"
+	if filepath.Base(opts.Dir) == ".devcontainer" {
+		self = "${localWorkspaceFolder}/.devcontainer/devcontainer.json"
+	}
+	dc.Mounts = []string{
+		formatMountString(Mount{Type: MountTypeVolume, Source: homeVolume, Target: targetHome, Options: "rw"}),
+		formatMountString(Mount{Source: "${localEnv:HOME}/.gitconfig", Target: GitConfigTarget, Options: "ro", Relabel: relabel}),
+		formatMountString(Mount{Source: self, Target: DevContainerTarget, Options: "ro", Relabel: relabel}),
+	}
+	for _, m := range c.Mounts {
+		m.Source = toLocalEnv(m.Source)
+		m.Target = toLocalEnv(m.Target)
+		if (m.Type == "" || m.Type == MountTypeBind) && m.Options == "" {
+			m.Options = "ro" // Run's default for config mounts
+		}
+		dc.Mounts = append(dc.Mounts, formatMountString(m))
+	}
+
+	// Lifecycle
+	dc.InitializeCommand = LifecycleCommand(c.Lifecycle.Initialize)
+	dc.OnCreateCommand = LifecycleCommand(c.Lifecycle.OnCreate)
+	dc.UpdateContentCommand = LifecycleCommand(c.Lifecycle.UpdateContent)
+	dc.PostCreateCommand = LifecycleCommand(c.Lifecycle.PostCreate)
+	dc.PostStartCommand = LifecycleCommand(c.Lifecycle.PostStart)
+	dc.PostAttachCommand = LifecycleCommand(c.Lifecycle.PostAttach)
+
+	// Features
+	for _, f := range c.Features {
+		if dc.Features == nil {
+			dc.Features = make(map[string]json.RawMessage)
+		}
+		settings := f.settings
+		if len(settings) == 0 {
+			settings = json.RawMessage("{}")
+		}
+		dc.Features["./"+relativeTo(opts.Dir, f.Dir)] = settings
+	}
+
+	// Everything else goes to customizations.ai-shell
+	rest := *c
+	rest.Ports = fixedPorts
+	custom, err := customizations(&rest)
+	if err != nil {
+		return nil, err
+	}
+	if len(custom) > 0 {
+		dc.Customizations = map[string]json.RawMessage{"ai-shell": custom}
+	}
+
+	return dc, nil
+}
+
+// limitArgs are the podman arguments of the security level and the
+// resources, which Run passes itself.
+func limitArgs(c *Config) []string {
+	return append(c.Security.PodmanArgs(), c.Resources.PodmanArgs(c.Security.AtLeast(SecurityParanoid))...)
+}
+
+// Marshal renders the config as an indented devcontainer.json.
+func (dc *DevContainerConfig) Marshal() ([]byte, error) {
+	data, err := json.MarshalIndent(dc, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+	return append(data, '\n'), nil
+}
+
+// customizations returns the config keys that have no devcontainer.json
+// equivalent, leaving out empty values.
+func customizations(c *Config) (json.RawMessage, error) {
+	data, err := json.Marshal(c)
+	if err != nil {
+		return nil, err
+	}
+	var all map[string]any
+	if err := json.Unmarshal(data, &all); err != nil {
+		return nil, err
+	}
+
+	custom := make(map[string]any)
+	for k, v := range all {
+		if !customizationKeys[k] || isEmptyJSON(v) {
+			continue
+		}
+		custom[k] = v
+	}
+	if len(custom) == 0 {
+		return nil, nil
+	}
+	return json.Marshal(custom)
+}
+
+func isEmptyJSON(v any) bool {
+	switch t := v.(type) {
+	case nil:
+		return true
+	case string:
+		return t == ""
+	case bool:
+		return !t
+	case []any:
+		return len(t) == 0
+	case map[string]any:
+		return len(t) == 0
+	}
+	return false
+}
+
+// formatMountString is the inverse of parseMountString.
+func formatMountString(m Mount) string {
+	typ := m.Type
+	if typ == "" {
+		typ = MountTypeBind
+	}
+	fields := []string{"type=" + typ}
+	if m.Source != "" {
+		fields = append(fields, "source="+m.Source)
+	}
+	fields = append(fields, "target="+m.Target)
+
+	for _, o := range strings.Split(m.ResolvedOptions(""), ",") {
+		switch {
+		case o == "" || o == "rw":
+		case o == "ro":
+			fields = append(fields, "readonly")
+		case o == "nocopy":
+			fields = append(fields, "volume-nocopy")
+		case strings.HasPrefix(o, "size="):
+			fields = append(fields, "tmpfs-size="+strings.TrimPrefix(o, "size="))
+		case strings.HasPrefix(o, "mode="):
+			fields = append(fields, "tmpfs-mode="+strings.TrimPrefix(o, "mode="))
+		case o == "z":
+			fields = append(fields, "relabel="+RelabelShared)
+		case o == "Z":
+			fields = append(fields, "relabel="+RelabelPrivate)
+		case strings.HasPrefix(o, "idmap="):
+			fields = append(fields, o)
+		default:
+			fields = append(fields, "bind-propagation="+o)
+		}
+	}
+
+	for i, f := range fields {
+		if strings.ContainsAny(f, ",\"") {
+			fields[i] = `"` + strings.ReplaceAll(f, `"`, `""`) + `"`
+		}
+	}
+	return strings.Join(fields, ",")
+}
+
+// containerEnvPattern matches the ${VAR} and ${VAR:-default} references of
+// Config.RemoteEnv.
+var containerEnvPattern = regexp.MustCompile(`\$\{([A-Za-z_][A-Za-z0-9_]*)(:-[^}]*)?\}`)
+
+// toContainerEnv is the inverse of the deferred ${containerEnv:VAR}
+// substitution.
+func toContainerEnv(ref string) string {
+	m := containerEnvPattern.FindStringSubmatch(ref)
+	if m[2] != "" {
+		return fmt.Sprintf("${containerEnv:%s:%s}", m[1], m[2][2:])
+	}
+	return fmt.Sprintf("${containerEnv:%s}", m[1])
+}
+
+// toLocalEnv rewrites $VAR and ${VAR} references, which Run expands on the
+// host, into ${localEnv:VAR}.
+func toLocalEnv(s string) string {
+	return os.Expand(s, func(k string) string {
+		return fmt.Sprintf("${localEnv:%s}", k)
+	})
+}
+
+func relativeTo(dir, path string) string {
+	if dir == "" || path == "" {
+		return path
+	}
+	if rel, err := filepath.Rel(dir, path); err == nil {
+		return rel
+	}
+	return path
+}
@@ -0,0 +1,168 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestGenerateDevContainer(t *testing.T) {
+	tmpDir, err := os.MkdirTemp("", "generate-test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() { _ = os.RemoveAll(tmpDir) }()
+
+	t.Setenv("AI_SHELL_TEST_TOKEN", "secret")
+	t.Setenv("MODE", "dev")
+	t.Setenv("USER", "me")
+
+	cfg := &Config{
+		EnvVars:    []string{"AI_SHELL_TEST_TOKEN", "MODE=literal"},
+		RemoteEnv:  []string{"PATH=${PATH}:/extra"},
+		Mounts:     []Mount{{Source: "/opt/data", Target: "/data"}},
+		PodmanArgs: []string{"--cap-add=NET_ADMIN"},
+		Registries: []Registry{{Registry: "quay.io", TokenEnv: "QUAY_TOKEN"}},
+		Profile:    "go",
+		SSH:        true,
+		Lifecycle:  Lifecycle{PostCreate: []Command{{Shell: "make deps"}}},
+		Ports:      []Port{{Container: 5173, Label: "Vite"}, {Container: 8888, Host: 9999}},
+		Security:   Security{Level: SecurityStrict},
+		Resources:  Resources{CPUs: 2, Memory: "4g", PidsLimit: 512},
+	}
+
+	dcDir := filepath.Join(tmpDir, ".devcontainer")
+	if err := os.Mkdir(dcDir, 0755); err != nil {
+		t.Fatal(err)
+	}
+	dc, err := GenerateDevContainer(cfg, DevContainerOptions{Name: "test", Image: "ai-shell-go:latest", Dir: dcDir, HomeVolume: "ai-home-test", HomeRoot: "/home"})
+	if err != nil {
+		t.Fatalf("Generate failed: %v", err)
+	}
+	data, err := dc.Marshal()
+	if err != nil {
+		t.Fatal(err)
+	}
+	path := filepath.Join(dcDir, "devcontainer.json")
+	if err := os.WriteFile(path, data, 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	parsed, err := ParseDevContainer(path)
+	if err != nil {
+		t.Fatalf("Parse failed: %v\n%s", err, data)
+	}
+	got, err := parsed.ToConfig()
+	if err != nil {
+		t.Fatalf("ToConfig failed: %v\n%s", err, data)
+	}
+
+	if got.Image != "ai-shell-go:latest" {
+		t.Errorf("Expected image ai-shell-go:latest, got %q", got.Image)
+	}
+	if got.Profile != "go" || !got.SSH {
+		t.Errorf("Expected profile go with ssh, got %q %v", got.Profile, got.SSH)
+	}
+	if len(got.Registries) != 1 || got.Registries[0].Registry != "quay.io" {
+		t.Errorf("Expected registries to round-trip, got %+v", got.Registries)
+	}
+	// Literal values are taken from the host environment too
+	for _, want := range []string{"AI_SHELL_TEST_TOKEN", "MODE"} {
+		if !slices.Contains(got.EnvVars, want) {
+			t.Errorf("Expected env var %q, got %v", want, got.EnvVars)
+		}
+	}
+	if !slices.Equal(got.RemoteEnv, cfg.RemoteEnv) {
+		t.Errorf("Expected remote env to round-trip, got %v", got.RemoteEnv)
+	}
+	if !slices.Contains(got.Mounts, Mount{Type: MountTypeBind, Source: "/opt/data", Target: "/data", Options: "ro"}) {
+		t.Errorf("Expected the config mount to round-trip, got %+v", got.Mounts)
+	}
+	// Run passes its own arguments and mounts, and checks the config mounts against them
+	if !slices.Equal(got.PodmanArgs, []string{"--cap-add=NET_ADMIN"}) {
+		t.Errorf("Expected only the config's podman args to round-trip, got %v", got.PodmanArgs)
+	}
+	if err := got.CheckMountConflicts(tmpDir); err != nil {
+		t.Errorf("Expected the generated file to load, got: %v", err)
+	}
+	if len(got.Lifecycle.PostCreate) != 1 || got.Lifecycle.PostCreate[0].Shell != "make deps" {
+		t.Errorf("Expected postCreateCommand to round-trip, got %+v", got.Lifecycle.PostCreate)
+	}
+	expectedPorts := []Port{{Container: 5173, Label: "Vite"}, {Container: 8888, Host: 9999}}
+	if !reflect.DeepEqual(got.Ports, expectedPorts) {
+		t.Errorf("Expected ports to round-trip, got %+v", got.Ports)
+	}
+	for _, value := range []string{"secret", "literal"} {
+		if strings.Contains(string(data), value) {
+			t.Errorf("Expected no env values in the file, Got: %q", value)
+		}
+	}
+	// VS Code applies the limits from runArgs
+	runArgs := strings.Join(dc.RunArgs, " ")
+	for _, want := range []string{"--cpus 2", "--memory 4g", "--pids-limit 512", "--cap-drop all", "no-new-privileges"} {
+		if !strings.Contains(runArgs, want) {
+			t.Errorf("Expected %q in runArgs, Got: %s", want, runArgs)
+		}
+	}
+	if got.Security.Level != SecurityStrict || got.Resources != cfg.Resources {
+		t.Errorf("Expected the limits to round-trip, Got: %+v %+v", got.Security, got.Resources)
+	}
+	// SELinux labeling stays on unless the config disables it
+	if slices.Contains(dc.RunArgs, "label=disable") || !strings.Contains(dc.WorkspaceMount, "relabel=shared") {
+		t.Errorf("Expected relabeled mounts without label=disable, Got: %v %s", dc.RunArgs, dc.WorkspaceMount)
+	}
+
+	// Regenerating from the loaded file gives the same file
+	again, err := GenerateDevContainer(got, DevContainerOptions{Name: "test", Image: "ai-shell-go:latest", Dir: dcDir, HomeVolume: "ai-home-test", HomeRoot: "/home"})
+	if err != nil {
+		t.Fatalf("Regenerate failed: %v", err)
+	}
+	againData, err := again.Marshal()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(againData) != string(data) {
+		t.Errorf("Expected the regenerated file to match.\nFirst:\n%s\nAgain:\n%s", data, againData)
+	}
+}
+
+func TestFormatMountString(t *testing.T) {
+	tests := []Mount{
+		{Type: MountTypeBind, Source: "/a", Target: "/b", Options: "ro"},
+		{Type: MountTypeBind, Source: "/a,b", Target: "/c", Options: "rw,rslave"},
+		{Type: MountTypeVolume, Source: "cache", Target: "/cache", Options: "rw,nocopy"},
+		{Type: MountTypeTmpfs, Target: "/tmp", Options: "rw,size=64m,mode=1777"},
+		{Type: MountTypeBind, Source: "/a", Target: "/b", Options: "rw", Relabel: RelabelPrivate},
+		{Type: MountTypeBind, Source: "/a", Target: "/b", Options: "ro", IDMap: &IDMap{UIDs: "0-1000-1", GIDs: "0-1000-1"}},
+	}
+	for _, m := range tests {
+		s := formatMountString(m)
+		got, err := parseMountString(s)
+		if err != nil {
+			t.Errorf("Input: %+v, Expected to parse %q, Got error: %v", m, s, err)
+			continue
+		}
+		if !reflect.DeepEqual(*got, m) {
+			t.Errorf("Input: %+v, Expected round-trip, Got: %+v (%s)", m, *got, s)
+		}
+	}
+}
+
+func TestGenerateDevContainerLabelDisable(t *testing.T) {
+	cfg := &Config{Security: Security{LabelDisable: true}}
+	dc, err := GenerateDevContainer(cfg, DevContainerOptions{Name: "test", Image: "ai-shell-go:latest", Dir: t.TempDir(), HomeRoot: "/home"})
+	if err != nil {
+		t.Fatalf("Generate failed: %v", err)
+	}
+	if !slices.Contains(dc.RunArgs, "label=disable") {
+		t.Errorf("Input: label_disable, Expected: label=disable in runArgs, Got: %v", dc.RunArgs)
+	}
+	for _, m := range append([]string{dc.WorkspaceMount}, dc.Mounts...) {
+		if strings.Contains(m, "relabel=") {
+			t.Errorf("Input: label_disable, Expected: no relabeled mounts, Got: %s", m)
+		}
+	}
+}
@@ -2,11 +2,13 @@ package config
 
 import (
 	"bufio"
+	"bytes"
 	"crypto/sha256"
 	"fmt"
 	"io"
 	"os"
 	"path/filepath"
+	"slices"
 	"strings"
 
 	"github.com/spf13/viper"
@@ -25,6 +27,11 @@ func LoadConfigWithTrust(startDir string, autoTrust bool) (*Config, string, erro
 			if err != nil {
 				return nil, "", fmt.Errorf("failed to load global config: %w", err)
 			}
+			c.migrateRelabelOptions()
+			if err := c.Validate(); err != nil {
+				return nil, "", fmt.Errorf("invalid global config %s: %w", globalPath, err)
+			}
+			c.Mounts = dedupeMounts(c.Mounts)
 			globalCfg = c
 		}
 	}
@@ -59,36 +66,69 @@ func LoadConfigWithTrust(startDir string, autoTrust bool) (*Config, string, erro
 
 	// 3. Load and Merge Project Config
 	if projectPath != "" {
-		trusted, err := checkTrust(projectPath, autoTrust)
+		// Loaded first so that the prompt can show what the file would do
+		projectCfg, loadErr := loadProjectFile(projectPath, isDevContainer)
+		trusted, err := checkTrust(projectPath, projectCfg, autoTrust)
 		if err != nil {
 			return nil, "", err
 		}
 		if trusted {
-			var projectCfg *Config
-			if isDevContainer {
-				dc, err := ParseDevContainer(projectPath)
-				if err != nil {
-					return nil, projectPath, err
-				}
-				projectCfg = dc.ToConfig()
-			} else {
-				c, _, err := loadFile(projectPath)
-				if err != nil {
-					return nil, projectPath, err
-				}
-				projectCfg = c
+			if loadErr != nil {
+				return nil, projectPath, loadErr
+			}
+			projectCfg.migrateRelabelOptions()
+			if err := projectCfg.Validate(); err != nil {
+				return nil, projectPath, fmt.Errorf("invalid configuration %s: %w", projectPath, err)
+			}
+
+			if len(projectCfg.ProtectedPaths) > 0 {
+				fmt.Println("⚠️  Ignoring protected_paths from project configuration; it can only be set globally.")
+			}
+			if projectCfg.Security.LabelDisable {
+				fmt.Println("⚠️  Ignoring security.label_disable from project configuration; it can only be set globally.")
+			}
+			if projectCfg.Security.Seccomp.Weakened() {
+				fmt.Println("⚠️  Ignoring security.seccomp allow, audit and disable from project configuration; they can only be set globally.")
+			}
+			var denied []string
+			if projectCfg.PodmanArgs, denied = splitProjectPodmanArgs(projectCfg.PodmanArgs); len(denied) > 0 {
+				fmt.Printf("⚠️  Ignoring podman arguments %s from project configuration; use security, network and mounts instead.\n", strings.Join(denied, " "))
+			}
+			if projectCfg.ModelHosts, denied = narrowModelHosts(projectCfg.ModelHosts, globalCfg.ModelHostList()); len(denied) > 0 {
+				fmt.Printf("⚠️  Ignoring model_hosts %s from project configuration; a project can only narrow the global list.\n", strings.Join(denied, " "))
 			}
 
 			mergeConfig(globalCfg, projectCfg)
+			if err := globalCfg.CheckMountConflicts(startDir); err != nil {
+				return nil, projectPath, fmt.Errorf("invalid configuration %s: %w", projectPath, err)
+			}
+			if err := enforcePolicy(globalCfg); err != nil {
+				return nil, projectPath, err
+			}
 			return globalCfg, projectPath, nil
 		}
 		
 		fmt.Println("   Skipping local configuration.")
 	}
 
+	if err := globalCfg.CheckMountConflicts(startDir); err != nil {
+		return nil, "", fmt.Errorf("invalid global config: %w", err)
+	}
+	if err := enforcePolicy(globalCfg); err != nil {
+		return nil, "", err
+	}
 	return globalCfg, "", nil
 }
 
+// enforcePolicy applies the organization policy, if there is one.
+func enforcePolicy(c *Config) error {
+	p, err := LoadPolicy()
+	if err != nil {
+		return err
+	}
+	return p.Enforce(c)
+}
+
 func mergeConfig(base, override *Config) {
 	// EnvVars: Append unique
 	seen := make(map[string]bool)
@@ -102,9 +142,11 @@ func mergeConfig(base, override *Config) {
 		}
 	}
 
-	// Mounts: Append (Project overrides happen naturally if Podman respects last flag,
-	// but strictly we should dedupe by Target. For simplicity, append works as last-mount-wins in Podman usually).
-	base.Mounts = append(base.Mounts, override.Mounts...)
+	// RemoteEnv: Append, the entrypoint sets them in order
+	base.RemoteEnv = append(base.RemoteEnv, override.RemoteEnv...)
+
+	// Mounts: Append, the last mount of a target wins
+	base.Mounts = dedupeMounts(append(base.Mounts, override.Mounts...))
 
 	// Args: Append
 	base.PodmanArgs = append(base.PodmanArgs, override.PodmanArgs...)
@@ -114,7 +156,141 @@ func mergeConfig(base, override *Config) {
 
 	// SCMs: Append
 	base.SCMs = append(base.SCMs, override.SCMs...)
+
+	// Image: Override (a project build wins over an inherited image and vice versa)
+	if override.Image != "" || override.Build != nil {
+		base.Image = override.Image
+		base.Build = override.Build
+	}
+
+	// Workspace & Users: Override
+	if override.WorkspaceFolder != "" {
+		base.WorkspaceFolder = override.WorkspaceFolder
+	}
+	if override.WorkspaceMount != nil {
+		base.WorkspaceMount = override.WorkspaceMount
+	}
+	if override.RemoteUser != "" {
+		base.RemoteUser = override.RemoteUser
+	}
+	if override.ContainerUser != "" {
+		base.ContainerUser = override.ContainerUser
+	}
+
+	// Features: Append
+	base.Features = append(base.Features, override.Features...)
+
+	// Lifecycle: Append per stage
+	base.Lifecycle.Initialize = append(base.Lifecycle.Initialize, override.Lifecycle.Initialize...)
+	base.Lifecycle.OnCreate = append(base.Lifecycle.OnCreate, override.Lifecycle.OnCreate...)
+	base.Lifecycle.UpdateContent = append(base.Lifecycle.UpdateContent, override.Lifecycle.UpdateContent...)
+	base.Lifecycle.PostCreate = append(base.Lifecycle.PostCreate, override.Lifecycle.PostCreate...)
+	base.Lifecycle.PostStart = append(base.Lifecycle.PostStart, override.Lifecycle.PostStart...)
+	base.Lifecycle.PostAttach = append(base.Lifecycle.PostAttach, override.Lifecycle.PostAttach...)
+
+	// Profile: Override, SSH & NetHost: Enable only
+	if override.Profile != "" {
+		base.Profile = override.Profile
+	}
+	base.SSH = base.SSH || override.SSH
+	base.NetHost = base.NetHost || override.NetHost
+	// Offline: Enable only, a project can ask for less network but not more,
+	// so it cannot open model egress if the global config is offline already
+	base.ModelEgress = base.ModelEgress || (override.ModelEgress && !base.Offline)
+	base.Offline = base.Offline || override.Offline
+	// ModelHosts: Override, the loader narrows a project's list
+	if len(override.ModelHosts) > 0 {
+		base.ModelHosts = override.ModelHosts
+	}
+
+	// HostPorts: Append unique
+	for _, p := range override.HostPorts {
+		if !slices.Contains(base.HostPorts, p) {
+			base.HostPorts = append(base.HostPorts, p)
+		}
+	}
+
+	// Ports: Append, the last entry of a container port wins
+	base.Ports = dedupePorts(append(base.Ports, override.Ports...))
+
+	// DNS, ExtraHosts & CACerts: Append unique
+	base.DNS = appendUnique(base.DNS, override.DNS...)
+	base.ExtraHosts = appendUnique(base.ExtraHosts, override.ExtraHosts...)
+	base.CACerts = appendUnique(base.CACerts, override.CACerts...)
+
+	// Proxy: Override per value
+	if override.Proxy.HTTP != "" {
+		base.Proxy.HTTP = override.Proxy.HTTP
+	}
+	if override.Proxy.HTTPS != "" {
+		base.Proxy.HTTPS = override.Proxy.HTTPS
+	}
+	if len(override.Proxy.NoProxy) > 0 {
+		base.Proxy.NoProxy = override.Proxy.NoProxy
+	}
+	base.Proxy.FromHost = base.Proxy.FromHost || override.Proxy.FromHost
+
+	// Network: Append, a project can only extend the allowlist
+	base.Network.Allow = append(base.Network.Allow, override.Network.Allow...)
+	// Audit: Enable only
+	base.Network.Audit = base.Network.Audit || override.Network.Audit
+
+	// Security: a project can only raise the level, block more syscalls, and
+	// never disable labeling
+	if override.Security.Level != "" && override.Security.AtLeast(base.Security.EffectiveLevel()) {
+		base.Security.Level = override.Security.Level
+	}
+	base.Security.Seccomp.Block = appendUnique(base.Security.Seccomp.Block, override.Security.Seccomp.Block...)
+
+	// Record: Enable only
+	base.Record = base.Record || override.Record
+	// CommandAudit: Enable only
+	base.CommandAudit = base.CommandAudit || override.CommandAudit
+
+	// Approval: a project can hold back more commands
+	base.Approval.Commands = appendUnique(base.Approval.Commands, override.Approval.Commands...)
+	if override.Approval.Timeout != 0 {
+		base.Approval.Timeout = override.Approval.Timeout
+	}
+
+	// Sandbox: a project can sandbox more agents and hide more
+	base.Sandbox.Agents = appendUnique(base.Sandbox.Agents, override.Sandbox.Agents...)
+	base.Sandbox.Hide = appendUnique(base.Sandbox.Hide, override.Sandbox.Hide...)
+	base.Sandbox.Env = appendUnique(base.Sandbox.Env, override.Sandbox.Env...)
+
+	// Resources: Override per limit
+	if override.Resources.CPUs != 0 {
+		base.Resources.CPUs = override.Resources.CPUs
+	}
+	if override.Resources.Memory != "" {
+		base.Resources.Memory = override.Resources.Memory
+	}
+	if override.Resources.MemorySwap != "" {
+		base.Resources.MemorySwap = override.Resources.MemorySwap
+	}
+	if override.Resources.PidsLimit != 0 {
+		base.Resources.PidsLimit = override.Resources.PidsLimit
+	}
+	if override.Resources.TmpfsSize != "" {
+		base.Resources.TmpfsSize = override.Resources.TmpfsSize
+	}
+	if override.Resources.HomeSize != "" {
+		base.Resources.HomeSize = override.Resources.HomeSize
+	}
+
+	// ProtectedPaths: never taken from the override, a project must not be able
+	// to weaken the list of paths it is allowed to mount.
+}
+
+func appendUnique[T comparable](list []T, values ...T) []T {
+	for _, v := range values {
+		if !slices.Contains(list, v) {
+			list = append(list, v)
+		}
+	}
+	return list
 }
+
 func loadFile(path string) (*Config, string, error) {
 	v := viper.New()
 	v.SetConfigFile(path)
@@ -128,7 +304,67 @@ func loadFile(path string) (*Config, string, error) {
 	return &cfg, path, nil
 }
 
-func checkTrust(path string, autoTrust bool) (bool, error) {
+// loadJSON decodes a config given as JSON, with the same keys as config.yaml.
+func loadJSON(data []byte) (*Config, error) {
+	v := viper.New()
+	v.SetConfigType("json")
+	if err := v.ReadConfig(bytes.NewReader(data)); err != nil {
+		return nil, fmt.Errorf("failed to read config: %w", err)
+	}
+	var cfg Config
+	if err := v.Unmarshal(&cfg); err != nil {
+		return nil, fmt.Errorf("failed to parse config: %w", err)
+	}
+	return &cfg, nil
+}
+
+// loadProjectFile loads a devcontainer.json or an .ai-shell.yaml.
+func loadProjectFile(path string, isDevContainer bool) (*Config, error) {
+	if !isDevContainer {
+		c, _, err := loadFile(path)
+		return c, err
+	}
+	dc, err := ParseDevContainer(path)
+	if err != nil {
+		return nil, err
+	}
+	return dc.ToConfig()
+}
+
+// trustWarning describes what trusting a project config allows, with the
+// host commands cfg runs and what it builds. cfg is nil if it did not load.
+func trustWarning(cfg *Config) []string {
+	lines := []string{
+		"This file can run commands on your host, build images from its own Dockerfile and features,",
+		"and change the mounts, the user, the environment and the registry credentials of the container.",
+	}
+	if cfg == nil {
+		return lines
+	}
+	if len(cfg.Lifecycle.Initialize) > 0 {
+		lines = append(lines, "It runs on your host, before each start:")
+		for _, c := range cfg.Lifecycle.Initialize {
+			cmd := c.Shell
+			if cmd == "" {
+				cmd = strings.Join(c.Args, " ")
+			}
+			lines = append(lines, "  $ "+cmd)
+		}
+	}
+	if cfg.Build != nil && cfg.Build.Dockerfile != "" {
+		lines = append(lines, "It builds "+cfg.Build.Dockerfile+".")
+	}
+	if len(cfg.Features) > 0 {
+		ids := make([]string, len(cfg.Features))
+		for i, f := range cfg.Features {
+			ids[i] = f.ID
+		}
+		lines = append(lines, "It installs the features "+strings.Join(ids, ", ")+".")
+	}
+	return lines
+}
+
+func checkTrust(path string, cfg *Config, autoTrust bool) (bool, error) {
 	if autoTrust {
 		return true, nil
 	}
@@ -167,7 +403,9 @@ func checkTrust(path string, autoTrust bool) (bool, error) {
 	}
 
 	fmt.Printf("⚠️  Found project configuration: %s\n", path)
-	fmt.Println("   This file can modify environment variables and registry credentials.")
+	for _, line := range trustWarning(cfg) {
+		fmt.Printf("   %s\n", line)
+	}
 	fmt.Printf("   Fingerprint: %s\n", hash)
 	fmt.Print("   Do you trust this configuration? [y/N] ")
 
@@ -0,0 +1,195 @@
+package config
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// Relabel values
+const (
+	RelabelShared  = "shared"
+	RelabelPrivate = "private"
+)
+
+// relabelOptions are the podman options relabel replaces. They are still
+// accepted in options, see migrateRelabelOptions.
+var relabelOptions = map[string]string{
+	"z": RelabelShared,
+	"Z": RelabelPrivate,
+}
+
+var idMapPattern = regexp.MustCompile(`^@?\d+-\d+-\d+(#@?\d+-\d+-\d+)*$`)
+
+// Validate checks the mount for unsupported types and options that do not
+// apply to its type. Sources are only checked when the container starts.
+func (m Mount) Validate() error {
+	if m.Target == "" {
+		return fmt.Errorf("missing target")
+	}
+	if !strings.HasPrefix(m.Target, "/") && !strings.HasPrefix(m.Target, "$") {
+		return fmt.Errorf("target %s is not an absolute path", m.Target)
+	}
+
+	switch m.Type {
+	case "", MountTypeBind:
+		if m.Source == "" {
+			return fmt.Errorf("%s: bind mounts need a source", m.Target)
+		}
+	case MountTypeVolume, MountTypeTmpfs:
+		if m.Type == MountTypeTmpfs && m.Source != "" {
+			return fmt.Errorf("%s: tmpfs mounts do not take a source", m.Target)
+		}
+		if m.Type == MountTypeVolume && m.Source != "" && !IsVolumeName(m.Source) {
+			return fmt.Errorf("%s: volume source %s is not a volume name, use a bind mount for host paths", m.Target, m.Source)
+		}
+		if m.Required || m.Create {
+			return fmt.Errorf("%s: required and create only apply to bind mounts", m.Target)
+		}
+		if m.Relabel != "" {
+			return fmt.Errorf("%s: relabel only applies to bind mounts", m.Target)
+		}
+		if m.IDMap != nil {
+			return fmt.Errorf("%s: idmap only applies to bind mounts", m.Target)
+		}
+	default:
+		return fmt.Errorf("%s: unsupported type %s", m.Target, m.Type)
+	}
+
+	switch m.Relabel {
+	case "", RelabelShared, RelabelPrivate:
+	default:
+		return fmt.Errorf("%s: relabel must be %s or %s, got %s", m.Target, RelabelShared, RelabelPrivate, m.Relabel)
+	}
+
+	if m.IDMap != nil {
+		if m.IDMap.UIDs == "" && m.IDMap.GIDs == "" {
+			return fmt.Errorf("%s: idmap needs uids or gids", m.Target)
+		}
+		for _, r := range []string{m.IDMap.UIDs, m.IDMap.GIDs} {
+			if r != "" && !idMapPattern.MatchString(r) {
+				return fmt.Errorf("%s: invalid idmap range %q, expected container-host-size", m.Target, r)
+			}
+		}
+	}
+
+	for _, o := range strings.Split(m.Options, ",") {
+		if r, ok := relabelOptions[o]; ok && m.Relabel != "" && m.Relabel != r {
+			return fmt.Errorf("%s: the %s option conflicts with relabel: %s", m.Target, o, m.Relabel)
+		}
+	}
+	return nil
+}
+
+// migrateRelabel moves a z or Z option to Relabel, unless Relabel says
+// otherwise, and returns the option it moved.
+func (m *Mount) migrateRelabel() string {
+	var moved string
+	var out []string
+	for _, o := range strings.Split(m.Options, ",") {
+		if r, ok := relabelOptions[o]; ok && (m.Relabel == "" || m.Relabel == r) {
+			m.Relabel = r
+			moved = o
+			continue
+		}
+		out = append(out, o)
+	}
+	if moved != "" {
+		m.Options = strings.Join(out, ",")
+	}
+	return moved
+}
+
+// migrateRelabelOptions maps the z and Z options of the mounts onto relabel,
+// with a deprecation warning.
+func (c *Config) migrateRelabelOptions() {
+	mounts := make([]*Mount, 0, len(c.Mounts)+1)
+	for i := range c.Mounts {
+		mounts = append(mounts, &c.Mounts[i])
+	}
+	if c.WorkspaceMount != nil {
+		mounts = append(mounts, c.WorkspaceMount)
+	}
+	for _, m := range mounts {
+		if o := m.migrateRelabel(); o != "" {
+			fmt.Printf("⚠️  %s: the %s mount option is deprecated, use relabel: %s instead.\n", m.Target, o, m.Relabel)
+		}
+	}
+}
+
+// IsVolumeName reports whether s names a podman volume rather than a host
+// path, which podman would bind mount instead.
+func IsVolumeName(s string) bool {
+	return !strings.Contains(s, "/") && !strings.HasPrefix(s, ".") && !strings.HasPrefix(s, "~")
+}
+
+// ResolvedOptions returns the podman options of the mount: Options (or def
+// if it is empty) with the structured settings applied.
+func (m Mount) ResolvedOptions(def string) string {
+	opts := m.Options
+	if opts == "" {
+		opts = def
+	}
+
+	relabel := m.Relabel
+	var out []string
+	for _, o := range strings.Split(opts, ",") {
+		if r, ok := relabelOptions[o]; ok {
+			if relabel == "" {
+				relabel = r
+			}
+			continue
+		}
+		if o == "" || (m.ReadOnly != nil && (o == "ro" || o == "rw")) {
+			continue
+		}
+		out = append(out, o)
+	}
+	if m.ReadOnly != nil {
+		mode := "rw"
+		if *m.ReadOnly {
+			mode = "ro"
+		}
+		out = append([]string{mode}, out...)
+	}
+
+	switch relabel {
+	case RelabelShared:
+		out = append(out, "z")
+	case RelabelPrivate:
+		out = append(out, "Z")
+	}
+	if m.IDMap != nil {
+		out = append(out, "idmap="+m.IDMap.String())
+	}
+	return strings.Join(out, ",")
+}
+
+// String formats the map the way podman's idmap option expects it.
+func (i IDMap) String() string {
+	var parts []string
+	if i.UIDs != "" {
+		parts = append(parts, "uids="+i.UIDs)
+	}
+	if i.GIDs != "" {
+		parts = append(parts, "gids="+i.GIDs)
+	}
+	return strings.Join(parts, ";")
+}
+
+// parseIDMap is the inverse of IDMap.String.
+func parseIDMap(s string) (*IDMap, error) {
+	m := &IDMap{}
+	for _, part := range strings.Split(s, ";") {
+		k, v, _ := strings.Cut(part, "=")
+		switch k {
+		case "uids":
+			m.UIDs = v
+		case "gids":
+			m.GIDs = v
+		default:
+			return nil, fmt.Errorf("invalid idmap %q", s)
+		}
+	}
+	return m, nil
+}
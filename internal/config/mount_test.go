@@ -0,0 +1,97 @@
+package config
+
+import "testing"
+
+func TestMountValidate(t *testing.T) {
+	yes := true
+	tests := []struct {
+		mount Mount
+		valid bool
+	}{
+		{Mount{Source: "/a", Target: "/b"}, true},
+		{Mount{Source: "$HOME/.kube", Target: "$HOME/.kube", Required: true}, true},
+		{Mount{Source: "/a", Target: "/b", Create: true, ReadOnly: &yes, Relabel: "private"}, true},
+		{Mount{Source: "/a", Target: "/b", IDMap: &IDMap{UIDs: "0-1000-1", GIDs: "0-1000-1#1-100000-65536"}}, true},
+		{Mount{Type: MountTypeVolume, Target: "/cache"}, true},
+		{Mount{Type: MountTypeTmpfs, Target: "/tmp", Options: "size=64m"}, true},
+		{Mount{Target: "/b"}, false},
+		{Mount{Source: "/a"}, false},
+		{Mount{Source: "/a", Target: "b"}, false},
+		{Mount{Type: "npipe", Source: "/a", Target: "/b"}, false},
+		{Mount{Type: MountTypeTmpfs, Source: "/a", Target: "/b"}, false},
+		{Mount{Type: MountTypeVolume, Source: "v", Target: "/b", Required: true}, false},
+		{Mount{Type: MountTypeVolume, Source: "v", Target: "/b", Relabel: "shared"}, false},
+		{Mount{Type: MountTypeVolume, Source: "cache-${localWorkspaceFolderBasename}", Target: "/b"}, true},
+		// Podman bind mounts paths given as volume sources
+		{Mount{Type: MountTypeVolume, Source: "/root/.ssh", Target: "/b"}, false},
+		{Mount{Type: MountTypeVolume, Source: "~/.ssh", Target: "/b"}, false},
+		{Mount{Type: MountTypeVolume, Source: "../secrets", Target: "/b"}, false},
+		{Mount{Source: "/a", Target: "/b", Relabel: "yes"}, false},
+		// z and Z are deprecated in favor of relabel
+		{Mount{Source: "/a", Target: "/b", Options: "ro,Z"}, true},
+		{Mount{Source: "/a", Target: "/b", Options: "ro,Z", Relabel: "private"}, true},
+		{Mount{Source: "/a", Target: "/b", Options: "ro,z", Relabel: "private"}, false},
+		{Mount{Source: "/a", Target: "/b", IDMap: &IDMap{}}, false},
+		{Mount{Source: "/a", Target: "/b", IDMap: &IDMap{UIDs: "1000"}}, false},
+	}
+
+	for _, tt := range tests {
+		err := tt.mount.Validate()
+		if (err == nil) != tt.valid {
+			t.Errorf("Input: %+v, Expected valid: %v, Got: %v", tt.mount, tt.valid, err)
+		}
+	}
+}
+
+func TestMountResolvedOptions(t *testing.T) {
+	yes, no := true, false
+	tests := []struct {
+		mount    Mount
+		expected string
+	}{
+		{Mount{}, "ro"},
+		{Mount{Options: "rw,rslave"}, "rw,rslave"},
+		{Mount{Options: "rw,rslave", ReadOnly: &yes}, "ro,rslave"},
+		{Mount{ReadOnly: &no}, "rw"},
+		{Mount{Relabel: RelabelShared}, "ro,z"},
+		{Mount{Relabel: RelabelPrivate, IDMap: &IDMap{UIDs: "0-1000-1", GIDs: "0-1000-1"}}, "ro,Z,idmap=uids=0-1000-1;gids=0-1000-1"},
+		{Mount{Options: "z,ro"}, "ro,z"},
+		{Mount{Options: "ro,Z", Relabel: RelabelPrivate}, "ro,Z"},
+	}
+
+	for _, tt := range tests {
+		if got := tt.mount.ResolvedOptions("ro"); got != tt.expected {
+			t.Errorf("Input: %+v, Expected: %q, Got: %q", tt.mount, tt.expected, got)
+		}
+	}
+}
+
+func TestMigrateRelabelOptions(t *testing.T) {
+	c := &Config{
+		Mounts: []Mount{
+			{Source: "/a", Target: "/a", Options: "ro,Z"},
+			{Source: "/b", Target: "/b", Options: "z", Relabel: RelabelPrivate},
+			{Source: "/c", Target: "/c", Options: "rw"},
+		},
+		WorkspaceMount: &Mount{Source: "/src", Target: "/src", Options: "rw,z"},
+	}
+	c.migrateRelabelOptions()
+
+	expected := []Mount{
+		{Source: "/a", Target: "/a", Options: "ro", Relabel: RelabelPrivate},
+		// A conflict is left for Validate
+		{Source: "/b", Target: "/b", Options: "z", Relabel: RelabelPrivate},
+		{Source: "/c", Target: "/c", Options: "rw"},
+	}
+	for i, m := range c.Mounts {
+		if m.Options != expected[i].Options || m.Relabel != expected[i].Relabel {
+			t.Errorf("Expected: %+v, Got: %+v", expected[i], m)
+		}
+	}
+	if c.WorkspaceMount.Options != "rw" || c.WorkspaceMount.Relabel != RelabelShared {
+		t.Errorf("Expected the workspace mount relabeled shared, Got: %+v", c.WorkspaceMount)
+	}
+	if err := c.Validate(); err == nil {
+		t.Error("Expected an error for z with relabel: private")
+	}
+}
@@ -0,0 +1,192 @@
+package config
+
+import (
+	"fmt"
+	"net"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// DefaultModelHosts are the model APIs the agents talk to, unless the config
+// lists its own in model_hosts. They are the only destinations left in
+// offline mode with model egress.
+var DefaultModelHosts = []string{
+	"api.anthropic.com",
+	"statsig.anthropic.com",
+	"generativelanguage.googleapis.com",
+	"api.openai.com",
+}
+
+// DefaultAllow is reachable whenever egress is filtered, along with the model
+// hosts: package registries. Configured SCM and registry hosts are added to it.
+var DefaultAllow = []string{
+	"registry.npmjs.org",
+	"pypi.org",
+	"files.pythonhosted.org",
+	"proxy.golang.org",
+	"sum.golang.org",
+	"storage.googleapis.com",
+	"crates.io",
+	"*.crates.io",
+	"rubygems.org",
+	"repo.maven.apache.org",
+}
+
+// Network controls what the container can reach.
+type Network struct {
+	// Allow turns on egress filtering: the container can only reach these
+	// destinations (plus DefaultAllow) through ai-shell's proxy. Entries are
+	// domains ("github.com", "*.github.com"), IPs or CIDRs, each with an
+	// optional port or port range ("10.0.0.0/8:5000-5010", "[fd00::1]:443").
+	Allow []string `mapstructure:"allow" yaml:"allow" json:"allow,omitempty"`
+	// Audit records the DNS queries and outbound connections of every
+	// session, see the session package.
+	Audit bool `mapstructure:"audit" yaml:"audit" json:"audit,omitempty"`
+}
+
+// AllowRule is a parsed entry of Network.Allow.
+type AllowRule struct {
+	// Domain matches the host name, and all subdomains if Subdomains is set
+	Domain     string
+	Subdomains bool
+	// CIDR matches the destination address
+	CIDR string
+	// Ports is empty for the web ports (80 and 443), otherwise "443" or "8000-8100"
+	Ports string
+}
+
+var domainPattern = regexp.MustCompile(`^[a-zA-Z0-9]([a-zA-Z0-9-]*[a-zA-Z0-9])?(\.[a-zA-Z0-9]([a-zA-Z0-9-]*[a-zA-Z0-9])?)*$`)
+
+// Filtered reports whether egress goes through the filtering proxy.
+func (n Network) Filtered() bool {
+	return len(n.Allow) > 0
+}
+
+// ModelHostList returns the model hosts of c, DefaultModelHosts if it lists
+// none.
+func (c *Config) ModelHostList() []string {
+	if c == nil || len(c.ModelHosts) == 0 {
+		return DefaultModelHosts
+	}
+	return c.ModelHosts
+}
+
+// narrowModelHosts separates the hosts that allowed lists from the others.
+func narrowModelHosts(hosts, allowed []string) (kept, removed []string) {
+	for _, h := range hosts {
+		if slices.Contains(allowed, h) {
+			kept = append(kept, h)
+		} else {
+			removed = append(removed, h)
+		}
+	}
+	return kept, removed
+}
+
+// AllowRules returns the rules of the effective allowlist: the model hosts,
+// DefaultAllow, the SCM and registry hosts of c, then Network.Allow.
+func (c *Config) AllowRules() ([]AllowRule, error) {
+	entries := append(append([]string{}, c.ModelHostList()...), DefaultAllow...)
+	for _, s := range c.SCMs {
+		if s.Host != "" {
+			entries = append(entries, s.Host, "*."+s.Host)
+		}
+	}
+	for _, r := range c.Registries {
+		if r.Registry != "" {
+			entries = append(entries, r.Registry)
+		}
+	}
+	entries = append(entries, c.Network.Allow...)
+	return parseAllowRules(entries)
+}
+
+// ModelAllowRules returns the rules for offline mode with model egress.
+func (c *Config) ModelAllowRules() ([]AllowRule, error) {
+	return parseAllowRules(c.ModelHostList())
+}
+
+// parseAllowRules parses entries, dropping duplicates.
+func parseAllowRules(entries []string) ([]AllowRule, error) {
+	var rules []AllowRule
+	seen := make(map[AllowRule]bool)
+	for _, e := range entries {
+		r, err := ParseAllowRule(e)
+		if err != nil {
+			return nil, err
+		}
+		if !seen[r] {
+			seen[r] = true
+			rules = append(rules, r)
+		}
+	}
+	return rules, nil
+}
+
+// ParseAllowRule parses an entry of Network.Allow.
+func ParseAllowRule(s string) (AllowRule, error) {
+	var r AllowRule
+	host := strings.TrimSpace(s)
+	if host == "" {
+		return r, fmt.Errorf("empty network.allow entry")
+	}
+
+	// Split off the port; IPv6 addresses need brackets to carry one
+	if strings.HasPrefix(host, "[") {
+		end := strings.Index(host, "]")
+		if end < 0 {
+			return r, fmt.Errorf("invalid network.allow entry %q", s)
+		}
+		rest := host[end+1:]
+		host = host[1:end]
+		if rest != "" {
+			if !strings.HasPrefix(rest, ":") {
+				return r, fmt.Errorf("invalid network.allow entry %q", s)
+			}
+			r.Ports = rest[1:]
+		}
+	} else if strings.Count(host, ":") == 1 {
+		host, r.Ports, _ = strings.Cut(host, ":")
+	}
+	if r.Ports != "" && !validPorts(r.Ports) {
+		return r, fmt.Errorf("invalid port in network.allow entry %q", s)
+	}
+
+	switch {
+	case strings.Contains(host, "/"):
+		_, ipNet, err := net.ParseCIDR(host)
+		if err != nil {
+			return r, fmt.Errorf("invalid CIDR in network.allow entry %q", s)
+		}
+		r.CIDR = ipNet.String()
+	case net.ParseIP(host) != nil:
+		ip := net.ParseIP(host)
+		bits := 128
+		if ip.To4() != nil {
+			bits = 32
+		}
+		r.CIDR = fmt.Sprintf("%s/%d", ip, bits)
+	default:
+		if strings.HasPrefix(host, "*.") || strings.HasPrefix(host, ".") {
+			r.Subdomains = true
+			host = strings.TrimPrefix(strings.TrimPrefix(host, "*"), ".")
+		}
+		if !domainPattern.MatchString(host) {
+			return r, fmt.Errorf("invalid domain in network.allow entry %q", s)
+		}
+		r.Domain = strings.ToLower(host)
+	}
+	return r, nil
+}
+
+func validPorts(s string) bool {
+	lo, hi, isRange := strings.Cut(s, "-")
+	if !isRange {
+		hi = lo
+	}
+	l, err1 := strconv.Atoi(lo)
+	h, err2 := strconv.Atoi(hi)
+	return err1 == nil && err2 == nil && l > 0 && h <= 65535 && l <= h
+}
@@ -0,0 +1,133 @@
+package config
+
+import "testing"
+
+func TestParseAllowRule(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected AllowRule
+		valid    bool
+	}{
+		{"github.com", AllowRule{Domain: "github.com"}, true},
+		{"*.GitHub.com", AllowRule{Domain: "github.com", Subdomains: true}, true},
+		{".github.com:443", AllowRule{Domain: "github.com", Subdomains: true, Ports: "443"}, true},
+		{"registry.local:5000", AllowRule{Domain: "registry.local", Ports: "5000"}, true},
+		{"10.0.0.0/8:8000-8100", AllowRule{CIDR: "10.0.0.0/8", Ports: "8000-8100"}, true},
+		{"192.168.1.10", AllowRule{CIDR: "192.168.1.10/32"}, true},
+		{"fd00::/8", AllowRule{CIDR: "fd00::/8"}, true},
+		{"[fd00::1]:443", AllowRule{CIDR: "fd00::1/128", Ports: "443"}, true},
+		{"", AllowRule{}, false},
+		{"github.com:0", AllowRule{}, false},
+		{"github.com:99999", AllowRule{}, false},
+		{"github.com:9000-8000", AllowRule{}, false},
+		{"10.0.0.0/33", AllowRule{}, false},
+		{"https://github.com", AllowRule{}, false},
+		{"*github.com", AllowRule{}, false},
+		{"[fd00::1", AllowRule{}, false},
+	}
+
+	for _, tt := range tests {
+		got, err := ParseAllowRule(tt.input)
+		if (err == nil) != tt.valid {
+			t.Errorf("Input: %s, Expected valid: %v, Got: %v", tt.input, tt.valid, err)
+			continue
+		}
+		if tt.valid && got != tt.expected {
+			t.Errorf("Input: %s, Expected: %+v, Got: %+v", tt.input, tt.expected, got)
+		}
+	}
+}
+
+func TestAllowRules(t *testing.T) {
+	cfg := &Config{
+		SCMs:       []SCM{{Host: "gitlab.example.com"}},
+		Registries: []Registry{{Registry: "quay.io"}},
+		Network:    Network{Allow: []string{"api.anthropic.com", "internal.example.com:8443"}},
+	}
+	rules, err := cfg.AllowRules()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	has := func(r AllowRule) bool {
+		for _, got := range rules {
+			if got == r {
+				return true
+			}
+		}
+		return false
+	}
+	for _, r := range []AllowRule{
+		{Domain: "api.anthropic.com"},
+		{Domain: "registry.npmjs.org"},
+		{Domain: "gitlab.example.com", Subdomains: true},
+		{Domain: "quay.io"},
+		{Domain: "internal.example.com", Ports: "8443"},
+	} {
+		if !has(r) {
+			t.Errorf("Expected rule %+v in %+v", r, rules)
+		}
+	}
+
+	// Duplicates of the defaults are dropped
+	count := 0
+	for _, r := range rules {
+		if r.Domain == "api.anthropic.com" {
+			count++
+		}
+	}
+	if count != 1 {
+		t.Errorf("Expected api.anthropic.com once, got %d times", count)
+	}
+
+	if err := (&Config{NetHost: true, Network: Network{Allow: []string{"github.com"}}}).Validate(); err == nil {
+		t.Error("Expected net_host and network.allow to be rejected")
+	}
+}
+
+func TestMergeConfigOffline(t *testing.T) {
+	tests := []struct {
+		base, override Config
+		offline, model bool
+	}{
+		{Config{}, Config{Offline: true}, true, false},
+		{Config{Offline: true}, Config{}, true, false},
+		{Config{}, Config{Offline: true, ModelEgress: true}, true, true},
+		{Config{Offline: true, ModelEgress: true}, Config{}, true, true},
+		// A project cannot open model egress in a globally offline setup
+		{Config{Offline: true}, Config{Offline: true, ModelEgress: true}, true, false},
+	}
+
+	for i, tt := range tests {
+		base := tt.base
+		mergeConfig(&base, &tt.override)
+		if base.Offline != tt.offline || base.ModelEgress != tt.model {
+			t.Errorf("Case %d: Expected offline=%v model_egress=%v, Got: %v %v", i, tt.offline, tt.model, base.Offline, base.ModelEgress)
+		}
+	}
+}
+
+func TestModelHosts(t *testing.T) {
+	var c *Config
+	rules, err := c.ModelAllowRules()
+	if err != nil || len(rules) != len(DefaultModelHosts) {
+		t.Fatalf("Expected the default model hosts, Got: %+v (%v)", rules, err)
+	}
+	for _, r := range rules {
+		if r.Subdomains {
+			t.Errorf("Expected no wildcard in the default model hosts, Got: %+v", r)
+		}
+	}
+
+	c = &Config{ModelHosts: []string{"llm.internal.example:8443"}}
+	rules, err = c.ModelAllowRules()
+	if err != nil || len(rules) != 1 || rules[0].Domain != "llm.internal.example" {
+		t.Errorf("Expected only the configured model host, Got: %+v (%v)", rules, err)
+	}
+
+	// A project can only narrow the list
+	kept, removed := narrowModelHosts([]string{"api.anthropic.com", "*.googleapis.com"}, DefaultModelHosts)
+	if len(kept) != 1 || kept[0] != "api.anthropic.com" || len(removed) != 1 || removed[0] != "*.googleapis.com" {
+		t.Errorf("Expected *.googleapis.com removed, Got: kept %v, removed %v", kept, removed)
+	}
+}
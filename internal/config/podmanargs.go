@@ -0,0 +1,113 @@
+package config
+
+import "strings"
+
+// projectAllowedFlags are the podman flags a project config may pass in
+// podman_args (or runArgs). Anything else could undo what ai-shell applies:
+// the entrypoint, the user, the security level, seccomp, SELinux separation,
+// network filtering, protected paths or its own environment.
+var projectAllowedFlags = map[string]bool{
+	"--add-host":     true,
+	"--annotation":   true,
+	"--label":        true,
+	"-l":             true,
+	"--init":         true,
+	"--tz":           true,
+	"--ulimit":       true,
+	"--stop-signal":  true,
+	"--stop-timeout": true,
+	"--platform":     true,
+	"--arch":         true,
+	"--pull":         true,
+	"--log-driver":   true,
+	"--log-opt":      true,
+	// Resources, which a policy caps
+	"--cpus":               true,
+	"--cpu-period":         true,
+	"--cpu-quota":          true,
+	"--cpu-shares":         true,
+	"-c":                   true,
+	"--cpuset-cpus":        true,
+	"--memory":             true,
+	"-m":                   true,
+	"--memory-swap":        true,
+	"--memory-reservation": true,
+	"--pids-limit":         true,
+	"--shm-size":           true,
+}
+
+// resourceFlags set what Resources limits, which a policy caps.
+var resourceFlags = map[string]bool{
+	"--cpus":               true,
+	"--cpu-period":         true,
+	"--cpu-quota":          true,
+	"--cpu-shares":         true,
+	"-c":                   true,
+	"--cpuset-cpus":        true,
+	"--memory":             true,
+	"-m":                   true,
+	"--memory-swap":        true,
+	"--memory-reservation": true,
+	"--pids-limit":         true,
+	"--tmpfs":              true,
+	"--shm-size":           true,
+}
+
+// boolFlags take no separate value.
+var boolFlags = map[string]bool{
+	"--init":            true,
+	"--privileged":      true,
+	"--read-only":       true,
+	"--read-only-tmpfs": true,
+	"--rm":              true,
+	"-d":                true,
+	"--detach":          true,
+	"-i":                true,
+	"--interactive":     true,
+	"-t":                true,
+	"--tty":             true,
+	"--no-hosts":        true,
+	"--replace":         true,
+}
+
+// splitPodmanArgs separates the given flags, with their values, from the
+// other podman arguments.
+func splitPodmanArgs(args []string, flags map[string]bool) (kept, removed []string) {
+	for i := 0; i < len(args); i++ {
+		name, _, hasValue := strings.Cut(args[i], "=")
+		if !flags[name] {
+			kept = append(kept, args[i])
+			continue
+		}
+		removed = append(removed, args[i])
+		if !hasValue && !boolFlags[name] && i+1 < len(args) {
+			i++
+			removed = append(removed, args[i])
+		}
+	}
+	return kept, removed
+}
+
+// splitProjectPodmanArgs separates the flags a project may not pass, with
+// their values, from the ones in projectAllowedFlags. A flag it does not know
+// takes the next argument with it unless that looks like a flag.
+func splitProjectPodmanArgs(args []string) (kept, removed []string) {
+	for i := 0; i < len(args); i++ {
+		name, _, hasValue := strings.Cut(args[i], "=")
+		takesValue := !hasValue && !boolFlags[name] && i+1 < len(args)
+		if !projectAllowedFlags[name] {
+			removed = append(removed, args[i])
+			if takesValue && !strings.HasPrefix(args[i+1], "-") {
+				i++
+				removed = append(removed, args[i])
+			}
+			continue
+		}
+		kept = append(kept, args[i])
+		if takesValue {
+			i++
+			kept = append(kept, args[i])
+		}
+	}
+	return kept, removed
+}
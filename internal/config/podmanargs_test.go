@@ -0,0 +1,53 @@
+package config
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestSplitProjectPodmanArgs(t *testing.T) {
+	tests := []struct {
+		args    []string
+		kept    string
+		removed string
+	}{
+		{[]string{"--init", "--shm-size=1g"}, "--init --shm-size=1g", ""},
+		{[]string{"--cap-add=ALL", "--init"}, "--init", "--cap-add=ALL"},
+		{[]string{"--security-opt", "seccomp=unconfined", "--init"}, "--init", "--security-opt seccomp=unconfined"},
+		{[]string{"--privileged", "--init"}, "--init", "--privileged"},
+		{[]string{"-v", "/root/.ssh:/x", "--network=host"}, "", "-v /root/.ssh:/x --network=host"},
+		{[]string{"--add-host", "db:10.0.0.5", "--ulimit=nofile=4096"}, "--add-host db:10.0.0.5 --ulimit=nofile=4096", ""},
+		// The entrypoint, the user and the environment are ai-shell's
+		{[]string{"--entrypoint", "/bin/zsh", "--init"}, "--init", "--entrypoint /bin/zsh"},
+		{[]string{"--entrypoint=[\"/bin/zsh\"]"}, "", "--entrypoint=[\"/bin/zsh\"]"},
+		{[]string{"--user", "root", "--init"}, "--init", "--user root"},
+		{[]string{"-u=0"}, "", "-u=0"},
+		{[]string{"-e", "AI_SHELL_USER=root", "--init"}, "--init", "-e AI_SHELL_USER=root"},
+		{[]string{"--env=BASH_ENV=/tmp/x"}, "", "--env=BASH_ENV=/tmp/x"},
+		{[]string{"--env-file", "/tmp/env"}, "", "--env-file /tmp/env"},
+		// Mounts that skip the protected paths and conflict checks
+		{[]string{"--volumes-from", "other", "--init"}, "--init", "--volumes-from other"},
+		{[]string{"--tmpfs", "/run/ai-shell/approval"}, "", "--tmpfs /run/ai-shell/approval"},
+		{[]string{"--tmpfs=/run/ai-shell"}, "", "--tmpfs=/run/ai-shell"},
+		// A pod shares its network, past the egress filter
+		{[]string{"--pod", "mypod", "--init"}, "--init", "--pod mypod"},
+		{[]string{"--pod=new:x"}, "", "--pod=new:x"},
+		// Unknown flags go, without the next flag
+		{[]string{"--gpus", "all", "--init"}, "--init", "--gpus all"},
+		{[]string{"--some-switch", "--init"}, "--init", "--some-switch"},
+	}
+
+	for _, tt := range tests {
+		kept, removed := splitProjectPodmanArgs(tt.args)
+		if strings.Join(kept, " ") != tt.kept || strings.Join(removed, " ") != tt.removed {
+			t.Errorf("Input: %v, Expected: %q and %q, Got: %q and %q", tt.args, tt.kept, tt.removed, kept, removed)
+		}
+	}
+}
+
+func TestSplitPodmanArgs(t *testing.T) {
+	kept, removed := splitPodmanArgs([]string{"--init", "--memory", "2g", "--cpus=2", "--tmpfs", "/scratch", "--shm-size=8g"}, resourceFlags)
+	if strings.Join(kept, " ") != "--init" || strings.Join(removed, " ") != "--memory 2g --cpus=2 --tmpfs /scratch --shm-size=8g" {
+		t.Errorf("Expected the resource flags to be split off, Got: %q and %q", kept, removed)
+	}
+}
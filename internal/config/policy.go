@@ -0,0 +1,100 @@
+package config
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/spf13/viper"
+)
+
+// PolicyPath is the organization policy on the host. Unlike the global
+// config, it is meant to be managed by an administrator, not the user.
+var PolicyPath = "/etc/ai-shell/policy.yaml"
+
+// Policy bounds what user and project configs can ask for.
+type Policy struct {
+	// Resources are maxima, and the defaults for the limits a config leaves out
+	Resources Resources `mapstructure:"resources" yaml:"resources"`
+}
+
+// LoadPolicy reads the policy file; it returns nil if there is none.
+func LoadPolicy() (*Policy, error) {
+	if _, err := os.Stat(PolicyPath); os.IsNotExist(err) {
+		return nil, nil
+	} else if err != nil {
+		return nil, fmt.Errorf("failed to read policy %s: %w", PolicyPath, err)
+	}
+	v := viper.New()
+	v.SetConfigFile(PolicyPath)
+	if err := v.ReadInConfig(); err != nil {
+		return nil, fmt.Errorf("failed to read policy %s: %w", PolicyPath, err)
+	}
+	var p Policy
+	if err := v.Unmarshal(&p); err != nil {
+		return nil, fmt.Errorf("failed to parse policy %s: %w", PolicyPath, err)
+	}
+	if err := p.Resources.Validate(); err != nil {
+		return nil, fmt.Errorf("invalid policy %s: %w", PolicyPath, err)
+	}
+	return &p, nil
+}
+
+// Enforce fills in the limits c leaves out and rejects the ones above the
+// policy, as well as podman arguments that would replace them.
+func (p *Policy) Enforce(c *Config) error {
+	if p == nil {
+		return nil
+	}
+	if p.Resources != (Resources{}) {
+		if _, flags := splitPodmanArgs(c.PodmanArgs, resourceFlags); len(flags) > 0 {
+			return fmt.Errorf("podman arguments %s would bypass the resource limits of the policy %s, use resources instead", strings.Join(flags, " "), PolicyPath)
+		}
+	}
+	limit, r := p.Resources, &c.Resources
+	exceeds := func(key, value, max string) error {
+		return fmt.Errorf("resources.%s %s exceeds the limit %s of the policy %s", key, value, max, PolicyPath)
+	}
+
+	if limit.CPUs > 0 {
+		if r.CPUs > limit.CPUs {
+			return exceeds("cpus", fmt.Sprint(r.CPUs), fmt.Sprint(limit.CPUs))
+		}
+		if r.CPUs == 0 {
+			r.CPUs = limit.CPUs
+		}
+	}
+	if limit.PidsLimit > 0 {
+		if r.PidsLimit > limit.PidsLimit {
+			return exceeds("pids_limit", fmt.Sprint(r.PidsLimit), fmt.Sprint(limit.PidsLimit))
+		}
+		if r.PidsLimit == 0 {
+			r.PidsLimit = limit.PidsLimit
+		}
+	}
+
+	sizes := []struct {
+		key        string
+		value, max *string
+	}{
+		{"memory", &r.Memory, &limit.Memory},
+		{"memory_swap", &r.MemorySwap, &limit.MemorySwap},
+		{"tmpfs_size", &r.TmpfsSize, &limit.TmpfsSize},
+		{"home_size", &r.HomeSize, &limit.HomeSize},
+	}
+	for _, s := range sizes {
+		if *s.max == "" || *s.max == "-1" {
+			continue
+		}
+		if *s.value == "" {
+			*s.value = *s.max
+			continue
+		}
+		max, _ := ParseSize(*s.max)
+		value, err := ParseSize(*s.value)
+		if *s.value == "-1" || err != nil || value > max {
+			return exceeds(s.key, *s.value, *s.max)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,102 @@
+package config
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// Port publishes a container port on the host's loopback interface, e.g. for
+// a dev server started by an agent.
+type Port struct {
+	Container int `mapstructure:"container" yaml:"container" json:"container"`
+	// Host defaults to Container; a free port is picked if it is in use
+	Host  int    `mapstructure:"host" yaml:"host,omitempty" json:"host,omitempty"`
+	Label string `mapstructure:"label" yaml:"label,omitempty" json:"label,omitempty"`
+}
+
+// Validate checks the port numbers.
+func (p Port) Validate() error {
+	if p.Container < 1 || p.Container > 65535 {
+		return fmt.Errorf("invalid container port %d", p.Container)
+	}
+	if p.Host != 0 && (p.Host < 1 || p.Host > 65535) {
+		return fmt.Errorf("invalid host port %d", p.Host)
+	}
+	return nil
+}
+
+// HostPort is the port requested on the host.
+func (p Port) HostPort() int {
+	if p.Host != 0 {
+		return p.Host
+	}
+	return p.Container
+}
+
+// String describes the port for the user, with its label if it has one.
+func (p Port) String() string {
+	if p.Label != "" {
+		return fmt.Sprintf("%d (%s)", p.Container, p.Label)
+	}
+	return strconv.Itoa(p.Container)
+}
+
+// dedupePorts keeps the last entry of every container port, at the position
+// of the first one.
+func dedupePorts(ports []Port) []Port {
+	index := make(map[int]int, len(ports))
+	var deduped []Port
+	for _, p := range ports {
+		if i, ok := index[p.Container]; ok {
+			deduped[i] = p
+			continue
+		}
+		index[p.Container] = len(deduped)
+		deduped = append(deduped, p)
+	}
+	return deduped
+}
+
+// parseForwardPort reads a devcontainer forwardPorts entry: a port number, or
+// "host:port" where only the container itself ("localhost") is supported.
+func parseForwardPort(v any) (Port, error) {
+	switch t := v.(type) {
+	case float64:
+		return Port{Container: int(t)}, nil
+	case string:
+		host, port, ok := strings.Cut(t, ":")
+		if !ok {
+			host, port = "localhost", t
+		}
+		if host != "localhost" && host != "127.0.0.1" {
+			return Port{}, fmt.Errorf("forwarding from %s is not supported", host)
+		}
+		n, err := strconv.Atoi(port)
+		if err != nil {
+			return Port{}, fmt.Errorf("invalid port %q", port)
+		}
+		return Port{Container: n}, nil
+	}
+	return Port{}, fmt.Errorf("expected a number or a string")
+}
+
+// portLabel finds the label of a port in devcontainer portsAttributes, whose
+// keys are ports or port ranges; an exact match wins over a range.
+func portLabel(attrs map[string]PortAttributes, port int) string {
+	if a, ok := attrs[strconv.Itoa(port)]; ok {
+		return a.Label
+	}
+	for _, k := range sortedKeys(attrs) {
+		from, to, ok := strings.Cut(k, "-")
+		if !ok {
+			continue
+		}
+		lo, err1 := strconv.Atoi(strings.TrimSpace(from))
+		hi, err2 := strconv.Atoi(strings.TrimSpace(to))
+		if err1 == nil && err2 == nil && lo <= port && port <= hi {
+			return attrs[k].Label
+		}
+	}
+	return ""
+}
@@ -0,0 +1,127 @@
+package config
+
+import (
+	"fmt"
+	"net"
+	"net/url"
+	"slices"
+	"strings"
+)
+
+// defaultNoProxy is always excluded from the proxy.
+var defaultNoProxy = []string{"localhost", "127.0.0.1", "::1"}
+
+// Proxy is an HTTP proxy the container has to go through, e.g. on a
+// corporate network.
+type Proxy struct {
+	HTTP    string   `mapstructure:"http" yaml:"http,omitempty" json:"http,omitempty"`
+	HTTPS   string   `mapstructure:"https" yaml:"https,omitempty" json:"https,omitempty"`
+	NoProxy []string `mapstructure:"no_proxy" yaml:"no_proxy,omitempty" json:"no_proxy,omitempty"`
+	// FromHost takes the values that are not set from the host's
+	// HTTP_PROXY, HTTPS_PROXY and NO_PROXY.
+	FromHost bool `mapstructure:"from_host" yaml:"from_host,omitempty" json:"from_host,omitempty"`
+}
+
+// Validate checks the proxy URLs.
+func (p Proxy) Validate() error {
+	for _, u := range []string{p.HTTP, p.HTTPS} {
+		if u == "" {
+			continue
+		}
+		if _, err := ParseProxyURL(u); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Resolve fills in the values taken from the host; lookup is os.Getenv.
+func (p Proxy) Resolve(lookup func(string) string) Proxy {
+	if !p.FromHost {
+		return p
+	}
+	env := func(name string) string {
+		if v := lookup(name); v != "" {
+			return v
+		}
+		return lookup(strings.ToLower(name))
+	}
+	if p.HTTP == "" {
+		p.HTTP = env("HTTP_PROXY")
+	}
+	if p.HTTPS == "" {
+		p.HTTPS = env("HTTPS_PROXY")
+	}
+	if len(p.NoProxy) == 0 {
+		for _, h := range strings.Split(env("NO_PROXY"), ",") {
+			if h = strings.TrimSpace(h); h != "" {
+				p.NoProxy = append(p.NoProxy, h)
+			}
+		}
+	}
+	p.FromHost = false
+	return p
+}
+
+// Enabled reports whether a proxy is set; call it on a resolved proxy.
+func (p Proxy) Enabled() bool {
+	return p.HTTP != "" || p.HTTPS != ""
+}
+
+// Upstream is the proxy that HTTPS traffic goes to, the HTTP one if there is
+// no HTTPS proxy.
+func (p Proxy) Upstream() string {
+	if p.HTTPS != "" {
+		return p.HTTPS
+	}
+	return p.HTTP
+}
+
+// Env returns the proxy variables of a resolved proxy, in both cases since
+// tools disagree about which one they read (git and curl only read
+// lowercase http_proxy, npm, pip and the agents read either).
+func (p Proxy) Env() []string {
+	if !p.Enabled() {
+		return nil
+	}
+	var env []string
+	add := func(name, value string) {
+		if value != "" {
+			env = append(env, name+"="+value, strings.ToLower(name)+"="+value)
+		}
+	}
+	add("HTTP_PROXY", p.HTTP)
+	add("HTTPS_PROXY", p.HTTPS)
+	noProxy := append([]string{}, defaultNoProxy...)
+	for _, h := range p.NoProxy {
+		if !slices.Contains(noProxy, h) {
+			noProxy = append(noProxy, h)
+		}
+	}
+	add("NO_PROXY", strings.Join(noProxy, ","))
+	return env
+}
+
+// ParseProxyURL parses a proxy URL, which needs an http or https scheme and
+// a host.
+func ParseProxyURL(s string) (*url.URL, error) {
+	u, err := url.Parse(s)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Hostname() == "" {
+		return nil, fmt.Errorf("proxy: invalid URL %q, expected http(s)://host[:port]", s)
+	}
+	return u, nil
+}
+
+// validateExtraHost checks a "name:ip" entry of ExtraHosts, like podman's
+// --add-host. The IP may be an IPv6 address or host-gateway.
+func validateExtraHost(s string) error {
+	name, ip, ok := strings.Cut(s, ":")
+	if !ok || name == "" {
+		return fmt.Errorf("extra_hosts: invalid entry %q, expected name:ip", s)
+	}
+	ip = strings.TrimSuffix(strings.TrimPrefix(ip, "["), "]")
+	if ip != "host-gateway" && net.ParseIP(ip) == nil {
+		return fmt.Errorf("extra_hosts: invalid IP in %q", s)
+	}
+	return nil
+}
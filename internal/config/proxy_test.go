@@ -0,0 +1,65 @@
+package config
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestProxyResolve(t *testing.T) {
+	host := map[string]string{
+		"HTTPS_PROXY": "http://proxy.corp:8080",
+		"http_proxy":  "http://proxy.corp:3128",
+		"NO_PROXY":    "corp.example, .internal",
+	}
+	lookup := func(k string) string { return host[k] }
+
+	p := Proxy{HTTPS: "http://other:8080", FromHost: true}.Resolve(lookup)
+	if p.HTTPS != "http://other:8080" {
+		t.Errorf("Expected the configured value to win, Got: %s", p.HTTPS)
+	}
+	if p.HTTP != "http://proxy.corp:3128" {
+		t.Errorf("Expected the lowercase host variable, Got: %s", p.HTTP)
+	}
+	if !slices.Equal(p.NoProxy, []string{"corp.example", ".internal"}) {
+		t.Errorf("Unexpected no_proxy: %v", p.NoProxy)
+	}
+
+	if p := (Proxy{}).Resolve(lookup); p.Enabled() {
+		t.Errorf("Expected no proxy without from_host, Got: %+v", p)
+	}
+}
+
+func TestProxyEnv(t *testing.T) {
+	env := Proxy{HTTPS: "http://proxy.corp:8080", NoProxy: []string{"localhost", ".corp"}}.Env()
+	expected := []string{
+		"HTTPS_PROXY=http://proxy.corp:8080", "https_proxy=http://proxy.corp:8080",
+		"NO_PROXY=localhost,127.0.0.1,::1,.corp", "no_proxy=localhost,127.0.0.1,::1,.corp",
+	}
+	if !slices.Equal(env, expected) {
+		t.Errorf("Expected: %v, Got: %v", expected, env)
+	}
+	if env := (Proxy{NoProxy: []string{".corp"}}).Env(); env != nil {
+		t.Errorf("Expected no variables without a proxy, Got: %v", env)
+	}
+}
+
+func TestValidateNameResolution(t *testing.T) {
+	tests := []struct {
+		cfg   Config
+		valid bool
+	}{
+		{Config{DNS: []string{"10.0.0.53", "fd00::53"}}, true},
+		{Config{DNS: []string{"dns.corp"}}, false},
+		{Config{ExtraHosts: []string{"git.corp:10.0.0.5", "v6.corp:fd00::5", "host:host-gateway"}}, true},
+		{Config{ExtraHosts: []string{"git.corp"}}, false},
+		{Config{ExtraHosts: []string{"git.corp:nowhere"}}, false},
+		{Config{Proxy: Proxy{HTTPS: "http://proxy.corp:8080"}}, true},
+		{Config{Proxy: Proxy{HTTP: "proxy.corp:8080"}}, false},
+	}
+	for _, tt := range tests {
+		err := tt.cfg.Validate()
+		if (err == nil) != tt.valid {
+			t.Errorf("Input: %+v, Expected valid: %v, Got: %v", tt.cfg, tt.valid, err)
+		}
+	}
+}
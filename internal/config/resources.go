@@ -0,0 +1,115 @@
+package config
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// Resources limits what the container can use, so that a runaway agent
+// cannot take the host down.
+type Resources struct {
+	CPUs       float64 `mapstructure:"cpus" yaml:"cpus,omitempty" json:"cpus,omitempty"`
+	Memory     string  `mapstructure:"memory" yaml:"memory,omitempty" json:"memory,omitempty"`
+	MemorySwap string  `mapstructure:"memory_swap" yaml:"memory_swap,omitempty" json:"memory_swap,omitempty"`
+	PidsLimit  int     `mapstructure:"pids_limit" yaml:"pids_limit,omitempty" json:"pids_limit,omitempty"`
+	// TmpfsSize mounts /tmp as a tmpfs of this size
+	TmpfsSize string `mapstructure:"tmpfs_size" yaml:"tmpfs_size,omitempty" json:"tmpfs_size,omitempty"`
+	// HomeSize is the quota of the home volume, applied when it is created
+	HomeSize string `mapstructure:"home_size" yaml:"home_size,omitempty" json:"home_size,omitempty"`
+}
+
+// paranoidPidsLimit stops fork bombs in paranoid mode.
+const paranoidPidsLimit = 1024
+
+// PodmanArgs returns the podman arguments of the resource limits. The
+// read-only root of paranoid mode needs a writable /tmp, and gets a process
+// limit unless one is configured.
+func (r Resources) PodmanArgs(paranoid bool) []string {
+	var args []string
+	if r.CPUs > 0 {
+		args = append(args, "--cpus", strconv.FormatFloat(r.CPUs, 'f', -1, 64))
+	}
+	if r.Memory != "" {
+		args = append(args, "--memory", r.Memory)
+	}
+	if r.MemorySwap != "" {
+		args = append(args, "--memory-swap", r.MemorySwap)
+	}
+	pids := r.PidsLimit
+	if pids == 0 && paranoid {
+		pids = paranoidPidsLimit
+	}
+	if pids > 0 {
+		args = append(args, "--pids-limit", fmt.Sprint(pids))
+	}
+	if r.TmpfsSize != "" || paranoid {
+		opts := "rw,nosuid,nodev"
+		if r.TmpfsSize != "" {
+			opts += ",size=" + r.TmpfsSize
+		}
+		args = append(args, "--tmpfs", "/tmp:"+opts)
+	}
+	return args
+}
+
+// Validate checks the limits.
+func (r Resources) Validate() error {
+	if r.CPUs < 0 {
+		return fmt.Errorf("resources.cpus: must be positive")
+	}
+	if r.PidsLimit < 0 {
+		return fmt.Errorf("resources.pids_limit: must be positive")
+	}
+	sizes := map[string]string{"memory": r.Memory, "tmpfs_size": r.TmpfsSize, "home_size": r.HomeSize}
+	if r.MemorySwap != "-1" {
+		sizes["memory_swap"] = r.MemorySwap
+	}
+	for _, k := range sortedKeys(sizes) {
+		if sizes[k] == "" {
+			continue
+		}
+		if _, err := ParseSize(sizes[k]); err != nil {
+			return fmt.Errorf("resources.%s: %w", k, err)
+		}
+	}
+	if r.MemorySwap != "" && r.MemorySwap != "-1" {
+		if r.Memory == "" {
+			return fmt.Errorf("resources.memory_swap needs resources.memory")
+		}
+		memory, _ := ParseSize(r.Memory)
+		swap, _ := ParseSize(r.MemorySwap)
+		if swap < memory {
+			return fmt.Errorf("resources.memory_swap is memory plus swap, it cannot be less than memory")
+		}
+	}
+	return nil
+}
+
+// ParseSize parses a size like podman does: a number with an optional
+// b, k, m, g or t suffix (case-insensitive, "kb" and "kib" work too).
+func ParseSize(s string) (int64, error) {
+	num := strings.ToLower(strings.TrimSpace(s))
+	num = strings.TrimSuffix(strings.TrimSuffix(num, "ib"), "b")
+	mult := int64(1)
+	if n := len(num); n > 0 {
+		switch num[n-1] {
+		case 'k':
+			mult = 1 << 10
+		case 'm':
+			mult = 1 << 20
+		case 'g':
+			mult = 1 << 30
+		case 't':
+			mult = 1 << 40
+		}
+		if mult > 1 {
+			num = num[:n-1]
+		}
+	}
+	v, err := strconv.ParseFloat(num, 64)
+	if err != nil || v <= 0 {
+		return 0, fmt.Errorf("invalid size %q, expected e.g. 512m or 8g", s)
+	}
+	return int64(v * float64(mult)), nil
+}
@@ -0,0 +1,161 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestParseSize(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected int64
+	}{
+		{"512", 512},
+		{"512m", 512 << 20},
+		{"2g", 2 << 30},
+		{"2G", 2 << 30},
+		{"1.5gb", 3 << 29},
+		{"64KiB", 64 << 10},
+	}
+	for _, tt := range tests {
+		got, err := ParseSize(tt.input)
+		if err != nil || got != tt.expected {
+			t.Errorf("Input: %q, Expected: %d, Got: %d (%v)", tt.input, tt.expected, got, err)
+		}
+	}
+	for _, input := range []string{"", "g", "-1", "0", "2x"} {
+		if _, err := ParseSize(input); err == nil {
+			t.Errorf("Input: %q, Expected: error", input)
+		}
+	}
+}
+
+func TestResourcesPodmanArgs(t *testing.T) {
+	tests := []struct {
+		resources Resources
+		paranoid  bool
+		expected  string
+	}{
+		{Resources{}, false, ""},
+		{Resources{CPUs: 1.5, Memory: "4g", MemorySwap: "-1"}, false, "--cpus 1.5 --memory 4g --memory-swap -1"},
+		{Resources{PidsLimit: 256, TmpfsSize: "512m"}, false, "--pids-limit 256 --tmpfs /tmp:rw,nosuid,nodev,size=512m"},
+		// The read-only root needs /tmp and a process limit
+		{Resources{}, true, "--pids-limit 1024 --tmpfs /tmp:rw,nosuid,nodev"},
+		{Resources{PidsLimit: 4096, TmpfsSize: "1g"}, true, "--pids-limit 4096 --tmpfs /tmp:rw,nosuid,nodev,size=1g"},
+	}
+	for _, tt := range tests {
+		got := strings.Join(tt.resources.PodmanArgs(tt.paranoid), " ")
+		if got != tt.expected {
+			t.Errorf("Input: %+v (paranoid %v), Expected: %q, Got: %q", tt.resources, tt.paranoid, tt.expected, got)
+		}
+	}
+}
+
+func TestResourcesValidate(t *testing.T) {
+	valid := []Resources{
+		{},
+		{CPUs: 2, Memory: "4g", MemorySwap: "6g", PidsLimit: 512, TmpfsSize: "1g", HomeSize: "20g"},
+		{Memory: "4g", MemorySwap: "-1"},
+	}
+	for _, r := range valid {
+		if err := r.Validate(); err != nil {
+			t.Errorf("Input: %+v, Expected: valid, Got: %v", r, err)
+		}
+	}
+	invalid := []Resources{
+		{CPUs: -1},
+		{PidsLimit: -5},
+		{Memory: "lots"},
+		{MemorySwap: "8g"},
+		{Memory: "4g", MemorySwap: "2g"},
+	}
+	for _, r := range invalid {
+		if err := r.Validate(); err == nil {
+			t.Errorf("Input: %+v, Expected: error", r)
+		}
+	}
+}
+
+func TestPolicyEnforce(t *testing.T) {
+	p := &Policy{Resources: Resources{CPUs: 4, Memory: "8g", PidsLimit: 2048}}
+
+	// Left out limits take the policy's
+	c := &Config{Resources: Resources{Memory: "2g"}}
+	if err := p.Enforce(c); err != nil {
+		t.Fatalf("Enforce failed: %v", err)
+	}
+	expected := Resources{CPUs: 4, Memory: "2g", PidsLimit: 2048}
+	if c.Resources != expected {
+		t.Errorf("Expected: %+v, Got: %+v", expected, c.Resources)
+	}
+
+	for _, r := range []Resources{{CPUs: 8}, {Memory: "16g"}, {PidsLimit: 4096}} {
+		if err := p.Enforce(&Config{Resources: r}); err == nil {
+			t.Errorf("Input: %+v, Expected: an error above the policy", r)
+		}
+	}
+
+	// podman applies the last value, podman_args must not replace the limits
+	for _, args := range [][]string{{"--memory=0"}, {"--pids-limit", "-1"}, {"-m", "64g"}} {
+		if err := p.Enforce(&Config{PodmanArgs: args}); err == nil {
+			t.Errorf("Input: %v, Expected: an error for podman arguments that replace the limits", args)
+		}
+	}
+	if err := p.Enforce(&Config{PodmanArgs: []string{"--init"}}); err != nil {
+		t.Errorf("Expected other podman arguments to pass, Got: %v", err)
+	}
+
+	var none *Policy
+	if err := none.Enforce(&Config{Resources: Resources{CPUs: 64}}); err != nil {
+		t.Errorf("Expected no limits without a policy, Got: %v", err)
+	}
+}
+
+func TestLoadPolicy(t *testing.T) {
+	tmpDir, err := os.MkdirTemp("", "ai-shell-policy-test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() { _ = os.RemoveAll(tmpDir) }()
+
+	orig := PolicyPath
+	defer func() { PolicyPath = orig }()
+	PolicyPath = filepath.Join(tmpDir, "policy.yaml")
+
+	if p, err := LoadPolicy(); p != nil || err != nil {
+		t.Errorf("Expected no policy, Got: %+v, %v", p, err)
+	}
+
+	if err := os.WriteFile(PolicyPath, []byte("resources:\n  memory: 8g\n  pids_limit: 1024\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	p, err := LoadPolicy()
+	if err != nil {
+		t.Fatalf("LoadPolicy failed: %v", err)
+	}
+	if p.Resources.Memory != "8g" || p.Resources.PidsLimit != 1024 {
+		t.Errorf("Unexpected policy: %+v", p.Resources)
+	}
+
+	// A policy that cannot be read is not the same as no policy
+	if os.Getuid() != 0 {
+		if err := os.Chmod(tmpDir, 0); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := LoadPolicy(); err == nil {
+			t.Error("Expected an error for an unreadable policy")
+		}
+		if err := os.Chmod(tmpDir, 0700); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if err := os.WriteFile(PolicyPath, []byte("resources:\n  memory: lots\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := LoadPolicy(); err == nil {
+		t.Error("Expected an error for an invalid policy")
+	}
+}
@@ -0,0 +1,51 @@
+package config
+
+import (
+	"fmt"
+	"path"
+	"regexp"
+	"strings"
+)
+
+// Sandbox runs the commands of the listed agents in a bubblewrap sandbox
+// inside the container, without the credentials the container holds.
+type Sandbox struct {
+	// Agents are the commands (e.g. claude) whose shell is sandboxed
+	Agents []string `mapstructure:"agents" yaml:"agents,omitempty" json:"agents,omitempty"`
+	// Hide are more paths to hide, relative to the home directory
+	Hide []string `mapstructure:"hide" yaml:"hide,omitempty" json:"hide,omitempty"`
+	// Env are more environment variables to strip
+	Env []string `mapstructure:"env" yaml:"env,omitempty" json:"env,omitempty"`
+}
+
+var (
+	// commandPattern keeps command names safe for the shell functions and
+	// shims that wrap them
+	commandPattern = regexp.MustCompile(`^[A-Za-z0-9][A-Za-z0-9._-]*$`)
+	envVarPattern  = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+)
+
+// Enabled reports whether any agent is sandboxed.
+func (s Sandbox) Enabled() bool {
+	return len(s.Agents) > 0
+}
+
+// Validate checks the agent names, paths and variable names.
+func (s Sandbox) Validate() error {
+	for _, a := range s.Agents {
+		if !commandPattern.MatchString(a) {
+			return fmt.Errorf("sandbox.agents: invalid command name %q", a)
+		}
+	}
+	for _, h := range s.Hide {
+		if h == "" || path.IsAbs(h) || path.Clean(h) == "." || strings.HasPrefix(path.Clean(h), "..") {
+			return fmt.Errorf("sandbox.hide: %q must be a path in the home directory, relative to it", h)
+		}
+	}
+	for _, e := range s.Env {
+		if !envVarPattern.MatchString(e) {
+			return fmt.Errorf("sandbox.env: invalid variable name %q", e)
+		}
+	}
+	return nil
+}
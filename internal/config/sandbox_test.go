@@ -0,0 +1,36 @@
+package config
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestSandboxValidate(t *testing.T) {
+	valid := Sandbox{Agents: []string{"claude", "gemini", "cursor-agent"}, Hide: []string{".npmrc", ".config/hub"}, Env: []string{"NPM_TOKEN"}}
+	if err := valid.Validate(); err != nil {
+		t.Errorf("Expected valid, Got: %v", err)
+	}
+	invalid := []Sandbox{
+		{Agents: []string{"claude; rm -rf ~"}},
+		{Agents: []string{"-claude"}},
+		{Hide: []string{"/etc/shadow"}},
+		{Hide: []string{"../other"}},
+		{Hide: []string{"."}},
+		{Env: []string{"MY-TOKEN"}},
+	}
+	for _, s := range invalid {
+		if err := s.Validate(); err == nil {
+			t.Errorf("Input: %+v, Expected: error", s)
+		}
+	}
+}
+
+func TestMergeConfigSandbox(t *testing.T) {
+	base := &Config{Sandbox: Sandbox{Agents: []string{"claude"}, Hide: []string{".npmrc"}}}
+	mergeConfig(base, &Config{Sandbox: Sandbox{Agents: []string{"claude", "gemini"}, Env: []string{"NPM_TOKEN"}}})
+
+	expected := Sandbox{Agents: []string{"claude", "gemini"}, Hide: []string{".npmrc"}, Env: []string{"NPM_TOKEN"}}
+	if !slices.Equal(base.Sandbox.Agents, expected.Agents) || !slices.Equal(base.Sandbox.Hide, expected.Hide) || !slices.Equal(base.Sandbox.Env, expected.Env) {
+		t.Errorf("Expected: %+v, Got: %+v", expected, base.Sandbox)
+	}
+}
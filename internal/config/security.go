@@ -0,0 +1,106 @@
+package config
+
+import (
+	"fmt"
+	"regexp"
+	"slices"
+	"strings"
+)
+
+// Security levels, from least to most restrictive
+const (
+	// SecurityStandard is podman's default, with passwordless sudo in the container
+	SecurityStandard = "standard"
+	// SecurityStrict drops all capabilities the entrypoint does not need, sets
+	// no-new-privileges and removes sudo once the entrypoint is done
+	SecurityStrict = "strict"
+	// SecurityParanoid adds a read-only root filesystem and a pids limit
+	SecurityParanoid = "paranoid"
+)
+
+var securityLevels = []string{SecurityStandard, SecurityStrict, SecurityParanoid}
+
+// entrypointCaps are what configure.sh needs for its root phase: adapting
+// the user and the home volume, updating the trust store and dropping to
+// the user. The user keeps none of them.
+var entrypointCaps = []string{"CHOWN", "DAC_OVERRIDE", "FOWNER", "FSETID", "SETGID", "SETUID"}
+
+// Security hardens the container.
+type Security struct {
+	Level string `mapstructure:"level" yaml:"level,omitempty" json:"level,omitempty"`
+	// LabelDisable turns off SELinux separation (label=disable), which is
+	// otherwise used on SELinux hosts. It is only honored from the global config.
+	LabelDisable bool `mapstructure:"label_disable" yaml:"label_disable,omitempty" json:"label_disable,omitempty"`
+
+	Seccomp Seccomp `mapstructure:"seccomp" yaml:"seccomp,omitempty" json:"seccomp"`
+}
+
+// Seccomp tunes ai-shell's seccomp profile, which blocks syscalls agents
+// never need (ptrace, mount, keyctl, bpf, ...) on top of podman's default.
+// Only Block is honored from a project config; the rest weakens the profile.
+type Seccomp struct {
+	// Block adds syscalls to the blocked ones, Allow removes them
+	Block []string `mapstructure:"block" yaml:"block,omitempty" json:"block,omitempty"`
+	Allow []string `mapstructure:"allow" yaml:"allow,omitempty" json:"allow,omitempty"`
+	// Audit logs the blocked syscalls to the kernel audit log instead of
+	// denying them, to tune the profile
+	Audit bool `mapstructure:"audit" yaml:"audit,omitempty" json:"audit,omitempty"`
+	// Disable keeps podman's default profile
+	Disable bool `mapstructure:"disable" yaml:"disable,omitempty" json:"disable,omitempty"`
+}
+
+var syscallPattern = regexp.MustCompile(`^[a-z0-9_]+$`)
+
+// Weakened reports whether the settings relax ai-shell's profile.
+func (s Seccomp) Weakened() bool {
+	return len(s.Allow) > 0 || s.Audit || s.Disable
+}
+
+// Validate checks the syscall names.
+func (s Seccomp) Validate() error {
+	for _, name := range append(append([]string{}, s.Block...), s.Allow...) {
+		if !syscallPattern.MatchString(name) {
+			return fmt.Errorf("security.seccomp: invalid syscall name %q", name)
+		}
+	}
+	return nil
+}
+
+// Validate checks the security level and the seccomp settings.
+func (s Security) Validate() error {
+	if s.Level != "" && !slices.Contains(securityLevels, s.Level) {
+		return fmt.Errorf("security.level: unknown level %q, expected one of %v", s.Level, securityLevels)
+	}
+	return s.Seccomp.Validate()
+}
+
+// EffectiveLevel is the configured level, standard if none is set.
+func (s Security) EffectiveLevel() string {
+	if s.Level == "" {
+		return SecurityStandard
+	}
+	return s.Level
+}
+
+// PodmanArgs returns the podman arguments of the security level.
+func (s Security) PodmanArgs() []string {
+	if !s.AtLeast(SecurityStrict) {
+		return nil
+	}
+	args := []string{
+		"--cap-drop", "all",
+		"--cap-add", strings.Join(entrypointCaps, ","),
+		"--security-opt", "no-new-privileges",
+		"-e", "AI_SHELL_SECURITY=" + s.EffectiveLevel(),
+	}
+	if s.AtLeast(SecurityParanoid) {
+		// Resources.PodmanArgs mounts /tmp and limits processes
+		args = append(args, "--read-only")
+	}
+	return args
+}
+
+// AtLeast reports whether the effective level is level or more restrictive.
+func (s Security) AtLeast(level string) bool {
+	return slices.Index(securityLevels, s.EffectiveLevel()) >= slices.Index(securityLevels, level)
+}
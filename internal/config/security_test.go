@@ -0,0 +1,75 @@
+package config
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestSecurityValidate(t *testing.T) {
+	for _, level := range []string{"", SecurityStandard, SecurityStrict, SecurityParanoid} {
+		if err := (Security{Level: level}).Validate(); err != nil {
+			t.Errorf("Input: %q, Expected: valid, Got: %v", level, err)
+		}
+	}
+	if err := (Security{Level: "high"}).Validate(); err == nil {
+		t.Error("Expected an error for an unknown level")
+	}
+	if err := (Security{Seccomp: Seccomp{Block: []string{"io_uring_setup"}}}).Validate(); err != nil {
+		t.Errorf("Expected a valid syscall name, Got: %v", err)
+	}
+	if err := (Security{Seccomp: Seccomp{Allow: []string{"ptrace; rm"}}}).Validate(); err == nil {
+		t.Error("Expected an error for an invalid syscall name")
+	}
+}
+
+func TestSecurityPodmanArgs(t *testing.T) {
+	tests := []struct {
+		level     string
+		expected  []string
+		forbidden []string
+	}{
+		{"", nil, []string{"--cap-drop", "no-new-privileges", "--read-only"}},
+		{SecurityStandard, nil, []string{"--cap-drop"}},
+		{SecurityStrict, []string{"--cap-drop all", "--cap-add CHOWN,", "SETUID", "no-new-privileges", "AI_SHELL_SECURITY=strict"}, []string{"--read-only", "--pids-limit"}},
+		{SecurityParanoid, []string{"--cap-drop all", "no-new-privileges", "--read-only", "AI_SHELL_SECURITY=paranoid"}, nil},
+	}
+	for _, tt := range tests {
+		args := strings.Join(Security{Level: tt.level}.PodmanArgs(), " ")
+		for _, e := range tt.expected {
+			if !strings.Contains(args, e) {
+				t.Errorf("Input: %q, Expected: %q, Got: %s", tt.level, e, args)
+			}
+		}
+		for _, f := range tt.forbidden {
+			if strings.Contains(args, f) {
+				t.Errorf("Input: %q, Unexpected: %q, Got: %s", tt.level, f, args)
+			}
+		}
+	}
+}
+
+func TestMergeConfigSecurity(t *testing.T) {
+	tests := []struct {
+		global, project, expected string
+	}{
+		{"", SecurityStrict, SecurityStrict},
+		{SecurityStrict, SecurityParanoid, SecurityParanoid},
+		// A project cannot lower the level
+		{SecurityParanoid, SecurityStandard, SecurityParanoid},
+		{SecurityStrict, "", SecurityStrict},
+	}
+	for _, tt := range tests {
+		base := &Config{Security: Security{Level: tt.global}}
+		mergeConfig(base, &Config{Security: Security{Level: tt.project}})
+		if got := base.Security.EffectiveLevel(); got != tt.expected {
+			t.Errorf("Input: %q over %q, Expected: %s, Got: %s", tt.project, tt.global, tt.expected, got)
+		}
+	}
+
+	// A project can block more syscalls, but not allow or audit them
+	base := &Config{Security: Security{Seccomp: Seccomp{Block: []string{"io_uring_setup"}}}}
+	mergeConfig(base, &Config{Security: Security{Seccomp: Seccomp{Block: []string{"kcmp"}, Allow: []string{"ptrace"}, Audit: true}}})
+	if s := base.Security.Seccomp; len(s.Block) != 2 || s.Weakened() {
+		t.Errorf("Unexpected seccomp settings: %+v", s)
+	}
+}
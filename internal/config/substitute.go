@@ -0,0 +1,158 @@
+package config
+
+import (
+	"crypto/sha256"
+	"encoding/base32"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+var variablePattern = regexp.MustCompile(`\$\{([^}]+)\}`)
+
+// substitution resolves the devcontainer.json variable grammar, e.g.
+// ${localEnv:VAR:default} or ${localWorkspaceFolder}.
+type substitution struct {
+	localWorkspaceFolder     string
+	containerWorkspaceFolder string
+	devcontainerID           string
+	// containerEnv holds the already resolved containerEnv of the config
+	containerEnv map[string]string
+	lookupEnv    func(string) (string, bool)
+	// deferContainerEnv leaves the other ${containerEnv:VAR} references to
+	// the container, as ${VAR}; deferred records that one was left
+	deferContainerEnv bool
+	deferred          bool
+}
+
+func newSubstitution(configPath string) *substitution {
+	workspace := workspaceFolderFor(configPath)
+	return &substitution{
+		localWorkspaceFolder: workspace,
+		// Path mirroring: the workspace lives at the same path in the container
+		containerWorkspaceFolder: workspace,
+		devcontainerID:           devcontainerID(workspace, configPath),
+		lookupEnv:                os.LookupEnv,
+	}
+}
+
+// workspaceFolderFor returns the project folder a devcontainer.json belongs to.
+func workspaceFolderFor(configPath string) string {
+	if configPath == "" {
+		return ""
+	}
+	dir := filepath.Dir(configPath)
+	if abs, err := filepath.Abs(dir); err == nil {
+		dir = abs
+	}
+	if filepath.Base(dir) == ".devcontainer" {
+		return filepath.Dir(dir)
+	}
+	return dir
+}
+
+// devcontainerID mirrors the reference implementation: a stable base32 encoded
+// hash of the labels identifying the dev container.
+func devcontainerID(workspace, configPath string) string {
+	h := sha256.Sum256([]byte(workspace + "\x00" + configPath))
+	return strings.ToLower(base32.StdEncoding.WithPadding(base32.NoPadding).EncodeToString(h[:]))
+}
+
+// replace substitutes all known variables in s. Unknown variables are left
+// untouched so that later stages (or podman) can report them.
+func (s *substitution) replace(in string) string {
+	return variablePattern.ReplaceAllStringFunc(in, func(match string) string {
+		expr := match[2 : len(match)-1]
+		name, arg, _ := strings.Cut(expr, ":")
+
+		switch name {
+		case "localWorkspaceFolder":
+			return s.localWorkspaceFolder
+		case "localWorkspaceFolderBasename":
+			return basename(s.localWorkspaceFolder)
+		case "containerWorkspaceFolder":
+			return s.containerWorkspaceFolder
+		case "containerWorkspaceFolderBasename":
+			return basename(s.containerWorkspaceFolder)
+		case "devcontainerId":
+			return s.devcontainerID
+		case "localEnv", "env":
+			key, def, _ := strings.Cut(arg, ":")
+			if v, ok := s.lookupEnv(key); ok {
+				return v
+			}
+			return def
+		case "containerEnv":
+			key, def, _ := strings.Cut(arg, ":")
+			if v, ok := s.containerEnv[key]; ok {
+				return v
+			}
+			if s.deferContainerEnv {
+				// The image's environment, e.g. PATH, is only known in the container
+				s.deferred = true
+				if def != "" {
+					return "${" + key + ":-" + def + "}"
+				}
+				return "${" + key + "}"
+			}
+			return def
+		}
+		return match
+	})
+}
+
+func (s *substitution) replaceAll(in []string) []string {
+	if in == nil {
+		return nil
+	}
+	out := make([]string, len(in))
+	for i, v := range in {
+		out[i] = s.replace(v)
+	}
+	return out
+}
+
+func (s *substitution) replaceCommands(in []Command) []Command {
+	if len(in) == 0 {
+		return nil
+	}
+	out := make([]Command, len(in))
+	for i, c := range in {
+		out[i] = Command{Name: c.Name, Shell: s.replace(c.Shell), Args: s.replaceAll(c.Args)}
+	}
+	return out
+}
+
+// replaceJSON substitutes variables in all string values of a JSON document.
+func (s *substitution) replaceJSON(raw []byte) ([]byte, error) {
+	var doc any
+	if err := json.Unmarshal(raw, &doc); err != nil {
+		return nil, err
+	}
+	var walk func(v any) any
+	walk = func(v any) any {
+		switch t := v.(type) {
+		case string:
+			return s.replace(t)
+		case []any:
+			for i := range t {
+				t[i] = walk(t[i])
+			}
+		case map[string]any:
+			for k := range t {
+				t[k] = walk(t[k])
+			}
+		}
+		return v
+	}
+	return json.Marshal(walk(doc))
+}
+
+func basename(p string) string {
+	if p == "" {
+		return ""
+	}
+	return filepath.Base(p)
+}
@@ -0,0 +1,47 @@
+package config
+
+import (
+	"testing"
+)
+
+func TestSubstitution(t *testing.T) {
+	sub := newSubstitution("/home/alice/src/my-app/.devcontainer/devcontainer.json")
+	sub.containerEnv = map[string]string{"PATH_EXTRA": "/opt/bin"}
+	sub.lookupEnv = func(key string) (string, bool) {
+		if key == "HOME" {
+			return "/home/alice", true
+		}
+		return "", false
+	}
+
+	tests := []struct {
+		in       string
+		expected string
+	}{
+		{"${localWorkspaceFolder}", "/home/alice/src/my-app"},
+		{"${localWorkspaceFolderBasename}", "my-app"},
+		{"${containerWorkspaceFolder}/sub", "/home/alice/src/my-app/sub"},
+		{"source=${localEnv:HOME}/.kube,target=/kube", "source=/home/alice/.kube,target=/kube"},
+		{"${localEnv:MISSING}", ""},
+		{"${localEnv:MISSING:fallback}", "fallback"},
+		{"${localEnv:MISSING:a:b}", "a:b"},
+		{"${containerEnv:PATH_EXTRA}:/usr/bin", "/opt/bin:/usr/bin"},
+		{"${containerEnv:MISSING:none}", "none"},
+		{"${unknownVariable}", "${unknownVariable}"},
+		{"plain $HOME", "plain $HOME"},
+	}
+
+	for _, tt := range tests {
+		if got := sub.replace(tt.in); got != tt.expected {
+			t.Errorf("Input: %s, Expected: %q, Got: %q", tt.in, tt.expected, got)
+		}
+	}
+
+	id := sub.replace("${devcontainerId}")
+	if len(id) != 52 {
+		t.Errorf("devcontainerId should be 52 characters, got %q", id)
+	}
+	if id != newSubstitution("/home/alice/src/my-app/.devcontainer/devcontainer.json").devcontainerID {
+		t.Error("devcontainerId should be stable")
+	}
+}
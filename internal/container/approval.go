@@ -0,0 +1,60 @@
+package container
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"runtime"
+	"time"
+
+	"github.com/arewm/ai-shell/internal/approval"
+	"github.com/arewm/ai-shell/internal/config"
+	"github.com/arewm/ai-shell/internal/session"
+)
+
+// approvalLog is the audit trail of the approval decisions of a project,
+// next to its sessions.
+const approvalLog = "approvals.jsonl"
+
+// startApproval serves the approval policy of cfg to the project's container
+// for as long as ai-shell runs, and returns the podman arguments that mount
+// the shims and the socket.
+func startApproval(info ProjectInfo, cfg *config.Config, workspace string, labeling bool) (*approval.Server, []string, error) {
+	if runtime.GOOS != "linux" {
+		// virtiofs does not pass unix sockets to the podman machine VM
+		return nil, nil, fmt.Errorf("approval.commands needs a Linux host, the approval socket cannot reach the podman machine")
+	}
+	dir := approval.RuntimeDir(info.ContainerName)
+	if err := approval.WriteShims(dir, cfg.Approval.Binaries()); err != nil {
+		return nil, nil, err
+	}
+	timeout := time.Duration(cfg.Approval.EffectiveTimeout()) * time.Second
+	s := &approval.Server{
+		Rules:     cfg.Approval.Commands,
+		Workspace: workspace,
+		Timeout:   timeout,
+		AuditLog:  filepath.Join(session.ProjectDir(info.ContainerName), approvalLog),
+		Notify: func(p approval.Prompt, approver bool) {
+			// The terminal is podman's, in raw mode
+			if approver {
+				fmt.Fprintf(os.Stderr, "\r\n⏸  ai-shell: '%s' waits for approval in ai-shell approve.\r\n", p.Request)
+				return
+			}
+			fmt.Fprintf(os.Stderr, "\r\n⏸  ai-shell: '%s' waits for approval: run 'ai-shell approve' in this project (denied after %s).\r\n", p.Request, timeout)
+		},
+	}
+	if err := s.Listen(dir); err != nil {
+		return nil, nil, err
+	}
+	return s, []string{"-v", volumeArg(approval.ContainerDir(dir), approval.MountTarget, relabel("ro", labeling, true))}, nil
+}
+
+// Approve asks the user about the commands the container of the project in
+// the current directory holds back, for ai-shell approve.
+func Approve(profile string) error {
+	info, err := CurrentProject(profile)
+	if err != nil {
+		return err
+	}
+	return approval.Approve(approval.RuntimeDir(info.ContainerName), os.Stdin, os.Stdout)
+}
@@ -0,0 +1,68 @@
+package container
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+
+	"github.com/arewm/ai-shell/internal/audit"
+	"github.com/arewm/ai-shell/internal/session"
+)
+
+// auditSpool is the directory the shell hooks of a project's container
+// write to. It outlives the sessions so that a restarted container finds it.
+func auditSpool(info ProjectInfo) string {
+	return filepath.Join(session.ProjectDir(info.ContainerName), ".audit-spool")
+}
+
+// startCommandAudit collects the commands of the container to the session
+// directory for as long as ai-shell runs, and returns the podman arguments
+// that mount the spool and enable the hooks.
+func startCommandAudit(info ProjectInfo, sessionDir string, labeling bool) (*audit.Collector, []string, error) {
+	c := &audit.Collector{
+		Spool: auditSpool(info),
+		Log:   filepath.Join(sessionDir, audit.LogFile),
+		Warn: func(msg string) {
+			// The terminal is podman's, in raw mode
+			fmt.Fprintf(os.Stderr, "\r\n⚠️  ai-shell: %s\r\n", msg)
+		},
+	}
+	if err := c.Start(); err != nil {
+		return nil, nil, err
+	}
+	return c, []string{
+		"-v", volumeArg(c.Spool, audit.MountTarget, relabel("", labeling, true)),
+		"-e", "AI_SHELL_AUDIT_FILE=" + audit.MountTarget + "/" + audit.SpoolFile,
+		"-e", "BASH_ENV=" + audit.BashEnv,
+	}, nil
+}
+
+// AuditLog prints the commands run in the container of the project in dir,
+// across its sessions, for ai-shell audit log.
+func AuditLog(w io.Writer, profile, dir string, filter audit.Filter, jsonOut bool) error {
+	info, err := ProjectAt(dir, profile)
+	if err != nil {
+		return err
+	}
+	ids, err := session.List(info.ContainerName)
+	if err != nil {
+		return err
+	}
+	dirs := make([]string, len(ids))
+	for i, id := range ids {
+		dirs[i] = filepath.Join(session.ProjectDir(info.ContainerName), id)
+	}
+	records, err := audit.Query(dirs, filter)
+	if err != nil {
+		return err
+	}
+	if jsonOut {
+		return audit.WriteJSON(w, records)
+	}
+	if len(records) == 0 {
+		fmt.Fprintf(w, "No commands logged for %s.\n", info.ContainerName)
+		return nil
+	}
+	return audit.Write(w, records)
+}
@@ -0,0 +1,18 @@
+package container
+
+import (
+	"testing"
+
+	"github.com/arewm/ai-shell/internal/audit"
+	"github.com/arewm/ai-shell/internal/config"
+)
+
+func TestCommandAuditMountsReserved(t *testing.T) {
+	// A project mount over the spool or the hooks would empty the log
+	for _, target := range []string{audit.MountTarget, audit.MountTarget + "/" + audit.SpoolFile, audit.BashEnv} {
+		cfg := &config.Config{Mounts: []config.Mount{{Type: config.MountTypeTmpfs, Target: target}}}
+		if err := cfg.CheckMountConflicts("/src/project"); err == nil {
+			t.Errorf("Input: %s, Expected a conflict, Got: none", target)
+		}
+	}
+}
@@ -0,0 +1,198 @@
+package container
+
+import (
+	"fmt"
+	"net/url"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+
+	"github.com/arewm/ai-shell/internal/config"
+)
+
+const proxyPort = 3128
+
+// egressProxy is the sidecar that filters the egress of a project container.
+// The project container only joins an internal network, on which the proxy
+// is the only member that can reach the outside.
+type egressProxy struct {
+	Name    string
+	Network string
+	// Dir holds the generated squid.conf and, by default, the log
+	Dir string
+	// LogFile receives denied requests, or all requests with LogAll
+	LogFile string
+	LogAll  bool
+}
+
+func newEgressProxy(info ProjectInfo) *egressProxy {
+	home, _ := os.UserHomeDir()
+	dir := filepath.Join(home, ".local", "share", "ai-shell", "network", info.ContainerName)
+	return &egressProxy{
+		Name:    info.ContainerName + "-proxy",
+		Network: info.ContainerName + "-net",
+		Dir:     dir,
+		LogFile: filepath.Join(dir, "denied.log"),
+	}
+}
+
+// Start (re)creates the internal network and the proxy container. In offline
+// mode only the model APIs are allowed, otherwise the config's allowlist.
+func (p *egressProxy) Start(opts RunOptions) error {
+	rules, err := egressRules(opts)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(p.Dir, 0700); err != nil {
+		return err
+	}
+	confPath := filepath.Join(p.Dir, "squid.conf")
+	// A configured proxy becomes the upstream of the egress proxy
+	var upstream *url.URL
+	if opts.Config != nil {
+		if u := opts.Config.Proxy.Resolve(os.Getenv).Upstream(); u != "" {
+			if upstream, err = config.ParseProxyURL(u); err != nil {
+				return err
+			}
+		}
+	}
+	// 0600, the upstream may carry credentials
+	if err := os.WriteFile(confPath, []byte(squidConfig(rules, p.LogAll, upstream)), 0600); err != nil {
+		return err
+	}
+
+	image, err := assetImage("proxy", opts.Verbose)
+	if err != nil {
+		return err
+	}
+
+	if exec.Command("podman", "network", "exists", p.Network).Run() != nil { //nolint:gosec
+		if out, err := exec.Command("podman", "network", "create", "--internal", p.Network).CombinedOutput(); err != nil { //nolint:gosec
+			return fmt.Errorf("failed to create network %s: %w: %s", p.Network, err, strings.TrimSpace(string(out)))
+		}
+	}
+
+	_ = exec.Command("podman", "rm", "-f", p.Name).Run() //nolint:gosec
+	args := []string{"run", "-d", "--name", p.Name,
+		"--network", "podman", "--network", p.Network,
+		"--log-driver", "k8s-file", "--log-opt", "path=" + p.LogFile,
+		"-v", volumeArg(confPath, "/etc/squid/ai-shell.conf", relabel("ro", selinuxLabeling(opts.Config), true)),
+	}
+	args = append(args, resolverArgs(opts.Config)...)
+	args = append(args, image)
+	if out, err := exec.Command("podman", args...).CombinedOutput(); err != nil { //nolint:gosec
+		return fmt.Errorf("failed to start egress proxy: %w: %s", err, strings.TrimSpace(string(out)))
+	}
+	if opts.Verbose {
+		fmt.Printf("   Egress proxy: %s (%d rules)\n", p.Name, len(rules))
+	}
+	return nil
+}
+
+// Stop removes the proxy container; the network is kept for restarts.
+func (p *egressProxy) Stop() {
+	_ = exec.Command("podman", "rm", "-f", p.Name).Run() //nolint:gosec
+}
+
+func egressRules(opts RunOptions) ([]config.AllowRule, error) {
+	if opts.Offline {
+		return opts.Config.ModelAllowRules()
+	}
+	return opts.Config.AllowRules()
+}
+
+// NetworkArgs attach the owner of the project's network namespace to the
+// internal network.
+func (p *egressProxy) NetworkArgs() []string {
+	return []string{"--network", p.Network}
+}
+
+// EnvArgs point the project container at the proxy.
+func (p *egressProxy) EnvArgs() []string {
+	url := fmt.Sprintf("http://%s:%d", p.Name, proxyPort)
+	noProxy := "localhost,127.0.0.1,::1"
+	return []string{
+		"-e", "HTTP_PROXY=" + url, "-e", "HTTPS_PROXY=" + url,
+		"-e", "http_proxy=" + url, "-e", "https_proxy=" + url,
+		"-e", "NO_PROXY=" + noProxy, "-e", "no_proxy=" + noProxy,
+	}
+}
+
+// squidConfig renders the allowlist as a squid configuration. Every rule
+// becomes a destination and port ACL; everything else is denied. Only
+// denials are logged unless logAll is set. With an upstream, all requests
+// are sent through it.
+func squidConfig(rules []config.AllowRule, logAll bool, upstream *url.URL) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "http_port %d\n", proxyPort)
+	b.WriteString("pid_filename none\ncache deny all\nshutdown_lifetime 1 seconds\n\n")
+
+	for i, r := range rules {
+		switch {
+		case r.CIDR != "":
+			fmt.Fprintf(&b, "acl allow%d_dst dst %s\n", i, r.CIDR)
+		case r.Subdomains:
+			fmt.Fprintf(&b, "acl allow%d_dst dstdomain .%s\n", i, r.Domain)
+		default:
+			fmt.Fprintf(&b, "acl allow%d_dst dstdomain %s\n", i, r.Domain)
+		}
+		ports := r.Ports
+		if ports == "" {
+			ports = "80 443"
+		}
+		fmt.Fprintf(&b, "acl allow%d_port port %s\n", i, ports)
+		fmt.Fprintf(&b, "http_access allow allow%d_dst allow%d_port\n", i, i)
+	}
+
+	b.WriteString("http_access deny all\n\n")
+	if upstream != nil {
+		port := upstream.Port()
+		if port == "" {
+			port = "3128"
+		}
+		fmt.Fprintf(&b, "cache_peer %s parent %s 0 no-query no-digest default", upstream.Hostname(), port)
+		if upstream.User != nil {
+			pass, _ := upstream.User.Password()
+			fmt.Fprintf(&b, " login=%s:%s", upstream.User.Username(), pass)
+		}
+		b.WriteString("\nnever_direct allow all\n\n")
+	}
+	if logAll {
+		b.WriteString("access_log stdio:/dev/stdout squid\n")
+	} else {
+		b.WriteString("acl denied http_status 403\n")
+		b.WriteString("access_log stdio:/dev/stdout squid denied\n")
+	}
+	return b.String()
+}
+
+// networkModeLabel records the network mode a container was created with, so
+// that --reuse does not attach to one with another network.
+const networkModeLabel = "ai-shell.network"
+
+// networkMode names the network opts give the container.
+func networkMode(opts RunOptions, filtered, modelEgress bool) string {
+	switch {
+	case opts.NetHost:
+		return "host"
+	case modelEgress:
+		return "model-egress"
+	case opts.Offline:
+		return "offline"
+	case filtered:
+		return "filtered"
+	}
+	return "default"
+}
+
+// containerNetworkMode returns the network mode of an existing container;
+// the ones created before the label was added count as "default".
+func containerNetworkMode(name string) string {
+	out, err := exec.Command("podman", "container", "inspect", "-f", `{{index .Config.Labels "`+networkModeLabel+`"}}`, name).Output() //nolint:gosec
+	mode := strings.TrimSpace(string(out))
+	if err != nil || mode == "" || mode == "<no value>" {
+		return "default"
+	}
+	return mode
+}
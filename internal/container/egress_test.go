@@ -0,0 +1,108 @@
+package container
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/arewm/ai-shell/internal/config"
+)
+
+func TestSquidConfig(t *testing.T) {
+	rules := []config.AllowRule{
+		{Domain: "github.com", Subdomains: true},
+		{Domain: "api.anthropic.com", Ports: "443"},
+		{CIDR: "10.0.0.0/8", Ports: "5000-5010"},
+	}
+	conf := squidConfig(rules, false, nil)
+
+	expected := []string{
+		"http_port 3128\n",
+		"acl allow0_dst dstdomain .github.com\nacl allow0_port port 80 443\nhttp_access allow allow0_dst allow0_port\n",
+		"acl allow1_dst dstdomain api.anthropic.com\nacl allow1_port port 443\n",
+		"acl allow2_dst dst 10.0.0.0/8\nacl allow2_port port 5000-5010\n",
+		"access_log stdio:/dev/stdout squid denied\n",
+	}
+	for _, e := range expected {
+		if !strings.Contains(conf, e) {
+			t.Errorf("Expected %q in squid config:\n%s", e, conf)
+		}
+	}
+
+	// Everything that is not allowed is denied, after the allow rules
+	deny := strings.Index(conf, "http_access deny all")
+	if deny < 0 || deny < strings.LastIndex(conf, "http_access allow") {
+		t.Errorf("Expected a final deny rule:\n%s", conf)
+	}
+
+	// The network audit logs every request
+	if conf := squidConfig(rules, true, nil); !strings.Contains(conf, "access_log stdio:/dev/stdout squid\n") {
+		t.Errorf("Expected all requests to be logged:\n%s", conf)
+	}
+
+	// A configured proxy is used as the upstream
+	upstream, err := config.ParseProxyURL("http://user:pw@proxy.corp:8080")
+	if err != nil {
+		t.Fatal(err)
+	}
+	conf = squidConfig(rules, false, upstream)
+	for _, e := range []string{"cache_peer proxy.corp parent 8080 0 no-query no-digest default login=user:pw\n", "never_direct allow all\n"} {
+		if !strings.Contains(conf, e) {
+			t.Errorf("Expected %q in squid config:\n%s", e, conf)
+		}
+	}
+}
+
+func TestEgressProxyRunArgs(t *testing.T) {
+	p := newEgressProxy(ProjectInfo{ContainerName: "ai-shell-project-1234"})
+	args := strings.Join(append(p.NetworkArgs(), p.EnvArgs()...), " ")
+	for _, e := range []string{"--network ai-shell-project-1234-net", "HTTPS_PROXY=http://ai-shell-project-1234-proxy:3128", "NO_PROXY=localhost"} {
+		if !strings.Contains(args, e) {
+			t.Errorf("Expected %q in %s", e, args)
+		}
+	}
+}
+
+func TestEgressRules(t *testing.T) {
+	cfg := &config.Config{SCMs: []config.SCM{{Host: "github.com"}}, Network: config.Network{Allow: []string{"example.com"}}}
+
+	rules, err := egressRules(RunOptions{Config: cfg, Offline: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(rules) != len(config.DefaultModelHosts) {
+		t.Errorf("Expected only the model hosts in offline mode, got %+v", rules)
+	}
+	for _, r := range rules {
+		if r.Domain == "github.com" || r.Domain == "example.com" {
+			t.Errorf("Unexpected rule in offline mode: %+v", r)
+		}
+	}
+
+	rules, err = egressRules(RunOptions{Config: cfg})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(rules) <= len(config.DefaultModelHosts) {
+		t.Errorf("Expected the full allowlist, got %+v", rules)
+	}
+}
+
+func TestNetworkMode(t *testing.T) {
+	tests := []struct {
+		opts        RunOptions
+		filtered    bool
+		modelEgress bool
+		expected    string
+	}{
+		{RunOptions{}, false, false, "default"},
+		{RunOptions{NetHost: true}, false, false, "host"},
+		{RunOptions{}, true, false, "filtered"},
+		{RunOptions{Offline: true}, false, false, "offline"},
+		{RunOptions{Offline: true}, true, true, "model-egress"},
+	}
+	for _, tt := range tests {
+		if got := networkMode(tt.opts, tt.filtered, tt.modelEgress); got != tt.expected {
+			t.Errorf("Input: %+v, Expected: %s, Got: %s", tt.opts, tt.expected, got)
+		}
+	}
+}
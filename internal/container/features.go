@@ -0,0 +1,163 @@
+package container
+
+import (
+	"crypto/sha256"
+	"fmt"
+	"io/fs"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/arewm/ai-shell/internal/config"
+)
+
+// featureImage returns the tag of the image with the given features layered
+// onto baseImage for remoteUser, building it if it is not cached yet. The tag
+// is derived from the ID of the base image, the feature contents and their
+// options.
+func featureImage(baseImage string, features []config.Feature, remoteUser string, verbose bool) (string, error) {
+	id, err := exec.Command("podman", "image", "inspect", "--format", "{{.Id}}", baseImage).Output() //nolint:gosec
+	if err != nil {
+		return "", fmt.Errorf("failed to inspect %s: %w", baseImage, err)
+	}
+	hash, err := hashFeatures(strings.TrimSpace(string(id)), features, remoteUser)
+	if err != nil {
+		return "", err
+	}
+	tag := fmt.Sprintf("localhost/ai-shell-features:%s", hash[:16])
+
+	if exec.Command("podman", "image", "exists", tag).Run() == nil { //nolint:gosec
+		if verbose {
+			fmt.Printf("   Using cached feature image %s\n", tag)
+		}
+		return tag, nil
+	}
+
+	buildDir, err := os.MkdirTemp("", "ai-shell-features-*")
+	if err != nil {
+		return "", err
+	}
+	defer func() { _ = os.RemoveAll(buildDir) }()
+
+	for i, f := range features {
+		dst := filepath.Join(buildDir, featureDirName(i, f))
+		if err := copyDir(f.Dir, dst); err != nil {
+			return "", fmt.Errorf("failed to copy feature %s: %w", f.ID, err)
+		}
+		if err := os.WriteFile(filepath.Join(dst, "devcontainer-features.env"), []byte(featureEnvFile(f, remoteUser)), 0644); err != nil { //nolint:gosec
+			return "", err
+		}
+	}
+
+	containerfile := filepath.Join(buildDir, "Containerfile")
+	if err := os.WriteFile(containerfile, []byte(featureContainerfile(baseImage, features)), 0644); err != nil { //nolint:gosec
+		return "", err
+	}
+
+	fmt.Printf("   Building feature image %s...\n", tag)
+	if err := execPodman("build", "-t", tag, "-f", containerfile, buildDir); err != nil {
+		return "", fmt.Errorf("failed to build feature image: %w", err)
+	}
+	return tag, nil
+}
+
+func featureDirName(i int, f config.Feature) string {
+	return fmt.Sprintf("%02d-%s", i, f.ID)
+}
+
+// featureContainerfile installs the features in order, as root, the way the
+// devcontainer CLI does: options are sourced from devcontainer-features.env,
+// and the home of the remote user is looked up in the image when it exists.
+func featureContainerfile(baseImage string, features []config.Feature) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "FROM %s\n\nUSER root\n", baseImage)
+
+	for i, f := range features {
+		dir := "/tmp/ai-shell-features/" + featureDirName(i, f)
+		fmt.Fprintf(&b, "\n# Feature: %s\n", f.ID)
+		fmt.Fprintf(&b, "COPY %s/ %s/\n", featureDirName(i, f), dir)
+		fmt.Fprintf(&b, "RUN cd %s \\\n    && chmod +x install.sh \\\n    && set -a && . ./devcontainer-features.env \\\n    && _REMOTE_USER_HOME=\"$(getent passwd \"$_REMOTE_USER\" | cut -d: -f6)\" \\\n    && _REMOTE_USER_HOME=\"${_REMOTE_USER_HOME:-/home/$_REMOTE_USER}\" && set +a \\\n    && ./install.sh\n", dir)
+		for _, k := range sortedMapKeys(f.ContainerEnv) {
+			fmt.Fprintf(&b, "ENV %s=%s\n", k, strconv.Quote(f.ContainerEnv[k]))
+		}
+	}
+
+	b.WriteString("\nRUN rm -rf /tmp/ai-shell-features\n")
+	return b.String()
+}
+
+// featureEnvFile holds the options of a feature plus the user variables the
+// spec defines, quoted for sourcing by sh. _REMOTE_USER_HOME is set by the
+// Containerfile.
+func featureEnvFile(f config.Feature, remoteUser string) string {
+	env := map[string]string{
+		"_REMOTE_USER":         remoteUser,
+		"_CONTAINER_USER":      "root",
+		"_CONTAINER_USER_HOME": "/root",
+	}
+	for k, v := range f.Options {
+		env[k] = v
+	}
+
+	var b strings.Builder
+	for _, k := range sortedMapKeys(env) {
+		fmt.Fprintf(&b, "%s=%s\n", k, shellQuote(env[k]))
+	}
+	return b.String()
+}
+
+// hashFeatures hashes everything that ends up in the feature image, starting
+// with the ID of the base image.
+func hashFeatures(baseID string, features []config.Feature, remoteUser string) (string, error) {
+	h := sha256.New()
+	fmt.Fprintf(h, "base=%s\n", baseID)
+	for _, f := range features {
+		fmt.Fprintf(h, "feature=%s\n%s%s", f.ID, featureEnvFile(f, remoteUser), featureContainerfile("", []config.Feature{f}))
+		if err := hashDir(h, f.Dir); err != nil {
+			return "", fmt.Errorf("failed to hash feature %s: %w", f.ID, err)
+		}
+	}
+	return fmt.Sprintf("%x", h.Sum(nil)), nil
+}
+
+// copyDir copies a feature into the build context.
+func copyDir(src, dst string) error {
+	return filepath.WalkDir(src, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		rel, _ := filepath.Rel(src, path)
+		target := filepath.Join(dst, rel)
+		if d.IsDir() {
+			return os.MkdirAll(target, 0755)
+		}
+		if err := checkRegular(path, d); err != nil {
+			return err
+		}
+		info, err := d.Info()
+		if err != nil {
+			return err
+		}
+		data, err := os.ReadFile(path) //nolint:gosec
+		if err != nil {
+			return err
+		}
+		return os.WriteFile(target, data, info.Mode().Perm())
+	})
+}
+
+func shellQuote(s string) string {
+	return "'" + strings.ReplaceAll(s, "'", `'\''`) + "'"
+}
+
+func sortedMapKeys(m map[string]string) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
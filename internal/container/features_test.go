@@ -0,0 +1,103 @@
+package container
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/arewm/ai-shell/internal/config"
+)
+
+func TestFeatureContainerfile(t *testing.T) {
+	features := []config.Feature{
+		{ID: "node", Options: map[string]string{"VERSION": "20"}, ContainerEnv: map[string]string{"NODE_HOME": "/opt/node"}},
+		{ID: "linters"},
+	}
+
+	cf := featureContainerfile("localhost/ai-shell-default:latest", features)
+	for _, expected := range []string{
+		"FROM localhost/ai-shell-default:latest\n",
+		"COPY 00-node/ /tmp/ai-shell-features/00-node/\n",
+		"COPY 01-linters/ /tmp/ai-shell-features/01-linters/\n",
+		`ENV NODE_HOME="/opt/node"`,
+		`_REMOTE_USER_HOME="$(getent passwd "$_REMOTE_USER" | cut -d: -f6)"`,
+	} {
+		if !strings.Contains(cf, expected) {
+			t.Errorf("Containerfile is missing %q:\n%s", expected, cf)
+		}
+	}
+	if strings.Index(cf, "00-node") > strings.Index(cf, "01-linters") {
+		t.Error("Features must be installed in order")
+	}
+
+	env := featureEnvFile(config.Feature{Options: map[string]string{"GREETING": "it's me"}}, "dev")
+	if !strings.Contains(env, `GREETING='it'\''s me'`) || !strings.Contains(env, "_REMOTE_USER='dev'") {
+		t.Errorf("Unexpected env file:\n%s", env)
+	}
+}
+
+func TestHashFeatures(t *testing.T) {
+	tmpDir, err := os.MkdirTemp("", "ai-shell-features-test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() { _ = os.RemoveAll(tmpDir) }()
+
+	install := filepath.Join(tmpDir, "install.sh")
+	if err := os.WriteFile(install, []byte("echo one"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	features := []config.Feature{{ID: "foo", Dir: tmpDir, Options: map[string]string{"A": "1"}}}
+
+	first, err := hashFeatures("base", features, "ai")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if again, _ := hashFeatures("base", features, "ai"); again != first {
+		t.Error("Hash should be stable")
+	}
+	if other, _ := hashFeatures("other-base", features, "ai"); other == first {
+		t.Error("Hash should depend on the base image")
+	}
+
+	if other, _ := hashFeatures("base", features, "dev"); other == first {
+		t.Error("Hash should depend on the remote user")
+	}
+
+	features[0].Options["A"] = "2"
+	if other, _ := hashFeatures("base", features, "ai"); other == first {
+		t.Error("Hash should depend on the options")
+	}
+	features[0].Options["A"] = "1"
+
+	if err := os.WriteFile(install, []byte("echo two"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	if other, _ := hashFeatures("base", features, "ai"); other == first {
+		t.Error("Hash should depend on the feature contents")
+	}
+}
+
+func TestCopyDirSymlink(t *testing.T) {
+	src := t.TempDir()
+	secret := filepath.Join(t.TempDir(), "id_ed25519")
+	if err := os.WriteFile(secret, []byte("key"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Symlink(secret, filepath.Join(src, "install.sh")); err != nil {
+		t.Fatal(err)
+	}
+
+	dst := filepath.Join(t.TempDir(), "feature")
+	if err := copyDir(src, dst); err == nil {
+		t.Errorf("Input: symlink to %s, Expected: error, Got: nil", secret)
+	}
+	if _, err := os.Stat(filepath.Join(dst, "install.sh")); !os.IsNotExist(err) {
+		t.Errorf("Input: symlink to %s, Expected: nothing copied, Got: %v", secret, err)
+	}
+	features := []config.Feature{{ID: "foo", Dir: src}}
+	if _, err := hashFeatures("base", features, "ai"); err == nil {
+		t.Errorf("Input: symlink to %s, Expected: hash error, Got: nil", secret)
+	}
+}
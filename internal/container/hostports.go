@@ -0,0 +1,147 @@
+package container
+
+import (
+	"bytes"
+	"fmt"
+	"net"
+	"net/url"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+
+	"go.yaml.in/yaml/v3"
+)
+
+// hostGateway is the name podman resolves to the host.
+const hostGateway = "host.containers.internal"
+
+// hostPortArgs returns the podman arguments that forward the given ports
+// from the container's loopback to the host's. On Linux pasta does this for
+// the listed ports only, so 127.0.0.1:<port> works unchanged. With podman
+// machine (macOS) the container has to go through hostGateway instead, see
+// rewriteKubeconfig.
+func hostPortArgs(goos string, ports []int) []string {
+	if len(ports) == 0 || goos == "darwin" {
+		return nil
+	}
+	opts := []string{}
+	for _, p := range ports {
+		opts = append(opts, "-T", strconv.Itoa(p))
+	}
+	return []string{"--network", "pasta:" + strings.Join(opts, ",")}
+}
+
+// kubeconfigArgs writes a copy of the host kubeconfig whose loopback servers
+// on the forwarded ports point at hostGateway, and returns the arguments that
+// mount it. It returns nothing if no server needs rewriting.
+func kubeconfigArgs(ports []int, tmpDir string) ([]string, error) {
+	path := os.Getenv("KUBECONFIG")
+	if path == "" {
+		home, _ := os.UserHomeDir()
+		path = filepath.Join(home, ".kube", "config")
+	}
+	// Only the first file of a KUBECONFIG list is rewritten
+	path = filepath.SplitList(path)[0]
+
+	data, err := os.ReadFile(path) //nolint:gosec
+	if err != nil {
+		return nil, nil
+	}
+	rewritten, changed, err := rewriteKubeconfig(data, ports, hostGateway)
+	if err != nil {
+		return nil, fmt.Errorf("failed to rewrite %s: %w", path, err)
+	}
+	if !changed {
+		return nil, nil
+	}
+
+	target := filepath.Join(tmpDir, "kubeconfig")
+	if err := os.WriteFile(target, rewritten, 0600); err != nil {
+		return nil, err
+	}
+	return []string{"-v", target + ":/etc/ai-shell/kubeconfig:ro", "-e", "KUBECONFIG=/etc/ai-shell/kubeconfig"}, nil
+}
+
+// rewriteKubeconfig points cluster servers on loopback and one of ports at
+// host. The original host name is kept as tls-server-name so that the server
+// certificate still verifies.
+func rewriteKubeconfig(data []byte, ports []int, host string) ([]byte, bool, error) {
+	var doc yaml.Node
+	if err := yaml.Unmarshal(data, &doc); err != nil {
+		return nil, false, err
+	}
+	if len(doc.Content) == 0 {
+		return data, false, nil
+	}
+
+	changed := false
+	clusters := mappingValue(doc.Content[0], "clusters")
+	if clusters == nil {
+		return data, false, nil
+	}
+	for _, entry := range clusters.Content {
+		cluster := mappingValue(entry, "cluster")
+		if cluster == nil {
+			continue
+		}
+		server := mappingValue(cluster, "server")
+		if server == nil {
+			continue
+		}
+		u, err := url.Parse(server.Value)
+		if err != nil || !isLoopback(u.Hostname()) {
+			continue
+		}
+		port, err := strconv.Atoi(u.Port())
+		if err != nil || !slices.Contains(ports, port) {
+			continue
+		}
+
+		if mappingValue(cluster, "tls-server-name") == nil {
+			cluster.Content = append(cluster.Content,
+				&yaml.Node{Kind: yaml.ScalarNode, Value: "tls-server-name"},
+				&yaml.Node{Kind: yaml.ScalarNode, Value: u.Hostname()},
+			)
+		}
+		u.Host = net.JoinHostPort(host, u.Port())
+		server.Value = u.String()
+		changed = true
+	}
+
+	if !changed {
+		return data, false, nil
+	}
+	var out bytes.Buffer
+	enc := yaml.NewEncoder(&out)
+	enc.SetIndent(2)
+	if err := enc.Encode(&doc); err != nil {
+		return nil, false, err
+	}
+	if err := enc.Close(); err != nil {
+		return nil, false, err
+	}
+	return out.Bytes(), true, nil
+}
+
+// mappingValue returns the value of key in a YAML mapping node.
+func mappingValue(n *yaml.Node, key string) *yaml.Node {
+	if n == nil || n.Kind != yaml.MappingNode {
+		return nil
+	}
+	for i := 0; i+1 < len(n.Content); i += 2 {
+		if n.Content[i].Value == key {
+			return n.Content[i+1]
+		}
+	}
+	return nil
+}
+
+func isLoopback(host string) bool {
+	if host == "localhost" {
+		return true
+	}
+	ip := net.ParseIP(host)
+	return ip != nil && ip.IsLoopback()
+}
@@ -0,0 +1,65 @@
+package container
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestHostPortArgs(t *testing.T) {
+	if args := hostPortArgs("linux", nil); args != nil {
+		t.Errorf("Expected no args without ports, got %v", args)
+	}
+	if got := strings.Join(hostPortArgs("linux", []int{6443, 5000}), " "); got != "--network pasta:-T,6443,-T,5000" {
+		t.Errorf("Unexpected args: %s", got)
+	}
+	if args := hostPortArgs("darwin", []int{6443}); args != nil {
+		t.Errorf("Expected no network args on darwin, got %v", args)
+	}
+}
+
+func TestRewriteKubeconfig(t *testing.T) {
+	kubeconfig := `apiVersion: v1
+kind: Config
+clusters:
+  - name: kind-dev
+    cluster:
+      server: https://127.0.0.1:6443
+      certificate-authority-data: abc
+  - name: local
+    cluster:
+      server: https://localhost:7443
+      tls-server-name: kind-control-plane
+  - name: other-port
+    cluster:
+      server: https://127.0.0.1:9443
+  - name: remote
+    cluster:
+      server: https://api.example.com:6443
+current-context: kind-dev
+`
+	out, changed, err := rewriteKubeconfig([]byte(kubeconfig), []int{6443, 7443}, hostGateway)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !changed {
+		t.Fatal("Expected the kubeconfig to be rewritten")
+	}
+	got := string(out)
+
+	expected := []string{
+		"server: https://host.containers.internal:6443\n      certificate-authority-data: abc\n      tls-server-name: 127.0.0.1",
+		"server: https://host.containers.internal:7443\n      tls-server-name: kind-control-plane",
+		"server: https://127.0.0.1:9443",
+		"server: https://api.example.com:6443",
+		"current-context: kind-dev",
+	}
+	for _, e := range expected {
+		if !strings.Contains(got, e) {
+			t.Errorf("Expected %q in:\n%s", e, got)
+		}
+	}
+
+	if _, changed, _ := rewriteKubeconfig([]byte(kubeconfig), []int{1234}, hostGateway); changed {
+		t.Error("Expected no rewrite when no server uses a forwarded port")
+	}
+}
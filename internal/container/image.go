@@ -0,0 +1,219 @@
+package container
+
+import (
+	"crypto/sha256"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/arewm/ai-shell/internal/assets"
+	"github.com/arewm/ai-shell/internal/config"
+)
+
+// entrypoint is what makes an image usable by ai-shell: it sets up path
+// mirroring as root and drops to the 'ai' user.
+const entrypoint = "/usr/local/bin/configure.sh"
+
+// ProfileImage is the image ai-shell build tags for a profile.
+func ProfileImage(profile string) string {
+	if profile == "" {
+		profile = "default"
+	}
+	return fmt.Sprintf("localhost/ai-shell-%s:latest", profile)
+}
+
+// resolveImage returns the image to run. A project image (or one built from
+// the project's Dockerfile) replaces the profile image; if it is not derived
+// from ai-shell-base, ai-shell's entrypoint is layered on top. Devcontainer
+// features are layered last, for remoteUser.
+func resolveImage(opts RunOptions, info ProjectInfo, remoteUser string) (string, error) {
+	image := opts.ImageName
+	if opts.Config == nil {
+		return image, nil
+	}
+	cfg := opts.Config
+
+	if cfg.Build != nil && cfg.Build.Dockerfile != "" {
+		tag, err := buildProjectImage(cfg.Build, info)
+		if err != nil {
+			return "", err
+		}
+		image = tag
+	} else if cfg.Image != "" {
+		image = cfg.Image
+	}
+
+	if image != opts.ImageName {
+		derived, err := hasEntrypoint(image)
+		if err != nil {
+			return "", err
+		}
+		if !derived {
+			fmt.Printf("   %s does not use ai-shell's entrypoint, adding it...\n", image)
+			if image, err = adaptImage(image, opts.Verbose); err != nil {
+				return "", err
+			}
+		}
+	}
+
+	if len(cfg.Features) > 0 {
+		return featureImage(image, cfg.Features, remoteUser, opts.Verbose)
+	}
+	return image, nil
+}
+
+// buildProjectImage builds the project's Dockerfile. The build always runs so
+// that changes are picked up; podman's layer cache keeps it cheap.
+func buildProjectImage(b *config.Build, info ProjectInfo) (string, error) {
+	tag := fmt.Sprintf("localhost/ai-shell-project-%s-%s:latest", strings.ToLower(info.Name), info.Hash)
+
+	buildContext := b.Context
+	if buildContext == "" {
+		buildContext = filepath.Dir(b.Dockerfile)
+	}
+	args := []string{"build", "-t", tag, "-f", b.Dockerfile}
+	keys := make([]string, 0, len(b.Args))
+	for k := range b.Args {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		args = append(args, "--build-arg", fmt.Sprintf("%s=%s", k, b.Args[k]))
+	}
+	args = append(args, buildContext)
+
+	fmt.Printf("   Building project image %s...\n", tag)
+	if err := execPodman(args...); err != nil {
+		return "", fmt.Errorf("failed to build project image from %s: %w", b.Dockerfile, err)
+	}
+	return tag, nil
+}
+
+// hasEntrypoint pulls image if needed and reports whether it runs ai-shell's
+// entrypoint.
+func hasEntrypoint(image string) (bool, error) {
+	if exec.Command("podman", "image", "exists", image).Run() != nil { //nolint:gosec
+		if err := execPodman("pull", image); err != nil {
+			return false, fmt.Errorf("failed to pull %s: %w", image, err)
+		}
+	}
+
+	out, err := exec.Command("podman", "image", "inspect", "--format", "{{json .Config.Entrypoint}}", image).Output() //nolint:gosec
+	if err != nil {
+		return false, fmt.Errorf("failed to inspect %s: %w", image, err)
+	}
+	return strings.Contains(string(out), entrypoint), nil
+}
+
+// adaptImage layers ai-shell's entrypoint and its requirements onto image.
+// The result is cached by the image ID and the adapter contents.
+func adaptImage(image string, verbose bool) (string, error) {
+	buildDir, err := os.MkdirTemp("", "ai-shell-adapter-*")
+	if err != nil {
+		return "", err
+	}
+	defer func() { _ = os.RemoveAll(buildDir) }()
+
+	if err := assets.WriteToDir(buildDir, "adapter"); err != nil {
+		return "", err
+	}
+	if err := os.Mkdir(filepath.Join(buildDir, "base"), 0755); err != nil {
+		return "", err
+	}
+	if err := assets.WriteToDir(filepath.Join(buildDir, "base"), "base"); err != nil {
+		return "", err
+	}
+
+	id, err := exec.Command("podman", "image", "inspect", "--format", "{{.Id}}", image).Output() //nolint:gosec
+	if err != nil {
+		return "", fmt.Errorf("failed to inspect %s: %w", image, err)
+	}
+	h := sha256.New()
+	fmt.Fprintf(h, "image=%s\n", strings.TrimSpace(string(id)))
+	if err := hashDir(h, buildDir); err != nil {
+		return "", err
+	}
+	tag := fmt.Sprintf("localhost/ai-shell-adapted:%x", h.Sum(nil)[:8])
+
+	if exec.Command("podman", "image", "exists", tag).Run() == nil { //nolint:gosec
+		if verbose {
+			fmt.Printf("   Using cached adapted image %s\n", tag)
+		}
+		return tag, nil
+	}
+
+	if err := execPodman("build", "-t", tag, "--build-arg", "BASE_IMAGE="+image, buildDir); err != nil {
+		return "", fmt.Errorf("failed to add ai-shell's entrypoint to %s: %w", image, err)
+	}
+	return tag, nil
+}
+
+// hashDir writes the relative paths and contents of all files below dir to h.
+func hashDir(h io.Writer, dir string) error {
+	return filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil || d.IsDir() {
+			return err
+		}
+		rel, _ := filepath.Rel(dir, path)
+		if err := checkRegular(path, d); err != nil {
+			return err
+		}
+		fmt.Fprintf(h, "file=%s\n", rel)
+		f, err := os.Open(path) //nolint:gosec
+		if err != nil {
+			return err
+		}
+		defer func() { _ = f.Close() }()
+		_, err = io.Copy(h, f)
+		return err
+	})
+}
+
+// checkRegular refuses symlinks and special files in a build context: a
+// link could pull any file of the host into the image, and a FIFO would
+// block the build.
+func checkRegular(path string, d fs.DirEntry) error {
+	if d.Type()&fs.ModeSymlink != 0 {
+		return fmt.Errorf("%s is a symlink", path)
+	}
+	if !d.Type().IsRegular() {
+		return fmt.Errorf("%s is not a regular file", path)
+	}
+	return nil
+}
+
+// assetImage builds the sidecar image of an assets directory, e.g. "proxy",
+// cached by the asset contents.
+func assetImage(name string, verbose bool) (string, error) {
+	buildDir, err := os.MkdirTemp("", "ai-shell-"+name+"-*")
+	if err != nil {
+		return "", err
+	}
+	defer func() { _ = os.RemoveAll(buildDir) }()
+
+	if err := assets.WriteToDir(buildDir, name); err != nil {
+		return "", err
+	}
+	h := sha256.New()
+	if err := hashDir(h, buildDir); err != nil {
+		return "", err
+	}
+	tag := fmt.Sprintf("localhost/ai-shell-%s:%x", name, h.Sum(nil)[:8])
+
+	if exec.Command("podman", "image", "exists", tag).Run() == nil { //nolint:gosec
+		if verbose {
+			fmt.Printf("   Using cached %s image %s\n", name, tag)
+		}
+		return tag, nil
+	}
+	fmt.Printf("   Building %s image %s...\n", name, tag)
+	if err := execPodman("build", "-t", tag, buildDir); err != nil {
+		return "", fmt.Errorf("failed to build %s image: %w", name, err)
+	}
+	return tag, nil
+}
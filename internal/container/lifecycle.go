@@ -0,0 +1,59 @@
+package container
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"strings"
+	"sync"
+
+	"github.com/arewm/ai-shell/internal/config"
+)
+
+// runHostCommands runs lifecycle commands on the host in dir. The commands of
+// one stage run in parallel; all of them are waited for before returning.
+func runHostCommands(stage string, cmds []config.Command, dir string) error {
+	if len(cmds) == 0 {
+		return nil
+	}
+
+	var wg sync.WaitGroup
+	errs := make([]error, len(cmds))
+	for i, c := range cmds {
+		var cmd *exec.Cmd
+		if len(c.Args) > 0 {
+			cmd = exec.Command(c.Args[0], c.Args[1:]...) //nolint:gosec
+		} else {
+			cmd = exec.Command("/bin/sh", "-c", c.Shell) //nolint:gosec
+		}
+		cmd.Dir = dir
+		cmd.Stdout = os.Stdout
+		cmd.Stderr = os.Stderr
+
+		name := stage
+		if c.Name != "" {
+			name = fmt.Sprintf("%s (%s)", stage, c.Name)
+		}
+		fmt.Printf("   Running %s...\n", name)
+
+		wg.Add(1)
+		go func(i int, name string) {
+			defer wg.Done()
+			if err := cmd.Run(); err != nil {
+				errs[i] = fmt.Errorf("%s failed: %w", name, err)
+			}
+		}(i, name)
+	}
+	wg.Wait()
+
+	var msgs []string
+	for _, err := range errs {
+		if err != nil {
+			msgs = append(msgs, err.Error())
+		}
+	}
+	if len(msgs) > 0 {
+		return fmt.Errorf("lifecycle command %s", strings.Join(msgs, "; "))
+	}
+	return nil
+}
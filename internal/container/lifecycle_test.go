@@ -0,0 +1,39 @@
+package container
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/arewm/ai-shell/internal/config"
+)
+
+func TestRunHostCommands(t *testing.T) {
+	tmpDir, err := os.MkdirTemp("", "ai-shell-lifecycle-test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() { _ = os.RemoveAll(tmpDir) }()
+
+	cmds := []config.Command{
+		{Name: "shell", Shell: "touch from-shell"},
+		{Name: "args", Args: []string{"touch", "from-args"}},
+	}
+	if err := runHostCommands("initializeCommand", cmds, tmpDir); err != nil {
+		t.Fatalf("runHostCommands failed: %v", err)
+	}
+	for _, f := range []string{"from-shell", "from-args"} {
+		if _, err := os.Stat(filepath.Join(tmpDir, f)); err != nil {
+			t.Errorf("Command did not run in %s: %v", tmpDir, err)
+		}
+	}
+
+	failing := []config.Command{{Shell: "true"}, {Name: "broken", Shell: "exit 3"}}
+	if err := runHostCommands("initializeCommand", failing, tmpDir); err == nil {
+		t.Error("Expected an error from a failing command")
+	}
+
+	if err := runHostCommands("initializeCommand", nil, tmpDir); err != nil {
+		t.Errorf("No commands should not fail: %v", err)
+	}
+}
@@ -0,0 +1,95 @@
+package container
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/arewm/ai-shell/internal/session"
+)
+
+// monitorLogDir is where the monitor writes its logs inside its container.
+const monitorLogDir = "/var/log/ai-shell"
+
+// netMonitor is the sidecar behind network.audit. It owns the network
+// namespace of the project container, resolves its DNS queries and logs
+// them together with its outbound connections to the session directory.
+type netMonitor struct {
+	Name string
+	// Dir is the session directory the logs are written to
+	Dir string
+	// Relabel makes Dir private to the monitor for SELinux
+	Relabel bool
+}
+
+func newNetMonitor(info ProjectInfo, dir string) *netMonitor {
+	return &netMonitor{Name: info.ContainerName + "-monitor", Dir: dir}
+}
+
+// Start (re)creates the monitor with the network arguments the project
+// container would otherwise get, and waits until its resolver is up.
+func (m *netMonitor) Start(netArgs []string, verbose bool) error {
+	image, err := assetImage("monitor", verbose)
+	if err != nil {
+		return err
+	}
+
+	_ = exec.Command("podman", "rm", "-f", m.Name).Run() //nolint:gosec
+	args := []string{"run", "-d", "--rm", "--name", m.Name,
+		"--cap-add", "NET_RAW",
+		"-v", volumeArg(m.Dir, monitorLogDir, relabel("", m.Relabel, true)),
+	}
+	args = append(args, netArgs...)
+	args = append(args, image)
+	if out, err := exec.Command("podman", args...).CombinedOutput(); err != nil { //nolint:gosec
+		return fmt.Errorf("failed to start network monitor: %w: %s", err, strings.TrimSpace(string(out)))
+	}
+
+	// dnsmasq creates its log once it has taken over resolv.conf
+	dnsLog := filepath.Join(m.Dir, session.DNSLog)
+	for i := 0; i < 50; i++ {
+		if _, err := os.Stat(dnsLog); err == nil {
+			if verbose {
+				fmt.Printf("   Network monitor: %s\n", m.Name)
+			}
+			return nil
+		}
+		time.Sleep(100 * time.Millisecond)
+	}
+	m.Stop()
+	return fmt.Errorf("network monitor %s did not start", m.Name)
+}
+
+// Stop removes the monitor container.
+func (m *netMonitor) Stop() {
+	_ = exec.Command("podman", "rm", "-f", m.Name).Run() //nolint:gosec
+}
+
+// RunArgs join the project container to the monitor's network namespace.
+func (m *netMonitor) RunArgs() []string {
+	return []string{"--network", "container:" + m.Name}
+}
+
+// NetLog writes the network summary of a session of the project in the
+// current directory, for ai-shell net-log. An empty id selects the latest
+// session.
+func NetLog(w io.Writer, profile, id string) error {
+	info, err := CurrentProject(profile)
+	if err != nil {
+		return err
+	}
+	dir, err := session.Resolve(info.ContainerName, id)
+	if err != nil {
+		return err
+	}
+	summary, err := session.SummarizeNetwork(dir)
+	if err != nil {
+		return err
+	}
+	fmt.Fprintf(w, "Session %s\n\n", filepath.Base(dir))
+	return session.WriteNetSummary(w, summary)
+}
@@ -0,0 +1,93 @@
+package container
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/arewm/ai-shell/internal/config"
+)
+
+// mountArgs translates a config mount into podman arguments. Bind mounts whose
+// source does not exist are created or skipped, unless they are required; bind
+// sources are checked against the protected paths. homeVolume is the home
+// volume of the project, the only one a config may mount.
+func mountArgs(m config.Mount, protected []config.ProtectedPath, homeVolume string) ([]string, error) {
+	src := os.ExpandEnv(m.Source)
+	tgt := os.ExpandEnv(m.Target)
+
+	switch m.Type {
+	case config.MountTypeVolume:
+		opt := m.ResolvedOptions("rw")
+		if src == "" {
+			// Anonymous volume, removed together with the container
+			return []string{"--mount", volumeMountSpec(tgt, opt)}, nil
+		}
+		// A variable may expand to a path, which podman would bind mount
+		if !config.IsVolumeName(src) {
+			return nil, fmt.Errorf("mount %s: volume source %s is not a volume name", tgt, src)
+		}
+		// The home of another project holds the credentials of its agents
+		if strings.HasPrefix(src, homeVolumePrefix) && src != homeVolume {
+			return nil, fmt.Errorf("mount %s: volume %s is the home volume of another project", tgt, src)
+		}
+		return []string{"-v", fmt.Sprintf("%s:%s:%s", src, tgt, opt)}, nil
+
+	case config.MountTypeTmpfs:
+		opt := m.ResolvedOptions("")
+		if opt == "" {
+			return []string{"--tmpfs", tgt}, nil
+		}
+		return []string{"--tmpfs", fmt.Sprintf("%s:%s", tgt, opt)}, nil
+
+	case "", config.MountTypeBind:
+		opt := m.ResolvedOptions("ro")
+		if _, err := os.Stat(src); err != nil {
+			switch {
+			case m.Create:
+				// Refuse before creating anything below a protected path
+				if action, hit := checkProtected(resolvePath(src), protected); action == protectDeny {
+					return nil, fmt.Errorf("refusing to create %s: it is below protected path %s", src, hit)
+				}
+				if err := os.MkdirAll(src, 0755); err != nil {
+					return nil, fmt.Errorf("mount %s: failed to create source: %w", tgt, err)
+				}
+			case m.Required:
+				return nil, fmt.Errorf("mount %s: required source %s does not exist", tgt, src)
+			default:
+				fmt.Printf("⚠️  Skipping mount of %s: it does not exist\n", src)
+				return nil, nil
+			}
+		}
+
+		// Check and mount the resolved path so symlinks cannot point elsewhere later
+		src = resolvePath(src)
+		switch action, hit := checkProtected(src, protected); action {
+		case protectDeny:
+			return nil, fmt.Errorf("refusing to mount %s: it exposes protected path %s", src, hit)
+		case protectReadOnly:
+			if ro := forceReadOnly(opt); ro != opt {
+				fmt.Printf("⚠️  Mounting %s read-only: it exposes protected path %s\n", src, hit)
+				opt = ro
+			}
+		}
+		return []string{"-v", fmt.Sprintf("%s:%s:%s", src, tgt, opt)}, nil
+	}
+
+	return nil, fmt.Errorf("mount %s: unsupported type %s", tgt, m.Type)
+}
+
+// volumeMountSpec builds a --mount value for an anonymous volume.
+func volumeMountSpec(target, opts string) string {
+	spec := []string{"type=volume", "destination=" + target}
+	for _, o := range strings.Split(opts, ",") {
+		switch o {
+		case "", "rw":
+		case "ro":
+			spec = append(spec, "ro=true")
+		default:
+			spec = append(spec, o)
+		}
+	}
+	return strings.Join(spec, ",")
+}
@@ -0,0 +1,92 @@
+package container
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/arewm/ai-shell/internal/config"
+)
+
+func TestMountArgs(t *testing.T) {
+	tmpDir, err := os.MkdirTemp("", "ai-shell-mounts-test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() { _ = os.RemoveAll(tmpDir) }()
+
+	src := resolvePath(tmpDir)
+	home := filepath.Join(os.TempDir(), "ai-shell-unused-home")
+	rw := false
+
+	tests := []struct {
+		mount    config.Mount
+		expected string
+	}{
+		{config.Mount{Source: tmpDir, Target: "/data"}, "-v " + src + ":/data:ro"},
+		{config.Mount{Type: "bind", Source: tmpDir, Target: "/data", Options: "rw"}, "-v " + src + ":/data:rw"},
+		{config.Mount{Source: filepath.Join(tmpDir, "missing"), Target: "/data"}, ""},
+		{config.Mount{Type: "volume", Source: "node_modules", Target: "/app/node_modules", Options: "rw"}, "-v node_modules:/app/node_modules:rw"},
+		{config.Mount{Type: "volume", Target: "/cache", Options: "ro,nocopy"}, "--mount type=volume,destination=/cache,ro=true,nocopy"},
+		{config.Mount{Type: "tmpfs", Target: "/scratch", Options: "rw,size=64m"}, "--tmpfs /scratch:rw,size=64m"},
+		{config.Mount{Type: "tmpfs", Target: "/scratch"}, "--tmpfs /scratch"},
+		{config.Mount{Source: tmpDir, Target: "/data", Options: "ro", ReadOnly: &rw, Relabel: "shared"}, "-v " + src + ":/data:rw,z"},
+		{config.Mount{Source: filepath.Join(tmpDir, "created"), Target: "/data", Create: true}, "-v " + filepath.Join(src, "created") + ":/data:ro"},
+	}
+
+	for _, tt := range tests {
+		args, err := mountArgs(tt.mount, defaultProtectedPaths(home), "")
+		if err != nil {
+			t.Errorf("Mount: %+v, Unexpected error: %v", tt.mount, err)
+			continue
+		}
+		if got := strings.Join(args, " "); got != tt.expected {
+			t.Errorf("Mount: %+v, Expected: %q, Got: %q", tt.mount, tt.expected, got)
+		}
+	}
+
+	if _, err := mountArgs(config.Mount{Source: tmpDir, Target: "/data"}, defaultProtectedPaths(tmpDir), ""); err == nil {
+		t.Error("Expected an error when mounting a protected path")
+	}
+	if _, err := mountArgs(config.Mount{Source: filepath.Join(tmpDir, "missing"), Target: "/data", Required: true}, nil, ""); err == nil {
+		t.Error("Expected an error for a missing required source")
+	}
+	// A volume source that expands to a host path would bypass the protected paths
+	t.Setenv("AI_SHELL_TEST_VOLUME", tmpDir)
+	if _, err := mountArgs(config.Mount{Type: "volume", Source: "$AI_SHELL_TEST_VOLUME", Target: "/data"}, nil, ""); err == nil {
+		t.Error("Expected an error for a volume source that is a path")
+	}
+	if _, err := mountArgs(config.Mount{Type: "volume", Source: "ai-home-other-0123456789ab", Target: "/data"}, nil, "ai-home-mine-ba9876543210"); err == nil {
+		t.Error("Expected an error for the home volume of another project")
+	}
+	if _, err := mountArgs(config.Mount{Type: "volume", Source: "ai-home-mine-ba9876543210", Target: "/data"}, nil, "ai-home-mine-ba9876543210"); err != nil {
+		t.Errorf("Expected the project's own home volume to mount, Got: %v", err)
+	}
+	if _, err := mountArgs(config.Mount{Type: "npipe", Target: "/data"}, nil, ""); err == nil {
+		t.Error("Expected an error for an unsupported type")
+	}
+}
+
+func TestMountArgsCreateSymlinkedParent(t *testing.T) {
+	tmpDir := t.TempDir()
+	home := filepath.Join(tmpDir, "home")
+	if err := os.MkdirAll(filepath.Join(home, ".ssh"), 0700); err != nil {
+		t.Fatal(err)
+	}
+	link := filepath.Join(tmpDir, "project", "link")
+	if err := os.MkdirAll(filepath.Dir(link), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Symlink(filepath.Join(home, ".ssh"), link); err != nil {
+		t.Fatal(err)
+	}
+
+	src := filepath.Join(link, "new", "dir")
+	if _, err := mountArgs(config.Mount{Source: src, Target: "/data", Create: true}, defaultProtectedPaths(home), ""); err == nil {
+		t.Errorf("Input: %s, Expected: error, Got: nil", src)
+	}
+	if _, err := os.Stat(filepath.Join(home, ".ssh", "new")); !os.IsNotExist(err) {
+		t.Errorf("Input: %s, Expected: nothing created below .ssh, Got: %v", src, err)
+	}
+}
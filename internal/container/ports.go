@@ -0,0 +1,202 @@
+package container
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"os/exec"
+	"os/signal"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/arewm/ai-shell/internal/config"
+)
+
+// resolvePorts fills in the host port of every port: the requested one if it
+// is available, a free one otherwise.
+func resolvePorts(ports []config.Port, available func(int) bool) ([]config.Port, error) {
+	used := make(map[int]bool)
+	resolved := make([]config.Port, 0, len(ports))
+	for _, p := range ports {
+		host := p.HostPort()
+		if used[host] || !available(host) {
+			free, err := freePort()
+			if err != nil {
+				return nil, fmt.Errorf("no free port for %s: %w", p, err)
+			}
+			host = free
+		}
+		used[host] = true
+		p.Host = host
+		resolved = append(resolved, p)
+	}
+	return resolved, nil
+}
+
+// publishArgs publishes the resolved ports on the host's loopback interface.
+func publishArgs(ports []config.Port) []string {
+	var args []string
+	for _, p := range ports {
+		args = append(args, "-p", fmt.Sprintf("127.0.0.1:%d:%d", p.Host, p.Container))
+	}
+	return args
+}
+
+// printPorts shows where the resolved ports can be reached; requested are
+// the ports as configured.
+func printPorts(resolved, requested []config.Port) {
+	for i, p := range resolved {
+		note := ""
+		if want := requested[i].HostPort(); want != p.Host {
+			note = fmt.Sprintf(" (%d is in use)", want)
+		}
+		fmt.Printf("   Port %s: http://127.0.0.1:%d%s\n", p, p.Host, note)
+	}
+}
+
+// portAvailable reports whether port can be bound on the host's loopback.
+func portAvailable(port int) bool {
+	l, err := net.Listen("tcp", fmt.Sprintf("127.0.0.1:%d", port))
+	if err != nil {
+		return false
+	}
+	_ = l.Close()
+	return true
+}
+
+func freePort() (int, error) {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		return 0, err
+	}
+	defer func() { _ = l.Close() }()
+	return l.Addr().(*net.TCPAddr).Port, nil
+}
+
+// ListPorts writes the published ports of the project's running container,
+// for ai-shell ports. Labels are taken from the config.
+func ListPorts(w io.Writer, cfg *config.Config, profile string) error {
+	info, err := CurrentProject(profile)
+	if err != nil {
+		return err
+	}
+	// With the network audit, the monitor owns the network namespace and the ports
+	var out []byte
+	for _, name := range []string{info.ContainerName, info.ContainerName + "-monitor"} {
+		if exec.Command("podman", "container", "exists", name).Run() != nil { //nolint:gosec
+			continue
+		}
+		if out, err = exec.Command("podman", "port", name).Output(); err != nil { //nolint:gosec
+			return fmt.Errorf("failed to list ports of %s: %w", name, err)
+		}
+		if len(out) > 0 {
+			break
+		}
+	}
+
+	ports := parsePodmanPorts(string(out))
+	if len(ports) == 0 {
+		fmt.Fprintln(w, "No published ports.")
+		return nil
+	}
+	labels := make(map[int]string)
+	if cfg != nil {
+		for _, p := range cfg.Ports {
+			labels[p.Container] = p.Label
+		}
+	}
+	for _, p := range ports {
+		p.Label = labels[p.Container]
+		fmt.Fprintf(w, "%s\thttp://127.0.0.1:%d\n", p, p.Host)
+	}
+	return nil
+}
+
+// parsePodmanPorts reads the TCP ports from podman port output, e.g.
+// "5173/tcp -> 127.0.0.1:5174".
+func parsePodmanPorts(out string) []config.Port {
+	var ports []config.Port
+	s := bufio.NewScanner(strings.NewReader(out))
+	for s.Scan() {
+		ctr, host, ok := strings.Cut(s.Text(), " -> ")
+		if !ok {
+			continue
+		}
+		num, proto, _ := strings.Cut(strings.TrimSpace(ctr), "/")
+		if proto != "" && proto != "tcp" {
+			continue
+		}
+		_, hostPort, err := net.SplitHostPort(strings.TrimSpace(host))
+		if err != nil {
+			continue
+		}
+		c, err1 := strconv.Atoi(num)
+		h, err2 := strconv.Atoi(hostPort)
+		if err1 != nil || err2 != nil {
+			continue
+		}
+		ports = append(ports, config.Port{Container: c, Host: h})
+	}
+	return ports
+}
+
+// ForwardPort forwards a port of the project's running container until
+// interrupted, for ai-shell ports add. Podman cannot publish ports on a
+// running container, so every connection is bridged with socat through
+// podman exec.
+func ForwardPort(profile string, p config.Port) error {
+	info, err := CurrentProject(profile)
+	if err != nil {
+		return err
+	}
+	out, _ := exec.Command("podman", "container", "inspect", "-f", "{{.State.Running}}", info.ContainerName).Output() //nolint:gosec
+	if strings.TrimSpace(string(out)) != "true" {
+		return fmt.Errorf("%s is not running; start it with --reuse first", info.ContainerName)
+	}
+
+	resolved, err := resolvePorts([]config.Port{p}, portAvailable)
+	if err != nil {
+		return err
+	}
+	l, err := net.Listen("tcp", fmt.Sprintf("127.0.0.1:%d", resolved[0].Host))
+	if err != nil {
+		return err
+	}
+	printPorts(resolved, []config.Port{p})
+	fmt.Println("   Forwarding, press Ctrl-C to stop.")
+
+	sig := make(chan os.Signal, 1)
+	signal.Notify(sig, os.Interrupt)
+	defer signal.Stop(sig)
+	go func() {
+		<-sig
+		_ = l.Close()
+	}()
+
+	for {
+		conn, err := l.Accept()
+		if errors.Is(err, net.ErrClosed) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		go bridgePort(info.ContainerName, p.Container, conn)
+	}
+}
+
+func bridgePort(container string, port int, conn net.Conn) {
+	defer func() { _ = conn.Close() }()
+	cmd := exec.Command("podman", "exec", "-i", container, "socat", "-", fmt.Sprintf("TCP:127.0.0.1:%d", port)) //nolint:gosec
+	cmd.Stdin = conn
+	cmd.Stdout = conn
+	// Do not wait for the client to close its side
+	cmd.WaitDelay = time.Second
+	if err := cmd.Run(); err != nil {
+		fmt.Printf("⚠️  Forwarding to port %d failed: %v\n", port, err)
+	}
+}
@@ -0,0 +1,48 @@
+package container
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/arewm/ai-shell/internal/config"
+)
+
+func TestResolvePorts(t *testing.T) {
+	taken := map[int]bool{8888: true}
+	available := func(p int) bool { return !taken[p] }
+
+	ports := []config.Port{
+		{Container: 5173, Label: "Vite"},
+		{Container: 8888},
+		{Container: 9000, Host: 5173}, // Clashes with the first port
+	}
+	resolved, err := resolvePorts(ports, available)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resolved[0].Host != 5173 || resolved[0].Label != "Vite" {
+		t.Errorf("Expected 5173 to be kept, Got: %+v", resolved[0])
+	}
+	for i := 1; i < len(resolved); i++ {
+		if resolved[i].Host == 8888 || resolved[i].Host == 5173 || resolved[i].Host == 0 {
+			t.Errorf("Expected a free port for %d, Got: %d", resolved[i].Container, resolved[i].Host)
+		}
+	}
+
+	args := strings.Join(publishArgs(resolved[:1]), " ")
+	if args != "-p 127.0.0.1:5173:5173" {
+		t.Errorf("Expected loopback publishing, Got: %s", args)
+	}
+}
+
+func TestParsePodmanPorts(t *testing.T) {
+	out := `5173/tcp -> 127.0.0.1:5174
+8888/tcp -> 127.0.0.1:8888
+5353/udp -> 127.0.0.1:5353
+`
+	expected := []config.Port{{Container: 5173, Host: 5174}, {Container: 8888, Host: 8888}}
+	if got := parsePodmanPorts(out); !reflect.DeepEqual(got, expected) {
+		t.Errorf("Expected: %+v, Got: %+v", expected, got)
+	}
+}
@@ -8,6 +8,9 @@ import (
 	"strings"
 )
 
+// homeVolumePrefix starts the names of the home volumes of all projects.
+const homeVolumePrefix = "ai-home-"
+
 type ProjectInfo struct {
 	Hash          string
 	Name          string
@@ -27,7 +30,27 @@ func GetProjectInfo(path string) ProjectInfo {
 	return ProjectInfo{
 		Hash:          hashStr,
 		Name:          projName,
-		VolumeName:    fmt.Sprintf("ai-home-%s-%s", projName, hashStr),
+		VolumeName:    fmt.Sprintf("%s%s-%s", homeVolumePrefix, projName, hashStr),
 		ContainerName: fmt.Sprintf("ai-shell-%s-%s", projName, hashStr),
 	}
 }
+
+// CurrentProject returns the project of the working directory, with the
+// container name of the given profile.
+func CurrentProject(profile string) (ProjectInfo, error) {
+	return ProjectAt(".", profile)
+}
+
+// ProjectAt returns the project of dir, like --project, with the container
+// name of the given profile.
+func ProjectAt(dir, profile string) (ProjectInfo, error) {
+	abs, err := filepath.Abs(dir)
+	if err != nil {
+		return ProjectInfo{}, err
+	}
+	info := GetProjectInfo(abs)
+	if profile != "" && profile != "default" {
+		info.ContainerName = fmt.Sprintf("%s-%s", info.ContainerName, profile)
+	}
+	return info, nil
+}
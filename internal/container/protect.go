@@ -0,0 +1,127 @@
+package container
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/arewm/ai-shell/internal/approval"
+	"github.com/arewm/ai-shell/internal/config"
+)
+
+const (
+	protectDeny     = "deny"
+	protectReadOnly = "readonly"
+)
+
+// defaultProtectedPaths is the built-in deny-list used when the global config
+// does not set protected_paths.
+func defaultProtectedPaths(home string) []config.ProtectedPath {
+	runtimeDir := os.Getenv("XDG_RUNTIME_DIR")
+	if runtimeDir == "" {
+		runtimeDir = fmt.Sprintf("/run/user/%d", os.Getuid())
+	}
+
+	return []config.ProtectedPath{
+		{Path: "/", Exact: true},
+		{Path: home, Exact: true},
+		{Path: filepath.Join(home, ".ssh")},
+		{Path: filepath.Join(home, ".gnupg")},
+		{Path: filepath.Join(runtimeDir, "podman")},
+		{Path: "/run/podman"},
+		{Path: "/var/run/docker.sock"},
+		// The control sockets of approval.RuntimeDir answer as the approver
+		{Path: filepath.Join(runtimeDir, "ai-shell")},
+		{Path: approval.TempBase()},
+		// The trust store decides which project configs are honored
+		{Path: filepath.Join(home, ".local", "share", "ai-shell")},
+		{Path: filepath.Join(home, ".config", "ai-shell"), Action: protectReadOnly},
+	}
+}
+
+// checkProtected reports how a mount of src has to be treated. It returns an
+// empty action if the mount is allowed, otherwise "deny" or "readonly" along
+// with the protected path that was hit. Deny wins over readonly.
+func checkProtected(src string, protected []config.ProtectedPath) (action, hit string) {
+	src = resolvePath(src)
+
+	for _, p := range protected {
+		path := resolvePath(expandHome(os.ExpandEnv(p.Path)))
+		if path == "" {
+			continue
+		}
+
+		// Mounting an ancestor exposes the protected path as well
+		matched := isWithin(path, src)
+		if !p.Exact && isWithin(src, path) {
+			matched = true
+		}
+		if !matched {
+			continue
+		}
+
+		if p.Action == protectReadOnly {
+			if action == "" {
+				action, hit = protectReadOnly, path
+			}
+			continue
+		}
+		return protectDeny, path
+	}
+	return action, hit
+}
+
+// resolvePath makes p absolute and resolves symlinks and ".." elements.
+// For a path that does not exist, the longest existing prefix is resolved
+// and the remaining elements are appended, so a symlinked parent cannot
+// hide where the path will be created.
+func resolvePath(p string) string {
+	if p == "" {
+		return ""
+	}
+	if abs, err := filepath.Abs(p); err == nil {
+		p = abs
+	}
+	p = filepath.Clean(p)
+	rest := ""
+	for dir := p; ; dir = filepath.Dir(dir) {
+		if resolved, err := filepath.EvalSymlinks(dir); err == nil {
+			return filepath.Join(resolved, rest)
+		}
+		if parent := filepath.Dir(dir); parent == dir {
+			return p
+		}
+		rest = filepath.Join(filepath.Base(dir), rest)
+	}
+}
+
+func expandHome(p string) string {
+	if p == "~" || strings.HasPrefix(p, "~/") {
+		if home, err := os.UserHomeDir(); err == nil {
+			return filepath.Join(home, p[1:])
+		}
+	}
+	return p
+}
+
+// isWithin reports whether path is parent or lies below it.
+func isWithin(path, parent string) bool {
+	rel, err := filepath.Rel(parent, path)
+	if err != nil {
+		return false
+	}
+	return rel == "." || (rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator)))
+}
+
+// forceReadOnly rewrites podman volume options so the mount is read-only.
+func forceReadOnly(opts string) string {
+	var res []string
+	for _, o := range strings.Split(opts, ",") {
+		if o == "" || o == "rw" || o == "ro" {
+			continue
+		}
+		res = append(res, o)
+	}
+	return strings.Join(append([]string{"ro"}, res...), ",")
+}
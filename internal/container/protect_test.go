@@ -0,0 +1,87 @@
+package container
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/arewm/ai-shell/internal/approval"
+	"github.com/arewm/ai-shell/internal/config"
+)
+
+func TestCheckProtected(t *testing.T) {
+	tmpDir, err := os.MkdirTemp("", "ai-shell-protect-test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() { _ = os.RemoveAll(tmpDir) }()
+
+	home := filepath.Join(tmpDir, "home")
+	for _, dir := range []string{".ssh", ".kube", ".config/ai-shell", "project"} {
+		if mkErr := os.MkdirAll(filepath.Join(home, dir), 0755); mkErr != nil {
+			t.Fatal(mkErr)
+		}
+	}
+	// A harmless looking link into a protected directory
+	if lnErr := os.Symlink(filepath.Join(home, ".ssh"), filepath.Join(home, "project", "keys")); lnErr != nil {
+		t.Fatal(lnErr)
+	}
+
+	runtimeDir := filepath.Join(tmpDir, "run")
+	t.Setenv("XDG_RUNTIME_DIR", runtimeDir)
+	protected := defaultProtectedPaths(home)
+
+	tests := []struct {
+		src    string
+		action string
+	}{
+		{"/", protectDeny},
+		{home, protectDeny},
+		{filepath.Dir(home), protectDeny},
+		{filepath.Join(home, ".ssh"), protectDeny},
+		{filepath.Join(home, ".ssh", "id_ed25519"), protectDeny},
+		{filepath.Join(home, "project", "keys"), protectDeny},
+		{filepath.Join(home, "project", "..", ".ssh"), protectDeny},
+		{filepath.Join(home, ".config"), protectReadOnly},
+		{filepath.Join(home, ".config", "ai-shell"), protectReadOnly},
+		{filepath.Join(home, ".kube"), ""},
+		{filepath.Join(home, "project"), ""},
+		// The approval control sockets
+		{filepath.Join(runtimeDir, "ai-shell"), protectDeny},
+		{filepath.Dir(approval.RuntimeDir("proj")), protectDeny},
+		{approval.TempBase(), protectDeny},
+		{filepath.Join(runtimeDir, "other"), ""},
+	}
+
+	for _, tt := range tests {
+		action, _ := checkProtected(tt.src, protected)
+		if action != tt.action {
+			t.Errorf("Source: %s, Expected action: %q, Got: %q", tt.src, tt.action, action)
+		}
+	}
+
+	// A global override replaces the built-in list
+	custom := []config.ProtectedPath{{Path: filepath.Join(home, ".kube"), Action: protectReadOnly}}
+	if action, _ := checkProtected(filepath.Join(home, ".ssh"), custom); action != "" {
+		t.Errorf("Expected .ssh to be allowed with a custom list, got %q", action)
+	}
+	if action, _ := checkProtected(filepath.Join(home, ".kube", "config"), custom); action != protectReadOnly {
+		t.Errorf("Expected .kube/config to be read-only, got %q", action)
+	}
+}
+
+func TestForceReadOnly(t *testing.T) {
+	tests := map[string]string{
+		"":      "ro",
+		"ro":    "ro",
+		"rw":    "ro",
+		"rw,z":  "ro,z",
+		"Z,rw":  "ro,Z",
+		"ro,rw": "ro",
+	}
+	for in, expected := range tests {
+		if got := forceReadOnly(in); got != expected {
+			t.Errorf("forceReadOnly(%q): Expected %q, Got %q", in, expected, got)
+		}
+	}
+}
@@ -0,0 +1,66 @@
+package container
+
+import (
+	"os"
+	"regexp"
+	"strings"
+
+	"github.com/arewm/ai-shell/internal/config"
+)
+
+// minSecretLen keeps short values (e.g. "1" or "true") out of the redaction,
+// which would mangle the whole recording.
+const minSecretLen = 4
+
+// secretNamePattern matches the names of variables whose values are redacted
+// from recordings.
+var secretNamePattern = regexp.MustCompile(`(?i)TOKEN|SECRET|PASSWORD|PASSWD|API_KEY|ACCESS_KEY|CREDENTIAL`)
+
+// secretValues returns the values of the secret variables passed to the
+// container: those named like secrets, the tokens of registries and SCMs,
+// and the credentials of the proxy.
+func secretValues(cfg *config.Config) []string {
+	names := config.DefaultEnvVars
+	tokens := make(map[string]bool)
+	var values []string
+	if cfg != nil {
+		var passed []string
+		for _, v := range cfg.EnvVars {
+			if k, val, ok := strings.Cut(v, "="); ok {
+				if secretNamePattern.MatchString(k) && len(val) >= minSecretLen {
+					values = append(values, os.ExpandEnv(val))
+				}
+			} else {
+				passed = append(passed, v)
+			}
+		}
+		if len(passed) > 0 {
+			names = passed
+		}
+		for _, r := range cfg.Registries {
+			tokens[r.TokenEnv] = true
+		}
+		for _, s := range cfg.SCMs {
+			tokens[s.TokenEnv] = true
+		}
+		p := cfg.Proxy.Resolve(os.Getenv)
+		for _, s := range []string{p.HTTP, p.HTTPS} {
+			u, err := config.ParseProxyURL(s)
+			if err != nil || u.User == nil {
+				continue
+			}
+			if pass, ok := u.User.Password(); ok && len(pass) >= minSecretLen {
+				values = append(values, pass, u.User.String())
+			}
+		}
+	}
+	for _, name := range names {
+		if !tokens[name] && !secretNamePattern.MatchString(name) {
+			continue
+		}
+		if val := os.Getenv(name); len(val) >= minSecretLen {
+			values = append(values, val)
+		}
+	}
+	return values
+}
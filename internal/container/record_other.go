@@ -0,0 +1,14 @@
+//go:build !linux && !darwin
+
+package container
+
+import (
+	"fmt"
+	"runtime"
+)
+
+// recordPodman runs podman without a recording, which needs a Unix terminal.
+func recordPodman(_ string, _ []string, args ...string) error {
+	fmt.Printf("⚠️  Recording is not supported on %s, the session is not recorded.\n", runtime.GOOS)
+	return execPodman(args...)
+}
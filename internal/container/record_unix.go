@@ -0,0 +1,92 @@
+//go:build linux || darwin
+
+package container
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"os/signal"
+	"syscall"
+
+	"golang.org/x/sys/unix"
+
+	"github.com/arewm/ai-shell/internal/session"
+)
+
+// recordPodman runs podman on a pseudo-terminal of its own and records what
+// it shows to path, in asciicast v2 format, while passing it through to the
+// user's terminal.
+func recordPodman(path string, secrets []string, args ...string) error {
+	stdin := int(os.Stdin.Fd())
+	if !isTerminal(stdin) {
+		fmt.Println("⚠️  Not a terminal, the session is not recorded.")
+		return execPodman(args...)
+	}
+	cols, rows, err := termSize(stdin)
+	if err != nil {
+		return err
+	}
+
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_EXCL|os.O_WRONLY, 0600) //nolint:gosec
+	if err != nil {
+		return fmt.Errorf("failed to create the recording: %w", err)
+	}
+	defer func() { _ = f.Close() }()
+	cast, err := session.NewCastWriter(f, session.CastHeader{
+		Width:  cols,
+		Height: rows,
+		Title:  "ai-shell",
+		Env:    map[string]string{"SHELL": "/bin/zsh", "TERM": os.Getenv("TERM")},
+	}, secrets)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = cast.Close() }()
+
+	master, slave, err := openPTY()
+	if err != nil {
+		return fmt.Errorf("failed to open a terminal for the recording: %w", err)
+	}
+	defer func() { _ = master.Close() }()
+	if err := setTermSize(int(master.Fd()), cols, rows); err != nil {
+		_ = slave.Close()
+		return err
+	}
+
+	cmd := exec.Command("podman", args...)
+	cmd.Stdin, cmd.Stdout, cmd.Stderr = slave, slave, slave
+	cmd.SysProcAttr = &syscall.SysProcAttr{Setsid: true, Setctty: true}
+	err = cmd.Start()
+	_ = slave.Close()
+	if err != nil {
+		return err
+	}
+
+	restore, err := makeRaw(stdin)
+	if err != nil {
+		_ = cmd.Process.Kill()
+		_ = cmd.Wait()
+		return err
+	}
+	defer restore()
+
+	// Terminal size changes go to podman and the recording
+	winch := make(chan os.Signal, 1)
+	signal.Notify(winch, unix.SIGWINCH)
+	defer signal.Stop(winch)
+	go func() {
+		for range winch {
+			if cols, rows, err := termSize(stdin); err == nil {
+				_ = setTermSize(int(master.Fd()), cols, rows)
+				cast.Resize(cols, rows)
+			}
+		}
+	}()
+
+	go func() { _, _ = io.Copy(master, os.Stdin) }()
+	// Reading fails once podman exits and the terminal is closed
+	_, _ = io.Copy(io.MultiWriter(os.Stdout, cast), master)
+	return cmd.Wait()
+}
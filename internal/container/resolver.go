@@ -0,0 +1,70 @@
+package container
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/arewm/ai-shell/internal/config"
+)
+
+// resolverArgs returns the nameserver and /etc/hosts arguments of the
+// config, for the owner of a network namespace.
+func resolverArgs(cfg *config.Config) []string {
+	if cfg == nil {
+		return nil
+	}
+	var args []string
+	for _, d := range cfg.DNS {
+		args = append(args, "--dns", d)
+	}
+	for _, h := range cfg.ExtraHosts {
+		args = append(args, "--add-host", h)
+	}
+	return args
+}
+
+// proxyEnvFile writes the configured proxy for every tool in the container
+// to an env file, and returns its path ("" without a proxy). The URLs may
+// carry credentials, so they stay off the podman command line, in a file
+// only the user can read; the caller removes it.
+func proxyEnvFile(cfg *config.Config) (string, error) {
+	if cfg == nil {
+		return "", nil
+	}
+	env := cfg.Proxy.Resolve(os.Getenv).Env()
+	if len(env) == 0 {
+		return "", nil
+	}
+	f, err := os.CreateTemp("", "ai-shell-proxy-*.env")
+	if err != nil {
+		return "", err
+	}
+	_, err = f.WriteString(strings.Join(env, "\n") + "\n")
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		_ = os.Remove(f.Name())
+		return "", err
+	}
+	return f.Name(), nil
+}
+
+// caBundle concatenates the configured CA certificates into one PEM file.
+func caBundle(paths []string) ([]byte, error) {
+	var b bytes.Buffer
+	for _, p := range paths {
+		data, err := os.ReadFile(expandHome(os.ExpandEnv(p))) //nolint:gosec
+		if err != nil {
+			return nil, fmt.Errorf("ca_certs: %w", err)
+		}
+		if !bytes.Contains(data, []byte("-----BEGIN CERTIFICATE-----")) {
+			return nil, fmt.Errorf("ca_certs: %s is not a PEM certificate", p)
+		}
+		b.Write(bytes.TrimSpace(data))
+		b.WriteByte('\n')
+	}
+	return b.Bytes(), nil
+}
@@ -0,0 +1,42 @@
+package container
+
+import (
+	"fmt"
+	"os/exec"
+	"strings"
+)
+
+// ensureHomeVolume creates the home volume if it does not exist yet. The
+// quota only applies at creation, and podman only supports it on XFS with
+// project quotas; elsewhere the volume is created without one. An existing
+// volume without the quota is kept, with a warning.
+func ensureHomeVolume(name, size string) {
+	if exec.Command("podman", "volume", "exists", name).Run() == nil { //nolint:gosec
+		if size == "" {
+			return
+		}
+		out, err := exec.Command("podman", "volume", "inspect", "--format", `{{index .Options "o"}}`, name).Output() //nolint:gosec
+		if err == nil && !hasSizeOption(string(out), size) {
+			fmt.Printf("⚠️  The home volume %s has no quota of %s, which only applies when it is created. Remove it with 'podman volume rm %s' to apply it.\n", name, size, name)
+		}
+		return
+	}
+	if size != "" {
+		out, err := exec.Command("podman", "volume", "create", "--opt", "o=size="+size, name).CombinedOutput() //nolint:gosec
+		if err == nil {
+			return
+		}
+		fmt.Printf("⚠️  Cannot limit the home volume to %s (%s); creating it without a quota.\n", size, strings.TrimSpace(string(out)))
+	}
+	_ = exec.Command("podman", "volume", "create", name).Run() //nolint:gosec
+}
+
+// hasSizeOption reports whether the mount options of a volume set size.
+func hasSizeOption(opts, size string) bool {
+	for _, o := range strings.Split(strings.TrimSpace(opts), ",") {
+		if o == "size="+size {
+			return true
+		}
+	}
+	return false
+}
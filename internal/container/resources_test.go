@@ -0,0 +1,21 @@
+package container
+
+import "testing"
+
+func TestHasSizeOption(t *testing.T) {
+	tests := []struct {
+		opts     string
+		expected bool
+	}{
+		{"size=10g\n", true},
+		{"nodev,size=10g", true},
+		{"size=20g", false},
+		{"<no value>\n", false},
+		{"", false},
+	}
+	for _, tt := range tests {
+		if got := hasSizeOption(tt.opts, "10g"); got != tt.expected {
+			t.Errorf("Input: %q, Expected: %v, Got: %v", tt.opts, tt.expected, got)
+		}
+	}
+}
@@ -10,6 +10,7 @@ import (
 	"strings"
 
 	"github.com/arewm/ai-shell/internal/config"
+	"github.com/arewm/ai-shell/internal/session"
 )
 
 type RunOptions struct {
@@ -19,8 +20,13 @@ type RunOptions struct {
 	MountSSH   bool
 	Config     *config.Config
 	ConfigPath string
-	ImageName  string
-	Profile    string
+	// ImageName is the image of Profile, see ProfileImage
+	ImageName string
+	Profile   string
+	// Offline cuts the container off the network, see config.Config.Offline
+	Offline bool
+	// Record records the session, see config.Config.Record
+	Record bool
 }
 
 func Run(opts RunOptions) error {
@@ -32,16 +38,76 @@ func Run(opts RunOptions) error {
 	// 1. Get Project Info
 	info := GetProjectInfo(pwd)
 	
+	// Flags can only enable what the config does not already default to
+	if opts.Config != nil {
+		opts.MountSSH = opts.MountSSH || opts.Config.SSH
+		opts.NetHost = opts.NetHost || opts.Config.NetHost
+		opts.Offline = opts.Offline || opts.Config.Offline
+		opts.Record = opts.Record || opts.Config.Record
+	}
+	profile := opts.Config.EffectiveProfile(opts.Profile)
+	// A profile from the config selects its image, like --profile does
+	if (opts.Profile == "" && profile != "") || opts.ImageName == "" {
+		opts.ImageName = ProfileImage(profile)
+	}
+	// Offline mode with model egress reuses the egress proxy, with only the model APIs allowed
+	modelEgress := opts.Offline && opts.Config != nil && opts.Config.ModelEgress
+	filtered := modelEgress || (!opts.Offline && opts.Config != nil && opts.Config.Network.Filtered())
+	if opts.NetHost && (filtered || opts.Offline) {
+		return fmt.Errorf("--net-host cannot be combined with network.allow or offline mode")
+	}
+	// The audit watches the project's own network namespace, which it does not have
+	// with the host network, and there is nothing to watch without a network
+	audit := opts.Config != nil && opts.Config.Network.Audit
+	if audit && opts.NetHost {
+		fmt.Println("⚠️  Host networking is enabled, the network audit is disabled.")
+		audit = false
+	}
+	audit = audit && (!opts.Offline || modelEgress)
+
+	// Network logs and recordings of this session
+	var sessionDir string
+	newSession := func() error {
+		if sessionDir != "" {
+			return nil
+		}
+		sessionDir, err = session.New(info.ContainerName)
+		return err
+	}
+	// attach runs podman in the terminal, recording the session if asked
+	attach := func(args ...string) error {
+		if !opts.Record {
+			return execPodman(args...)
+		}
+		if err := newSession(); err != nil {
+			return err
+		}
+		cast := filepath.Join(sessionDir, session.CastFile)
+		fmt.Printf("   Recording to %s\n", cast)
+		return recordPodman(cast, secretValues(opts.Config), args...)
+	}
+
 	// Append Profile to Container Name to avoid conflicts
-	if opts.Profile != "" && opts.Profile != "default" {
-		info.ContainerName = fmt.Sprintf("%s-%s", info.ContainerName, opts.Profile)
+	if profile != "" && profile != "default" {
+		info.ContainerName = fmt.Sprintf("%s-%s", info.ContainerName, profile)
+	}
+
+	spec := newRunSpec(opts.Config, pwd)
+	netMode := networkMode(opts, filtered, modelEgress)
+
+	var lifecycle config.Lifecycle
+	if opts.Config != nil {
+		lifecycle = opts.Config.Lifecycle
 	}
 
 	// 2. Reuse Logic
 	if opts.Reuse {
 		// Check if container exists
 		checkCmd := exec.Command("podman", "container", "exists", info.ContainerName) //nolint:gosec
-		if err := checkCmd.Run(); err == nil {
+		if err := checkCmd.Run(); err == nil && containerNetworkMode(info.ContainerName) != netMode {
+			// Reusing it would keep the network it was created with
+			fmt.Printf("   The existing container does not run in %s network mode, recreating it...\n", netMode)
+		} else if err == nil {
 			// Check if running
 			inspectCmd := exec.Command("podman", "container", "inspect", "-f", "{{.State.Running}}", info.ContainerName) //nolint:gosec
 			out, _ := inspectCmd.Output()
@@ -49,47 +115,299 @@ func Run(opts RunOptions) error {
 
 			if isRunning {
 				fmt.Println("   Reusing running container...")
-				// Must explicitly set user 'ai' because container starts as root
-				return execPodman("exec", "-it", "--user", "ai", info.ContainerName, "zsh")
+				if len(lifecycle.PostAttach) > 0 {
+					if err := execPodman("exec", "--user", spec.User, info.ContainerName, "/usr/local/bin/lifecycle.sh", "attach"); err != nil {
+						fmt.Printf("⚠️  postAttachCommand failed: %v\n", err)
+					}
+				}
+				// Must explicitly set the user because container starts as root
+				execArgs := []string{"exec", "-it", "--user", spec.User, "-w", spec.WorkDir}
+				if opts.Config != nil && opts.Config.Security.AtLeast(config.SecurityParanoid) {
+					// The read-only root keeps the image's home in /etc/passwd
+					execArgs = append(execArgs, "-e", "HOME="+config.HomeTarget())
+				}
+				return attach(append(execArgs, info.ContainerName, "zsh")...)
+			}
+			// A new session needs a new monitor, and so a new container
+			if !audit {
+				fmt.Println("   Restarting existing container...")
+				if err := runHostCommands("initializeCommand", lifecycle.Initialize, pwd); err != nil {
+					return err
+				}
+				if filtered {
+					proxy := newEgressProxy(info)
+					if err := proxy.Start(opts); err != nil {
+						return err
+					}
+					defer proxy.Stop()
+				}
+				if opts.Config != nil && opts.Config.Approval.Enabled() {
+					gate, _, err := startApproval(info, opts.Config, spec.WorkspaceMount.Target, false)
+					if err != nil {
+						return err
+					}
+					defer gate.Close()
+				}
+				if opts.Config != nil && opts.Config.CommandAudit {
+					if err := newSession(); err != nil {
+						return err
+					}
+					collector, _, err := startCommandAudit(info, sessionDir, false)
+					if err != nil {
+						return err
+					}
+					defer collector.Close()
+				}
+				return attach("start", "-ai", info.ContainerName)
 			}
-			fmt.Println("   Restarting existing container...")
-			return execPodman("start", "-ai", info.ContainerName)
 		}
 	}
 
 	// 3. Cleanup Old
 	_ = exec.Command("podman", "rm", "-f", info.ContainerName).Run() //nolint:gosec
 
+	// initializeCommand runs on the host before anything is created
+	if err := runHostCommands("initializeCommand", lifecycle.Initialize, pwd); err != nil {
+		return err
+	}
+
+	// Project image, entrypoint adaptation and devcontainer features
+	image, err := resolveImage(opts, info, spec.User)
+	if err != nil {
+		return err
+	}
+
 	// 4. Ensure Volume
-	_ = exec.Command("podman", "volume", "create", info.VolumeName).Run() //nolint:gosec
+	ensureHomeVolume(info.VolumeName, spec.Resources.HomeSize)
 
 	// 5. Construct Flags
 	// We must start as root (0:0) to allow configure.sh to setup paths/permissions.
-	// The entrypoint will drop privileges to 'ai'.
-	args := []string{"run", "-it", "--rm", "--user", "0:0", "--name", info.ContainerName, "--hostname", "ai-box", "--security-opt", "label=disable", "--userns=keep-id"}
+	// The entrypoint will drop privileges to 'ai' (or spec.User).
+	var security config.Security
+	if opts.Config != nil {
+		security = opts.Config.Security
+	}
+	args := []string{"run", "-it", "--rm", "--user", "0:0", "--name", info.ContainerName, userNSArg(security.Level, image, spec.User)}
+	args = append(args, "--label", networkModeLabel+"="+netMode)
+	// SELinux: separate the container where the host enforces labels, and
+	// relabel each mount deliberately below
+	labeling := selinuxLabeling(opts.Config)
+	if !labeling {
+		args = append(args, "--security-opt", "label=disable")
+	} else if opts.Verbose {
+		fmt.Println("   SELinux separation: enabled")
+	}
+	args = append(args, security.PodmanArgs()...)
+	args = append(args, spec.Resources.PodmanArgs(security.AtLeast(config.SecurityParanoid))...)
+	args = append(args, sandboxArgs(opts.Config)...)
+	// Approval: the shims ask the server on the host, which lives as long as
+	// this process
+	if opts.Config != nil && opts.Config.Approval.Enabled() {
+		gate, gateArgs, err := startApproval(info, opts.Config, spec.WorkspaceMount.Target, labeling)
+		if err != nil {
+			return err
+		}
+		defer gate.Close()
+		args = append(args, gateArgs...)
+		if opts.Verbose {
+			fmt.Printf("   Approval: %s wait for ai-shell approve\n", strings.Join(opts.Config.Approval.Binaries(), ", "))
+		}
+	}
+	// Command audit: the shell hooks write to a spool the collector reads
+	if opts.Config != nil && opts.Config.CommandAudit {
+		if err := newSession(); err != nil {
+			return err
+		}
+		collector, auditArgs, err := startCommandAudit(info, sessionDir, labeling)
+		if err != nil {
+			return err
+		}
+		defer collector.Close()
+		args = append(args, auditArgs...)
+		fmt.Printf("   Command audit: logging to %s\n", collector.Log)
+	}
+	// seccomp: podman's default profile without the syscalls agents never need
+	seccompPath, err := writeSeccompProfile(opts.Config)
+	if err != nil {
+		return err
+	}
+	if seccompPath != "" {
+		defer os.Remove(seccompPath)
+		args = append(args, "--security-opt", "seccomp="+seccompPath)
+		if security.Seccomp.Audit {
+			fmt.Println("   seccomp audit mode: blocked syscalls are logged to the kernel audit log, not denied.")
+		}
+	} else if opts.Verbose && !security.Seccomp.Disable {
+		fmt.Println("   No podman seccomp profile on this host, using podman's default.")
+	}
+	if opts.Verbose && security.AtLeast(config.SecurityStrict) {
+		fmt.Printf("   Security level: %s\n", security.EffectiveLevel())
+	}
+	// Network namespace: given to the monitor instead when auditing
+	netArgs := []string{"--hostname", "ai-box"}
+
+	if audit {
+		if err := newSession(); err != nil {
+			return err
+		}
+	}
 
 	if opts.NetHost {
-		args = append(args, "--network=host")
+		netArgs = append(netArgs, "--network=host")
+	}
+
+	// Filtered egress: an internal network whose only way out is the proxy
+	if filtered {
+		proxy := newEgressProxy(info)
+		if audit {
+			proxy.LogFile = filepath.Join(sessionDir, session.ProxyLog)
+			proxy.LogAll = true
+		}
+		if err := proxy.Start(opts); err != nil {
+			return err
+		}
+		defer proxy.Stop()
+		netArgs = append(netArgs, proxy.NetworkArgs()...)
+		args = append(args, proxy.EnvArgs()...)
+		if modelEgress {
+			fmt.Println("   Offline mode: only the model APIs are reachable.")
+		}
+		if !audit {
+			fmt.Printf("   Egress is filtered, denied requests are logged to %s\n", proxy.LogFile)
+		}
+	} else if opts.Offline {
+		netArgs = append(netArgs, "--network=none")
+		fmt.Println("   Offline mode: no network access.")
+	}
+	if opts.Offline {
+		// configure.sh skips registry logins and SCM setup
+		args = append(args, "-e", "AI_SHELL_OFFLINE=1")
+	} else if !filtered {
+		// With filtering, the egress proxy forwards to the configured proxy instead
+		envFile, err := proxyEnvFile(opts.Config)
+		if err != nil {
+			return fmt.Errorf("failed to write the proxy environment: %w", err)
+		}
+		if envFile != "" {
+			defer os.Remove(envFile)
+			args = append(args, "--env-file", envFile)
+		}
+	}
+
+	// Host ports: forward selected ports instead of sharing the host network
+	var kubeArgs []string
+	if opts.Config != nil && len(opts.Config.HostPorts) > 0 {
+		switch {
+		case opts.NetHost:
+			fmt.Println("   Host networking is enabled, ignoring host_ports.")
+		case opts.Offline:
+			fmt.Println("   Offline mode, ignoring host_ports.")
+		case filtered:
+			return fmt.Errorf("host_ports cannot be combined with network.allow")
+		default:
+			netArgs = append(netArgs, hostPortArgs(runtime.GOOS, opts.Config.HostPorts)...)
+			if runtime.GOOS == "darwin" {
+				tmpDir, err := os.MkdirTemp("", "ai-shell-kube-*")
+				if err != nil {
+					return err
+				}
+				defer func() { _ = os.RemoveAll(tmpDir) }()
+				if kubeArgs, err = kubeconfigArgs(opts.Config.HostPorts, tmpDir); err != nil {
+					return err
+				}
+			}
+		}
+	}
+
+	// Ports: published by the owner of the network namespace
+	if opts.Config != nil && len(opts.Config.Ports) > 0 {
+		switch {
+		case opts.NetHost:
+			fmt.Println("   Host networking is enabled, ports are reachable without publishing.")
+		case opts.Offline && !modelEgress:
+			fmt.Println("   Offline mode, ignoring ports.")
+		default:
+			ports, err := resolvePorts(opts.Config.Ports, portAvailable)
+			if err != nil {
+				return err
+			}
+			netArgs = append(netArgs, publishArgs(ports)...)
+			printPorts(ports, opts.Config.Ports)
+		}
+	}
+
+	// Nameservers & extra hosts: set by the owner of the network namespace
+	if resolver := resolverArgs(opts.Config); len(resolver) > 0 {
+		switch {
+		case opts.NetHost:
+			fmt.Println("   Host networking is enabled, ignoring dns and extra_hosts.")
+		case opts.Offline && !modelEgress:
+		default:
+			netArgs = append(netArgs, resolver...)
+		}
+	}
+
+	// Network audit: the monitor owns the network namespace and logs what
+	// happens in it
+	if audit {
+		monitor := newNetMonitor(info, sessionDir)
+		monitor.Relabel = labeling
+		if err := monitor.Start(netArgs, opts.Verbose); err != nil {
+			return err
+		}
+		defer monitor.Stop()
+		args = append(args, monitor.RunArgs()...)
+		fmt.Printf("   Network audit: logging to %s\n", sessionDir)
+	} else {
+		args = append(args, netArgs...)
 	}
 
 	// Mounts
 	home, _ := os.UserHomeDir()
-	hostHomeRoot := "/home"
-	if runtime.GOOS == "darwin" {
-		hostHomeRoot = "/Users"
-	}
+	hostHomeRoot := config.HostHomeRoot()
 	user := os.Getenv("USER")
-	targetHome := fmt.Sprintf("%s/%s", hostHomeRoot, user)
-    fmt.Printf("DEBUG: hostHomeRoot=%s, targetHome=%s\n", hostHomeRoot, targetHome)
+	targetHome := config.HomeTarget()
+
+	protected := defaultProtectedPaths(home)
+	if opts.Config != nil && len(opts.Config.ProtectedPaths) > 0 {
+		protected = opts.Config.ProtectedPaths
+	}
 
 	// Runtime Path Info & Standard Mounts
 	args = append(args,
 		"-e", fmt.Sprintf("HOST_USER=%s", user),
 		"-e", fmt.Sprintf("HOST_HOME_ROOT=%s", hostHomeRoot),
-		"-v", fmt.Sprintf("%s:%s", pwd, pwd),
-		"-w", pwd,
-		"-v", fmt.Sprintf("%s:%s", info.VolumeName, targetHome),
-		"-v", fmt.Sprintf("%s:%s:ro", filepath.Join(home, ".gitconfig"), "/etc/ai-shell/gitconfig.host"),
+		"-e", fmt.Sprintf("AI_SHELL_USER=%s", spec.User),
+		// sandbox-exec keeps the whole workspace writable
+		"-e", fmt.Sprintf("AI_SHELL_WORKSPACE=%s", spec.WorkspaceMount.Target),
+	)
+
+	// Workspace: mirrored unless the config mounts it elsewhere. It is shared
+	// with the other containers of the project (profiles).
+	if labeling && spec.WorkspaceMount.Type != config.MountTypeVolume && unlabelablePath(os.ExpandEnv(spec.WorkspaceMount.Source), home) {
+		return fmt.Errorf("cannot relabel %s for SELinux; run ai-shell in a project directory or set security.label_disable", spec.WorkspaceMount.Source)
+	}
+	if opts.Config != nil && opts.Config.WorkspaceMount != nil {
+		ws := spec.WorkspaceMount
+		if labeling && ws.Relabel == "" && ws.Type != config.MountTypeTmpfs {
+			ws.Relabel = config.RelabelShared
+		}
+		wsArgs, err := mountArgs(ws, protected, info.VolumeName)
+		if err != nil {
+			return fmt.Errorf("workspace mount: %w", err)
+		}
+		args = append(args, wsArgs...)
+	} else {
+		args = append(args, "-v", volumeArg(spec.WorkspaceMount.Source, spec.WorkspaceMount.Target, relabel("", labeling, false)))
+	}
+
+	// The home volume is shared with the other profiles of the project, like
+	// the workspace, and so is the host git config. :Z would relabel it away
+	// from a profile that is already running (see the README on SELinux)
+	args = append(args,
+		"-w", spec.WorkDir,
+		"-v", volumeArg(info.VolumeName, targetHome, relabel("", labeling, false)),
+		"-v", volumeArg(filepath.Join(home, ".gitconfig"), config.GitConfigTarget, relabel("ro", labeling, false)),
 	)
 
 	// Config Mounts (Merged)
@@ -103,17 +421,52 @@ func Run(opts RunOptions) error {
 				defer os.Remove(tmpConfig.Name())
 				if _, err := tmpConfig.Write(configData); err == nil {
 					tmpConfig.Close()
-					args = append(args, "-v", fmt.Sprintf("%s:/etc/ai-shell/config.yaml:ro", tmpConfig.Name()))
+					args = append(args, "-v", volumeArg(tmpConfig.Name(), config.ConfigTarget, relabel("ro", labeling, true)))
 				}
 			}
 		}
 	}
 
-	// Helper to add if exists
-	addMount := func(src, target, opts string) {
-		if _, err := os.Stat(src); err == nil {
-			args = append(args, "-v", fmt.Sprintf("%s:%s:%s", src, target, opts))
+	// CA certificates: configure.sh adds them to the trust store, Node.js
+	// does not use it
+	if opts.Config != nil && len(opts.Config.CACerts) > 0 {
+		bundle, err := caBundle(opts.Config.CACerts)
+		if err != nil {
+			return err
+		}
+		tmpCA, err := os.CreateTemp("", "ai-shell-ca-*.pem")
+		if err != nil {
+			return err
+		}
+		defer os.Remove(tmpCA.Name())
+		_, err = tmpCA.Write(bundle)
+		tmpCA.Close()
+		if err != nil {
+			return err
+		}
+		args = append(args,
+			"-v", volumeArg(tmpCA.Name(), config.CACertsTarget, relabel("ro", labeling, true)),
+			"-e", "NODE_EXTRA_CA_CERTS="+config.CACertsTarget,
+		)
+	}
+
+	// Helper to add if exists, a config mount of the same target wins
+	addMount := func(src, target, mountOpts string) {
+		if hasMountTarget(opts.Config, target) {
+			if opts.Verbose {
+				fmt.Printf("   Using the configured mount for %s\n", target)
+			}
+			return
+		}
+		if _, err := os.Stat(src); err != nil {
+			return
 		}
+		// Host directories are shared, except those that must keep their labels
+		if labeling && unlabelablePath(src, home) {
+			fmt.Printf("⚠️  Not mounting %s: relabeling it for SELinux would break the host. Set security.label_disable to mount it.\n", src)
+			return
+		}
+		args = append(args, "-v", volumeArg(src, target, relabel(mountOpts, labeling, false)))
 	}
 
 	addMount(filepath.Join(home, ".config", "gcloud"), fmt.Sprintf("%s/.config/gcloud", targetHome), "ro")
@@ -126,25 +479,34 @@ func Run(opts RunOptions) error {
 	// Custom Mounts from Config
 	if opts.Config != nil {
 		for _, m := range opts.Config.Mounts {
-			src := os.ExpandEnv(m.Source)
-			tgt := os.ExpandEnv(m.Target)
-			opt := m.Options
-			if opt == "" {
-				opt = "ro"
+			if labeling && (m.Type == "" || m.Type == config.MountTypeBind) && m.Relabel == "" {
+				fmt.Printf("⚠️  SELinux is enabled and mount %s has no relabel option, the container may not be able to access it.\n", m.Target)
 			}
-			if _, err := os.Stat(src); err == nil {
-				args = append(args, "-v", fmt.Sprintf("%s:%s:%s", src, tgt, opt))
+			mArgs, err := mountArgs(m, protected, info.VolumeName)
+			if err != nil {
+				return err
 			}
+			args = append(args, mArgs...)
 		}
 		// Custom Args
 		args = append(args, opts.Config.PodmanArgs...)
 	}
 
 	// Env Vars
-	defaultVars := []string{"CLAUDE_CODE_USE_VERTEX", "CLOUD_ML_REGION", "ANTHROPIC_VERTEX_PROJECT_ID", "GOOGLE_CLOUD_PROJECT", "GEMINI_API_KEY", "GH_TOKEN"}
-	varsToPass := defaultVars
-	if opts.Config != nil && len(opts.Config.EnvVars) > 0 {
-		varsToPass = opts.Config.EnvVars
+	varsToPass := config.DefaultEnvVars
+	if opts.Config != nil {
+		// NAME=value entries are always set, plain names replace the default pass-through list
+		var names []string
+		for _, v := range opts.Config.EnvVars {
+			if strings.Contains(v, "=") {
+				args = append(args, "-e", v)
+			} else {
+				names = append(names, v)
+			}
+		}
+		if len(names) > 0 {
+			varsToPass = names
+		}
 	}
 
 	for _, v := range varsToPass {
@@ -153,15 +515,24 @@ func Run(opts RunOptions) error {
 		}
 	}
 
-	args = append(args, opts.ImageName, "zsh")
+	// After the env vars, so that the rewritten kubeconfig wins
+	args = append(args, kubeArgs...)
+
+	args = append(args, image, "zsh")
 
 	if opts.Verbose {
 		fmt.Printf("   Project: %s\n", pwd)
+		if spec.WorkDir != pwd {
+			fmt.Printf("   Workspace: %s\n", spec.WorkDir)
+		}
 		fmt.Printf("   Persistence Volume: %s\n", info.VolumeName)
+		if limits := spec.Resources.PodmanArgs(false); len(limits) > 0 {
+			fmt.Printf("   Resource limits: %s\n", strings.Join(limits, " "))
+		}
 		fmt.Printf("   OS: %s (Home Root: %s)\n", runtime.GOOS, hostHomeRoot)
 	}
 
-	return execPodman(args...)
+	return attach(args...)
 }
 
 func execPodman(args ...string) error {
@@ -171,3 +542,16 @@ func execPodman(args ...string) error {
 	cmd.Stderr = os.Stderr
 	return cmd.Run()
 }
+
+// hasMountTarget reports whether cfg mounts something at target.
+func hasMountTarget(cfg *config.Config, target string) bool {
+	if cfg == nil {
+		return false
+	}
+	for _, m := range cfg.Mounts {
+		if filepath.Clean(os.ExpandEnv(m.Target)) == filepath.Clean(target) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,80 @@
+package container
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"strings"
+
+	"github.com/arewm/ai-shell/internal/config"
+)
+
+// sandboxExecPath is the bubblewrap wrapper in ai-shell's images.
+const sandboxExecPath = "/usr/local/bin/sandbox-exec"
+
+// sandboxSyscalls are what bwrap needs to set up its mount namespace. The
+// seccomp profile blocks them otherwise; without CAP_SYS_ADMIN they only
+// work in the nested user namespace bwrap creates.
+var sandboxSyscalls = []string{"mount", "umount2", "pivot_root"}
+
+// sandboxUnmaskPaths are podman's masked and read-only paths in /proc. The
+// kernel only lets bwrap mount a new /proc for its PID namespace if no part
+// of the container's is hidden by a mount, so all of them are unmasked in
+// the container; sandbox-exec masks them again in the sandbox. The masks of
+// /sys stay.
+var sandboxUnmaskPaths = []string{
+	"/proc/acpi",
+	"/proc/kcore",
+	"/proc/keys",
+	"/proc/latency_stats",
+	"/proc/sched_debug",
+	"/proc/scsi",
+	"/proc/timer_list",
+	"/proc/timer_stats",
+	"/proc/asound",
+	"/proc/bus",
+	"/proc/fs",
+	"/proc/irq",
+	"/proc/sys",
+	"/proc/sysrq-trigger",
+}
+
+// sandboxArgs returns the podman arguments the bubblewrap sandbox needs.
+func sandboxArgs(cfg *config.Config) []string {
+	if cfg == nil || !cfg.Sandbox.Enabled() {
+		return nil
+	}
+	return []string{"--security-opt", "unmask=" + strings.Join(sandboxUnmaskPaths, ":")}
+}
+
+// SandboxExec runs a command in the bubblewrap sandbox of the running
+// container of the project in the current directory, for ai-shell
+// sandbox-exec.
+func SandboxExec(cfg *config.Config, profile string, command []string) error {
+	if len(command) == 0 {
+		return fmt.Errorf("no command to run")
+	}
+	info, err := CurrentProject(profile)
+	if err != nil {
+		return err
+	}
+	out, _ := exec.Command("podman", "container", "inspect", "-f", "{{.State.Running}}", info.ContainerName).Output() //nolint:gosec
+	if strings.TrimSpace(string(out)) != "true" {
+		return fmt.Errorf("ai-shell is not running for this project (no container %s)", info.ContainerName)
+	}
+
+	pwd, err := os.Getwd()
+	if err != nil {
+		return err
+	}
+	spec := newRunSpec(cfg, pwd)
+	args := []string{"exec", "-i", "--user", spec.User, "-w", spec.WorkDir}
+	if fi, err := os.Stdin.Stat(); err == nil && fi.Mode()&os.ModeCharDevice != 0 {
+		args = append(args, "-t")
+	}
+	if cfg != nil && cfg.Security.AtLeast(config.SecurityParanoid) {
+		args = append(args, "-e", "HOME="+config.HomeTarget())
+	}
+	args = append(args, info.ContainerName, sandboxExecPath)
+	return execPodman(append(args, command...)...)
+}
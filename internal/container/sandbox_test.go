@@ -0,0 +1,81 @@
+package container
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/arewm/ai-shell/internal/config"
+)
+
+func TestSandboxArgs(t *testing.T) {
+	if args := sandboxArgs(nil); args != nil {
+		t.Errorf("Expected no arguments without a config, Got: %v", args)
+	}
+	if args := sandboxArgs(&config.Config{}); args != nil {
+		t.Errorf("Expected no arguments without sandboxed agents, Got: %v", args)
+	}
+	args := sandboxArgs(&config.Config{Sandbox: config.Sandbox{Agents: []string{"claude"}}})
+	if len(args) != 2 || args[0] != "--security-opt" || !strings.HasPrefix(args[1], "unmask=/proc/acpi:") {
+		t.Fatalf("Expected the /proc masks to be lifted for bwrap, Got: %v", args)
+	}
+	for _, p := range strings.Split(strings.TrimPrefix(args[1], "unmask="), ":") {
+		if !strings.HasPrefix(p, "/proc/") || strings.Contains(p, "*") {
+			t.Errorf("Expected only named /proc paths to be unmasked, Got: %s", p)
+		}
+	}
+}
+
+func TestWriteSeccompProfileSandbox(t *testing.T) {
+	tmpDir, err := os.MkdirTemp("", "ai-shell-seccomp-test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() { _ = os.RemoveAll(tmpDir) }()
+
+	base := filepath.Join(tmpDir, "seccomp.json")
+	if err := os.WriteFile(base, []byte(`{"defaultAction": "SCMP_ACT_ALLOW"}`), 0644); err != nil {
+		t.Fatal(err)
+	}
+	orig := seccompBasePaths
+	defer func() { seccompBasePaths = orig }()
+	seccompBasePaths = []string{base}
+
+	blocked := func(cfg *config.Config) []string {
+		path, err := writeSeccompProfile(cfg)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer func() { _ = os.Remove(path) }()
+		data, err := os.ReadFile(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var p struct {
+			Syscalls []struct {
+				Names []string `json:"names"`
+			} `json:"syscalls"`
+		}
+		if err := json.Unmarshal(data, &p); err != nil {
+			t.Fatal(err)
+		}
+		return p.Syscalls[0].Names
+	}
+
+	if names := blocked(&config.Config{}); !slices.Contains(names, "mount") {
+		t.Errorf("Expected mount to be blocked, Got: %v", names)
+	}
+	// bwrap needs to mount in its own namespace
+	names := blocked(&config.Config{Sandbox: config.Sandbox{Agents: []string{"claude"}}})
+	for _, name := range sandboxSyscalls {
+		if slices.Contains(names, name) {
+			t.Errorf("Expected %s to be allowed for the sandbox, Got: %v", name, names)
+		}
+	}
+	if !slices.Contains(names, "ptrace") {
+		t.Errorf("Expected ptrace to stay blocked, Got: %v", names)
+	}
+}
@@ -0,0 +1,143 @@
+package container
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"slices"
+
+	"github.com/arewm/ai-shell/internal/assets"
+	"github.com/arewm/ai-shell/internal/config"
+)
+
+// seccompBasePaths are where containers-common installs podman's default
+// profile, which ai-shell's profile is built on.
+var seccompBasePaths = []string{"/etc/containers/seccomp.json", "/usr/share/containers/seccomp.json"}
+
+// blockedSyscalls returns the syscalls to block: the embedded list, adapted
+// by the config.
+func blockedSyscalls(s config.Seccomp) ([]string, error) {
+	data, err := assets.ReadFile("seccomp/ai-shell.json")
+	if err != nil {
+		return nil, err
+	}
+	var embedded struct {
+		Syscalls []struct {
+			Names []string `json:"names"`
+		} `json:"syscalls"`
+	}
+	if err := json.Unmarshal(data, &embedded); err != nil {
+		return nil, fmt.Errorf("invalid embedded seccomp profile: %w", err)
+	}
+
+	var blocked []string
+	for _, r := range embedded.Syscalls {
+		blocked = append(blocked, r.Names...)
+	}
+	for _, name := range s.Block {
+		if !slices.Contains(blocked, name) {
+			blocked = append(blocked, name)
+		}
+	}
+	return slices.DeleteFunc(blocked, func(name string) bool {
+		return slices.Contains(s.Allow, name)
+	}), nil
+}
+
+// seccompProfile builds ai-shell's profile from podman's: the blocked
+// syscalls are taken out of its allow rules, so its default action denies
+// them. In audit mode they are logged instead.
+func seccompProfile(base []byte, blocked []string, audit bool) ([]byte, error) {
+	var profile map[string]any
+	if err := json.Unmarshal(base, &profile); err != nil {
+		return nil, fmt.Errorf("invalid seccomp profile: %w", err)
+	}
+
+	rules, _ := profile["syscalls"].([]any)
+	kept := make([]any, 0, len(rules)+1)
+	for _, r := range rules {
+		rule, ok := r.(map[string]any)
+		if !ok || rule["action"] != "SCMP_ACT_ALLOW" {
+			kept = append(kept, r)
+			continue
+		}
+		names, _ := rule["names"].([]any)
+		names = slices.DeleteFunc(names, func(n any) bool {
+			name, _ := n.(string)
+			return slices.Contains(blocked, name)
+		})
+		if len(names) == 0 {
+			continue
+		}
+		rule["names"] = names
+		kept = append(kept, rule)
+	}
+
+	// An explicit rule is only needed where the default action does not deny
+	action := ""
+	switch {
+	case audit:
+		action = "SCMP_ACT_LOG"
+	case profile["defaultAction"] != "SCMP_ACT_ERRNO":
+		action = "SCMP_ACT_ERRNO"
+	}
+	if action != "" && len(blocked) > 0 {
+		rule := map[string]any{"names": blocked, "action": action, "comment": "ai-shell"}
+		if action == "SCMP_ACT_ERRNO" {
+			rule["errnoRet"] = 1 // EPERM
+		}
+		kept = append(kept, rule)
+	}
+	profile["syscalls"] = kept
+	return json.MarshalIndent(profile, "", "  ")
+}
+
+// writeSeccompProfile writes the profile for cfg to a temporary file, which
+// the caller removes. It returns "" if there is no base profile on the host,
+// e.g. with podman machine, where podman's default stays in place.
+func writeSeccompProfile(cfg *config.Config) (string, error) {
+	var s config.Seccomp
+	if cfg != nil {
+		s = cfg.Security.Seccomp
+	}
+	if s.Disable {
+		return "", nil
+	}
+	if cfg != nil && cfg.Sandbox.Enabled() {
+		s.Allow = slices.Concat(s.Allow, sandboxSyscalls)
+	}
+
+	var base []byte
+	for _, p := range seccompBasePaths {
+		if data, err := os.ReadFile(p); err == nil { //nolint:gosec
+			base = data
+			break
+		}
+	}
+	if base == nil {
+		return "", nil
+	}
+
+	blocked, err := blockedSyscalls(s)
+	if err != nil {
+		return "", err
+	}
+	profile, err := seccompProfile(base, blocked, s.Audit)
+	if err != nil {
+		return "", err
+	}
+
+	f, err := os.CreateTemp("", "ai-shell-seccomp-*.json")
+	if err != nil {
+		return "", err
+	}
+	_, err = f.Write(profile)
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		_ = os.Remove(f.Name())
+		return "", err
+	}
+	return f.Name(), nil
+}
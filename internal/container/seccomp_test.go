@@ -0,0 +1,93 @@
+package container
+
+import (
+	"encoding/json"
+	"slices"
+	"testing"
+
+	"github.com/arewm/ai-shell/internal/config"
+)
+
+func TestBlockedSyscalls(t *testing.T) {
+	blocked, err := blockedSyscalls(config.Seccomp{Block: []string{"io_uring_setup", "ptrace"}, Allow: []string{"perf_event_open"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, name := range []string{"ptrace", "mount", "keyctl", "bpf", "userfaultfd", "io_uring_setup"} {
+		if !slices.Contains(blocked, name) {
+			t.Errorf("Expected %s to be blocked, Got: %v", name, blocked)
+		}
+	}
+	if slices.Contains(blocked, "perf_event_open") {
+		t.Errorf("Expected allow to remove perf_event_open, Got: %v", blocked)
+	}
+	if n := len(slices.Compact(slices.Sorted(slices.Values(blocked)))); n != len(blocked) {
+		t.Errorf("Expected no duplicates, Got: %v", blocked)
+	}
+}
+
+func TestSeccompProfile(t *testing.T) {
+	base := `{
+		"defaultAction": "SCMP_ACT_ERRNO",
+		"defaultErrnoRet": 38,
+		"syscalls": [
+			{"names": ["read", "ptrace", "write"], "action": "SCMP_ACT_ALLOW"},
+			{"names": ["mount"], "action": "SCMP_ACT_ALLOW", "includes": {"caps": ["CAP_SYS_ADMIN"]}},
+			{"names": ["ptrace"], "action": "SCMP_ACT_ERRNO", "errnoRet": 1}
+		]
+	}`
+	blocked := []string{"ptrace", "mount"}
+
+	type rule struct {
+		Names  []string `json:"names"`
+		Action string   `json:"action"`
+	}
+	parse := func(data []byte) (string, []rule) {
+		var p struct {
+			DefaultAction string `json:"defaultAction"`
+			Syscalls      []rule `json:"syscalls"`
+		}
+		if err := json.Unmarshal(data, &p); err != nil {
+			t.Fatal(err)
+		}
+		return p.DefaultAction, p.Syscalls
+	}
+
+	data, err := seccompProfile([]byte(base), blocked, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	def, rules := parse(data)
+	if def != "SCMP_ACT_ERRNO" {
+		t.Errorf("Expected the default action to be kept, Got: %s", def)
+	}
+	// The default action denies them, other rules stay untouched
+	expected := []rule{
+		{Names: []string{"read", "write"}, Action: "SCMP_ACT_ALLOW"},
+		{Names: []string{"ptrace"}, Action: "SCMP_ACT_ERRNO"},
+	}
+	if !slices.EqualFunc(rules, expected, func(a, b rule) bool { return a.Action == b.Action && slices.Equal(a.Names, b.Names) }) {
+		t.Errorf("Expected: %+v, Got: %+v", expected, rules)
+	}
+
+	// Audit mode logs them instead
+	data, err = seccompProfile([]byte(base), blocked, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, rules = parse(data)
+	last := rules[len(rules)-1]
+	if last.Action != "SCMP_ACT_LOG" || !slices.Equal(last.Names, blocked) {
+		t.Errorf("Expected a log rule for the blocked syscalls, Got: %+v", last)
+	}
+
+	// A permissive base needs an explicit deny rule
+	data, err = seccompProfile([]byte(`{"defaultAction": "SCMP_ACT_ALLOW"}`), blocked, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, rules = parse(data)
+	if len(rules) != 1 || rules[0].Action != "SCMP_ACT_ERRNO" {
+		t.Errorf("Expected an explicit deny rule, Got: %+v", rules)
+	}
+}
@@ -0,0 +1,52 @@
+package container
+
+import (
+	"fmt"
+	"os/exec"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/arewm/ai-shell/internal/config"
+)
+
+// imageUID is the UID of the ai user in ai-shell's images, assumed when the
+// image cannot tell.
+const imageUID = 1000
+
+var idPattern = regexp.MustCompile(`uid=(\d+)\S* gid=(\d+)`)
+
+// userNSArg maps the host user into the container. With a read-only root,
+// usermod cannot adapt the user of the image to the host user, so the host
+// user is mapped to the user's UID and GID in image instead.
+func userNSArg(level, image, user string) string {
+	if !(config.Security{Level: level}).AtLeast(config.SecurityParanoid) {
+		return "--userns=keep-id"
+	}
+	uid, gid, err := imageUserIDs(image, user)
+	if err != nil {
+		fmt.Printf("⚠️  Read-only root: assuming user %s has UID %d: %v\n", user, imageUID, err)
+		uid, gid = imageUID, imageUID
+	}
+	return fmt.Sprintf("--userns=keep-id:uid=%d,gid=%d", uid, gid)
+}
+
+// imageUserIDs returns the UID and GID of user in image.
+func imageUserIDs(image, user string) (int, int, error) {
+	out, err := exec.Command("podman", "run", "--rm", "--user", "0:0", "--network", "none", "--entrypoint", "id", image, user).Output() //nolint:gosec
+	if err != nil {
+		return 0, 0, fmt.Errorf("failed to look up %s in %s: %w", user, image, err)
+	}
+	return parseID(string(out))
+}
+
+// parseID parses the output of id.
+func parseID(out string) (int, int, error) {
+	m := idPattern.FindStringSubmatch(out)
+	if m == nil {
+		return 0, 0, fmt.Errorf("unexpected output of id: %q", strings.TrimSpace(out))
+	}
+	uid, _ := strconv.Atoi(m[1])
+	gid, _ := strconv.Atoi(m[2])
+	return uid, gid, nil
+}
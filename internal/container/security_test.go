@@ -0,0 +1,34 @@
+package container
+
+import (
+	"testing"
+
+	"github.com/arewm/ai-shell/internal/config"
+)
+
+func TestUserNSArg(t *testing.T) {
+	if arg := userNSArg(config.SecurityStrict, "localhost/ai-shell-default:latest", "ai"); arg != "--userns=keep-id" {
+		t.Errorf("Expected plain keep-id, Got: %s", arg)
+	}
+}
+
+func TestParseID(t *testing.T) {
+	tests := []struct {
+		input    string
+		uid, gid int
+	}{
+		{"uid=1000(ai) gid=1000(ai) groups=1000(ai),10(wheel)\n", 1000, 1000},
+		// The image had its own user with UID 1000
+		{"uid=1001(ai) gid=1001(ai) groups=1001(ai)\n", 1001, 1001},
+		{"uid=1000 gid=100\n", 1000, 100},
+	}
+	for _, tt := range tests {
+		uid, gid, err := parseID(tt.input)
+		if err != nil || uid != tt.uid || gid != tt.gid {
+			t.Errorf("Input: %q, Expected: %d:%d, Got: %d:%d (%v)", tt.input, tt.uid, tt.gid, uid, gid, err)
+		}
+	}
+	if _, _, err := parseID("id: 'ai': no such user"); err == nil {
+		t.Error("Expected an error for an unknown user")
+	}
+}
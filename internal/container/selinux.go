@@ -0,0 +1,60 @@
+package container
+
+import (
+	"os"
+	"path/filepath"
+	"runtime"
+
+	"github.com/arewm/ai-shell/internal/config"
+)
+
+// selinuxEnforcePath exists when the host has SELinux enabled.
+var selinuxEnforcePath = "/sys/fs/selinux/enforce"
+
+// selinuxLabeling reports whether containers run with SELinux separation:
+// on Linux hosts with SELinux enabled, unless the config opts out. Elsewhere
+// label=disable is kept; with podman machine the mounts come from the host
+// and cannot be relabeled.
+func selinuxLabeling(cfg *config.Config) bool {
+	if runtime.GOOS != "linux" || (cfg != nil && cfg.Security.LabelDisable) {
+		return false
+	}
+	_, err := os.Stat(selinuxEnforcePath)
+	return err == nil
+}
+
+// relabel adds the SELinux relabel option to the options of a bind mount or
+// named volume: "z" for content other containers share, "Z" for content
+// private to this container.
+func relabel(opts string, labeling, private bool) string {
+	if !labeling {
+		return opts
+	}
+	flag := "z"
+	if private {
+		flag = "Z"
+	}
+	if opts == "" {
+		return flag
+	}
+	return opts + "," + flag
+}
+
+// volumeArg builds a -v value, leaving out empty options.
+func volumeArg(src, target, opts string) string {
+	if opts == "" {
+		return src + ":" + target
+	}
+	return src + ":" + target + ":" + opts
+}
+
+// unlabelablePath reports whether podman refuses to relabel path, or
+// relabeling it would break the host, e.g. sshd reading ~/.ssh.
+func unlabelablePath(path, home string) bool {
+	path = filepath.Clean(path)
+	switch path {
+	case "/", "/home", "/Users", filepath.Clean(home), filepath.Join(home, ".ssh"):
+		return true
+	}
+	return false
+}
@@ -0,0 +1,73 @@
+package container
+
+import (
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+
+	"github.com/arewm/ai-shell/internal/config"
+)
+
+func TestRelabel(t *testing.T) {
+	tests := []struct {
+		opts     string
+		labeling bool
+		private  bool
+		expected string
+	}{
+		{"ro", false, false, "ro"},
+		{"", true, false, "z"},
+		{"ro", true, false, "ro,z"},
+		{"", true, true, "Z"},
+	}
+	for _, tt := range tests {
+		if got := relabel(tt.opts, tt.labeling, tt.private); got != tt.expected {
+			t.Errorf("Input: %q (labeling %v, private %v), Expected: %q, Got: %q", tt.opts, tt.labeling, tt.private, tt.expected, got)
+		}
+	}
+	if got := volumeArg("vol", "/home/me", ""); got != "vol:/home/me" {
+		t.Errorf("Expected no trailing colon, Got: %s", got)
+	}
+}
+
+func TestSELinuxLabeling(t *testing.T) {
+	tmpDir, err := os.MkdirTemp("", "ai-shell-selinux-test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() { _ = os.RemoveAll(tmpDir) }()
+
+	orig := selinuxEnforcePath
+	defer func() { selinuxEnforcePath = orig }()
+
+	selinuxEnforcePath = filepath.Join(tmpDir, "missing")
+	if selinuxLabeling(nil) {
+		t.Error("Expected no labeling without SELinux")
+	}
+
+	selinuxEnforcePath = filepath.Join(tmpDir, "enforce")
+	if err := os.WriteFile(selinuxEnforcePath, []byte("1"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	if got := selinuxLabeling(nil); got != (runtime.GOOS == "linux") {
+		t.Errorf("Expected labeling on SELinux Linux hosts, Got: %v", got)
+	}
+	if selinuxLabeling(&config.Config{Security: config.Security{LabelDisable: true}}) {
+		t.Error("Expected label_disable to turn labeling off")
+	}
+}
+
+func TestUnlabelablePath(t *testing.T) {
+	home := "/home/me"
+	for _, p := range []string{"/", "/home", home, home + "/", home + "/.ssh"} {
+		if !unlabelablePath(p, home) {
+			t.Errorf("Input: %s, Expected: unlabelable", p)
+		}
+	}
+	for _, p := range []string{home + "/src/project", home + "/.claude"} {
+		if unlabelablePath(p, home) {
+			t.Errorf("Input: %s, Expected: labelable", p)
+		}
+	}
+}
@@ -0,0 +1,78 @@
+package container
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/arewm/ai-shell/internal/session"
+)
+
+// maxIdle caps the pauses when playing a recording.
+const maxIdle = 2 * time.Second
+
+// ListSessions lists the sessions of the project in the current directory,
+// for ai-shell sessions list.
+func ListSessions(w io.Writer, profile string) error {
+	info, err := CurrentProject(profile)
+	if err != nil {
+		return err
+	}
+	ids, err := session.List(info.ContainerName)
+	if err != nil {
+		return err
+	}
+	if len(ids) == 0 {
+		fmt.Fprintf(w, "No sessions recorded for %s.\n", info.ContainerName)
+		return nil
+	}
+	var sessions []session.Info
+	for _, id := range ids {
+		s, err := session.Describe(filepath.Join(session.ProjectDir(info.ContainerName), id))
+		if err != nil {
+			return err
+		}
+		sessions = append(sessions, s)
+	}
+	return session.WriteList(w, sessions)
+}
+
+// PlaySession replays the recording of a session of the project in the
+// current directory, for ai-shell sessions play. An empty id selects the
+// latest session.
+func PlaySession(w io.Writer, profile, id string, speed float64) error {
+	cast, err := sessionCast(profile, id)
+	if err != nil {
+		return err
+	}
+	return session.Play(w, cast, speed, maxIdle)
+}
+
+// ExportSession writes the recording of a session of the project in the
+// current directory in the given format (cast or txt), for ai-shell
+// sessions export.
+func ExportSession(w io.Writer, profile, id, format string) error {
+	cast, err := sessionCast(profile, id)
+	if err != nil {
+		return err
+	}
+	return session.Export(w, cast, format)
+}
+
+func sessionCast(profile, id string) (string, error) {
+	info, err := CurrentProject(profile)
+	if err != nil {
+		return "", err
+	}
+	dir, err := session.Resolve(info.ContainerName, id)
+	if err != nil {
+		return "", err
+	}
+	cast := filepath.Join(dir, session.CastFile)
+	if _, err := os.Stat(cast); err != nil {
+		return "", fmt.Errorf("session %s was not recorded", filepath.Base(dir))
+	}
+	return cast, nil
+}
@@ -0,0 +1,68 @@
+package container
+
+import (
+	"fmt"
+
+	"github.com/arewm/ai-shell/internal/config"
+)
+
+// defaultUser is the unprivileged user of ai-shell images.
+const defaultUser = "ai"
+
+// RunSpec is how the project is laid out in the container and who the shell
+// runs as. The default mirrors the host path of the project.
+type RunSpec struct {
+	// WorkspaceMount mounts the project; its source is checked like a config mount
+	WorkspaceMount config.Mount
+	// WorkDir is the working directory of the shell
+	WorkDir string
+	// User runs the shell, lifecycle commands and attached sessions
+	User string
+	// Resources limits the container
+	Resources config.Resources
+}
+
+// newRunSpec applies the workspace and user settings of cfg to the defaults
+// for the project in pwd. Settings that would weaken ai-shell's isolation are
+// ignored with a warning.
+func newRunSpec(cfg *config.Config, pwd string) RunSpec {
+	spec := RunSpec{
+		WorkspaceMount: config.Mount{Type: config.MountTypeBind, Source: pwd, Target: pwd, Options: "rw"},
+		WorkDir:        pwd,
+		User:           defaultUser,
+	}
+	if cfg == nil {
+		return spec
+	}
+
+	if cfg.WorkspaceMount != nil {
+		spec.WorkspaceMount = *cfg.WorkspaceMount
+		spec.WorkDir = cfg.WorkspaceMount.Target
+		// Unlike config mounts, the workspace is writable unless it says otherwise
+		if spec.WorkspaceMount.Options == "" {
+			spec.WorkspaceMount.Options = "rw"
+		}
+	} else if cfg.WorkspaceFolder != "" {
+		spec.WorkspaceMount.Target = cfg.WorkspaceFolder
+	}
+	if cfg.WorkspaceFolder != "" {
+		spec.WorkDir = cfg.WorkspaceFolder
+	}
+	spec.Resources = cfg.Resources
+
+	switch cfg.RemoteUser {
+	case "", defaultUser:
+	case "root", "0":
+		fmt.Printf("⚠️  Ignoring remoteUser %q: ai-shell does not run the shell as root, using '%s'.\n", cfg.RemoteUser, defaultUser)
+	default:
+		spec.User = cfg.RemoteUser
+	}
+
+	switch cfg.ContainerUser {
+	case "", "root", "0":
+	default:
+		fmt.Printf("⚠️  Ignoring containerUser %q: ai-shell's entrypoint starts as root and drops to '%s'.\n", cfg.ContainerUser, spec.User)
+	}
+
+	return spec
+}
@@ -0,0 +1,52 @@
+package container
+
+import (
+	"testing"
+
+	"github.com/arewm/ai-shell/internal/config"
+)
+
+func TestNewRunSpec(t *testing.T) {
+	pwd := "/home/me/project"
+
+	tests := []struct {
+		name    string
+		cfg     *config.Config
+		target  string
+		workDir string
+		user    string
+	}{
+		{"default", nil, pwd, pwd, "ai"},
+		{"folder", &config.Config{WorkspaceFolder: "/workspace"}, "/workspace", "/workspace", "ai"},
+		{"mount", &config.Config{WorkspaceMount: &config.Mount{Source: pwd, Target: "/src"}}, "/src", "/src", "ai"},
+		{"mount and folder", &config.Config{WorkspaceMount: &config.Mount{Source: "/home/me", Target: "/src"}, WorkspaceFolder: "/src/project"}, "/src", "/src/project", "ai"},
+		{"user", &config.Config{RemoteUser: "dev"}, pwd, pwd, "dev"},
+		{"root", &config.Config{RemoteUser: "root"}, pwd, pwd, "ai"},
+		{"container user", &config.Config{ContainerUser: "dev"}, pwd, pwd, "ai"},
+	}
+
+	for _, tt := range tests {
+		spec := newRunSpec(tt.cfg, pwd)
+		if spec.WorkspaceMount.Target != tt.target || spec.WorkDir != tt.workDir || spec.User != tt.user {
+			t.Errorf("Input: %s, Expected: %s %s %s, Got: %+v", tt.name, tt.target, tt.workDir, tt.user, spec)
+		}
+	}
+
+	// A configured workspace mount without options is writable, like the default
+	yes := true
+	options := []struct {
+		mount    config.Mount
+		expected string
+	}{
+		{config.Mount{Source: pwd, Target: "/src"}, "rw"},
+		{config.Mount{Source: pwd, Target: "/src", Options: "ro"}, "ro"},
+		{config.Mount{Source: pwd, Target: "/src", ReadOnly: &yes}, "ro"},
+	}
+	for _, tt := range options {
+		mount := tt.mount
+		spec := newRunSpec(&config.Config{WorkspaceMount: &mount}, pwd)
+		if got := spec.WorkspaceMount.ResolvedOptions("ro"); got != tt.expected {
+			t.Errorf("Input: %+v, Expected: %s, Got: %s", tt.mount, tt.expected, got)
+		}
+	}
+}
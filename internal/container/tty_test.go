@@ -0,0 +1,41 @@
+//go:build linux || darwin
+
+package container
+
+import (
+	"io"
+	"testing"
+)
+
+func TestOpenPTY(t *testing.T) {
+	master, slave, err := openPTY()
+	if err != nil {
+		t.Skipf("No pseudo-terminals here: %v", err)
+	}
+	defer func() { _ = master.Close() }()
+	defer func() { _ = slave.Close() }()
+
+	if !isTerminal(int(slave.Fd())) {
+		t.Error("Expected the slave to be a terminal")
+	}
+	if err := setTermSize(int(master.Fd()), 132, 43); err != nil {
+		t.Fatal(err)
+	}
+	if cols, rows, err := termSize(int(slave.Fd())); err != nil || cols != 132 || rows != 43 {
+		t.Errorf("Expected: 132x43, Got: %dx%d (%v)", cols, rows, err)
+	}
+
+	restore, err := makeRaw(int(slave.Fd()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer restore()
+	if _, err := slave.Write([]byte("hi\n")); err != nil {
+		t.Fatal(err)
+	}
+	// Raw mode: no \r added to the newline
+	buf := make([]byte, 3)
+	if _, err := io.ReadFull(master, buf); err != nil || string(buf) != "hi\n" {
+		t.Errorf("Expected: %q, Got: %q (%v)", "hi\n", buf, err)
+	}
+}
@@ -0,0 +1,236 @@
+package session
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"regexp"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+	"unicode/utf8"
+)
+
+// CastFile is the terminal recording of a session, in asciicast v2 format.
+const CastFile = "session.cast"
+
+// redacted replaces secrets in recordings.
+const redacted = "[REDACTED]"
+
+// CastHeader is the first line of an asciicast v2 file.
+type CastHeader struct {
+	Version   int               `json:"version"`
+	Width     int               `json:"width"`
+	Height    int               `json:"height"`
+	Timestamp int64             `json:"timestamp,omitempty"`
+	Title     string            `json:"title,omitempty"`
+	Env       map[string]string `json:"env,omitempty"`
+}
+
+// CastEvent is an output ("o") or resize ("r") event.
+type CastEvent struct {
+	Time float64
+	Type string
+	Data string
+}
+
+// CastWriter records a terminal session. Secrets are redacted from the
+// output; as one can be split across writes, the last bytes of each write
+// are held back until the next.
+type CastWriter struct {
+	w       io.Writer
+	start   time.Time
+	secrets []string
+	hold    int
+
+	mu      sync.Mutex
+	pending []byte
+	err     error
+}
+
+// NewCastWriter writes the header of a recording to w.
+func NewCastWriter(w io.Writer, header CastHeader, secrets []string) (*CastWriter, error) {
+	header.Version = 2
+	if header.Timestamp == 0 {
+		header.Timestamp = time.Now().Unix()
+	}
+	data, err := json.Marshal(header)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := w.Write(append(data, '\n')); err != nil {
+		return nil, err
+	}
+
+	// Longest first, so that a secret containing another is redacted whole
+	secrets = slices.DeleteFunc(slices.Clone(secrets), func(s string) bool { return s == "" })
+	slices.SortFunc(secrets, func(a, b string) int { return len(b) - len(a) })
+	c := &CastWriter{w: w, start: time.Now(), secrets: secrets}
+	if len(secrets) > 0 {
+		c.hold = len(secrets[0]) - 1
+	}
+	return c, nil
+}
+
+// Write records terminal output.
+func (c *CastWriter) Write(p []byte) (int, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.pending = append(c.pending, p...)
+	c.pending = c.redact(c.pending)
+
+	// Hold back what could be the start of a secret, on a rune boundary
+	n := len(c.pending) - c.hold
+	for n > 0 && n < len(c.pending) && !utf8.RuneStart(c.pending[n]) {
+		n--
+	}
+	if n > 0 {
+		c.event("o", string(c.pending[:n]))
+		c.pending = slices.Clone(c.pending[n:])
+	}
+	return len(p), c.err
+}
+
+// Resize records a change of the terminal size. The output held back stays
+// pending, as a secret can be split around a resize too.
+func (c *CastWriter) Resize(width, height int) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.event("r", fmt.Sprintf("%dx%d", width, height))
+}
+
+// Close records the output held back.
+func (c *CastWriter) Close() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.flush()
+	return c.err
+}
+
+func (c *CastWriter) flush() {
+	if len(c.pending) > 0 {
+		c.event("o", string(c.pending))
+		c.pending = nil
+	}
+}
+
+func (c *CastWriter) redact(p []byte) []byte {
+	for _, s := range c.secrets {
+		p = []byte(strings.ReplaceAll(string(p), s, redacted))
+	}
+	return p
+}
+
+func (c *CastWriter) event(typ, data string) {
+	if c.err != nil {
+		return
+	}
+	line, err := json.Marshal([]any{time.Since(c.start).Seconds(), typ, data})
+	if err == nil {
+		_, err = c.w.Write(append(line, '\n'))
+	}
+	c.err = err
+}
+
+// ReadCast parses an asciicast v2 recording.
+func ReadCast(r io.Reader) (CastHeader, []CastEvent, error) {
+	var header CastHeader
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), 16*1024*1024)
+	if !scanner.Scan() {
+		return header, nil, fmt.Errorf("empty recording")
+	}
+	if err := json.Unmarshal(scanner.Bytes(), &header); err != nil || header.Version != 2 {
+		return header, nil, fmt.Errorf("not an asciicast v2 recording")
+	}
+
+	var events []CastEvent
+	for scanner.Scan() {
+		var raw []any
+		if err := json.Unmarshal(scanner.Bytes(), &raw); err != nil || len(raw) != 3 {
+			continue
+		}
+		t, _ := raw[0].(float64)
+		typ, _ := raw[1].(string)
+		data, _ := raw[2].(string)
+		events = append(events, CastEvent{Time: t, Type: typ, Data: data})
+	}
+	return header, events, scanner.Err()
+}
+
+// Play writes the output of a recording to w in its own time, with pauses
+// capped at maxIdle and sped up by speed.
+func Play(w io.Writer, path string, speed float64, maxIdle time.Duration) error {
+	f, err := os.Open(path) //nolint:gosec
+	if err != nil {
+		return err
+	}
+	defer func() { _ = f.Close() }()
+	_, events, err := ReadCast(f)
+	if err != nil {
+		return err
+	}
+	if speed <= 0 {
+		speed = 1
+	}
+
+	last := 0.0
+	for _, e := range events {
+		if e.Type != "o" {
+			continue
+		}
+		wait := time.Duration((e.Time - last) / speed * float64(time.Second))
+		if maxIdle > 0 && wait > maxIdle {
+			wait = maxIdle
+		}
+		time.Sleep(wait)
+		last = e.Time
+		if _, err := io.WriteString(w, e.Data); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// ansiPattern matches terminal escape sequences: CSI, OSC and two-byte ones.
+var ansiPattern = regexp.MustCompile(`\x1b\[[0-?]*[ -/]*[@-~]|\x1b\][^\x07\x1b]*(\x07|\x1b\\)|\x1b[@-Z\\-_]`)
+
+// Export formats
+const (
+	ExportCast = "cast" // the asciicast file itself
+	ExportText = "txt"  // the output without escape sequences
+)
+
+// Export writes a recording to w in the given format.
+func Export(w io.Writer, path, format string) error {
+	f, err := os.Open(path) //nolint:gosec
+	if err != nil {
+		return err
+	}
+	defer func() { _ = f.Close() }()
+
+	switch format {
+	case "", ExportCast:
+		_, err = io.Copy(w, f)
+		return err
+	case ExportText:
+		_, events, err := ReadCast(f)
+		if err != nil {
+			return err
+		}
+		var out strings.Builder
+		for _, e := range events {
+			if e.Type == "o" {
+				out.WriteString(e.Data)
+			}
+		}
+		text := ansiPattern.ReplaceAllString(out.String(), "")
+		text = strings.ReplaceAll(text, "\r\n", "\n")
+		_, err = io.WriteString(w, text)
+		return err
+	}
+	return fmt.Errorf("unknown export format %q, expected %s or %s", format, ExportCast, ExportText)
+}
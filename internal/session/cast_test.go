@@ -0,0 +1,135 @@
+package session
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestCastWriter(t *testing.T) {
+	var buf bytes.Buffer
+	cast, err := NewCastWriter(&buf, CastHeader{Width: 80, Height: 24}, []string{"ghp_secret123", "tok"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	// The secret is split across writes
+	for _, chunk := range []string{"export X=ghp_sec", "ret123\r\n", "héllo ", "tok"} {
+		if _, err := cast.Write([]byte(chunk)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	cast.Resize(120, 40)
+	if _, err := cast.Write([]byte("done\r\n")); err != nil {
+		t.Fatal(err)
+	}
+	if err := cast.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	if strings.Contains(buf.String(), "ghp_sec") {
+		t.Errorf("Expected the secret to be redacted, Got: %s", buf.String())
+	}
+
+	header, events, err := ReadCast(&buf)
+	if err != nil {
+		t.Fatalf("ReadCast failed: %v", err)
+	}
+	if header.Version != 2 || header.Width != 80 || header.Height != 24 || header.Timestamp == 0 {
+		t.Errorf("Unexpected header: %+v", header)
+	}
+	var out strings.Builder
+	resized := false
+	for _, e := range events {
+		switch e.Type {
+		case "o":
+			out.WriteString(e.Data)
+		case "r":
+			resized = e.Data == "120x40"
+		}
+	}
+	expected := "export X=[REDACTED]\r\nhéllo [REDACTED]done\r\n"
+	if out.String() != expected {
+		t.Errorf("Expected: %q, Got: %q", expected, out.String())
+	}
+	if !resized {
+		t.Errorf("Expected a resize event, Got: %+v", events)
+	}
+}
+
+func TestCastWriterResizeMidSecret(t *testing.T) {
+	var buf bytes.Buffer
+	cast, err := NewCastWriter(&buf, CastHeader{Width: 80, Height: 24}, []string{"ghp_secret123"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := cast.Write([]byte("token: ghp_sec")); err != nil {
+		t.Fatal(err)
+	}
+	cast.Resize(120, 40)
+	if _, err := cast.Write([]byte("ret123\r\n")); err != nil {
+		t.Fatal(err)
+	}
+	if err := cast.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	if strings.Contains(buf.String(), "ghp_sec") {
+		t.Errorf("Expected the secret to be redacted across the resize, Got: %s", buf.String())
+	}
+	_, events, err := ReadCast(&buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var out strings.Builder
+	for _, e := range events {
+		if e.Type == "o" {
+			out.WriteString(e.Data)
+		}
+	}
+	if out.String() != "token: [REDACTED]\r\n" {
+		t.Errorf("Expected: %q, Got: %q", "token: [REDACTED]\r\n", out.String())
+	}
+}
+
+func TestExport(t *testing.T) {
+	tmpDir := t.TempDir()
+	path := filepath.Join(tmpDir, CastFile)
+	data := `{"version": 2, "width": 80, "height": 24}
+[0.1, "o", "\u001b[1;32m$\u001b[0m ls\r\n"]
+[0.5, "r", "100x30"]
+[0.7, "o", "\u001b]0;title\u0007README.md\r\n"]
+`
+	if err := os.WriteFile(path, []byte(data), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	var text strings.Builder
+	if err := Export(&text, path, ExportText); err != nil {
+		t.Fatalf("Export failed: %v", err)
+	}
+	if text.String() != "$ ls\nREADME.md\n" {
+		t.Errorf("Expected the output without escape sequences, Got: %q", text.String())
+	}
+
+	var cast strings.Builder
+	if err := Export(&cast, path, ExportCast); err != nil {
+		t.Fatalf("Export failed: %v", err)
+	}
+	if cast.String() != data {
+		t.Error("Expected the cast export to be the recording itself")
+	}
+
+	if err := Export(&cast, path, "gif"); err == nil {
+		t.Error("Expected an error for an unknown format")
+	}
+
+	var played strings.Builder
+	if err := Play(&played, path, 100, 0); err != nil {
+		t.Fatalf("Play failed: %v", err)
+	}
+	if !strings.Contains(played.String(), "README.md") {
+		t.Errorf("Expected the output to be played, Got: %q", played.String())
+	}
+}
@@ -0,0 +1,245 @@
+package session
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"net"
+	"net/url"
+	"os"
+	"path/filepath"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+)
+
+// Network log files of a session
+const (
+	DNSLog        = "dns.log"         // dnsmasq with log-queries
+	ConnectionLog = "connections.log" // tcpdump of outbound connection attempts
+	ProxyLog      = "proxy.log"       // squid access log, when egress is filtered
+)
+
+// HostSummary is what a session did with one host.
+type HostSummary struct {
+	Host        string
+	Queries     int
+	Connections int
+	Denied      int
+	Ports       []int
+}
+
+// Connection is an outbound connection attempt.
+type Connection struct {
+	Proto string
+	Addr  string
+	Port  int
+}
+
+// SummarizeNetwork reads the network logs in dir and groups them by host.
+// Connections are attributed to the name the address was resolved from,
+// if any.
+func SummarizeNetwork(dir string) ([]HostSummary, error) {
+	hosts := make(map[string]*HostSummary)
+	get := func(name string) *HostSummary {
+		h, ok := hosts[name]
+		if !ok {
+			h = &HostSummary{Host: name}
+			hosts[name] = h
+		}
+		return h
+	}
+	addPort := func(h *HostSummary, port int) {
+		if port > 0 && !slices.Contains(h.Ports, port) {
+			h.Ports = append(h.Ports, port)
+		}
+	}
+
+	found := false
+	names := make(map[string]string)
+	if f, err := os.Open(filepath.Join(dir, DNSLog)); err == nil { //nolint:gosec
+		found = true
+		queries, resolved, err := ParseDNSLog(f)
+		_ = f.Close()
+		if err != nil {
+			return nil, err
+		}
+		for name, n := range queries {
+			get(name).Queries += n
+		}
+		names = resolved
+	}
+
+	if f, err := os.Open(filepath.Join(dir, ConnectionLog)); err == nil { //nolint:gosec
+		found = true
+		conns, err := ParseConnectionLog(f)
+		_ = f.Close()
+		if err != nil {
+			return nil, err
+		}
+		for _, c := range conns {
+			name := c.Addr
+			if n, ok := names[c.Addr]; ok {
+				name = n
+			}
+			h := get(name)
+			h.Connections++
+			addPort(h, c.Port)
+		}
+	}
+
+	if f, err := os.Open(filepath.Join(dir, ProxyLog)); err == nil { //nolint:gosec
+		found = true
+		reqs, err := ParseProxyLog(f)
+		_ = f.Close()
+		if err != nil {
+			return nil, err
+		}
+		for _, r := range reqs {
+			h := get(r.Host)
+			h.Connections++
+			if r.Denied {
+				h.Denied++
+			}
+			addPort(h, r.Port)
+		}
+	}
+
+	if !found {
+		return nil, fmt.Errorf("no network logs in %s; enable network.audit to record them", dir)
+	}
+
+	summary := make([]HostSummary, 0, len(hosts))
+	for _, h := range hosts {
+		sort.Ints(h.Ports)
+		summary = append(summary, *h)
+	}
+	sort.Slice(summary, func(i, j int) bool {
+		a, b := summary[i], summary[j]
+		if a.Connections+a.Queries != b.Connections+b.Queries {
+			return a.Connections+a.Queries > b.Connections+b.Queries
+		}
+		return a.Host < b.Host
+	})
+	return summary, nil
+}
+
+// WriteNetSummary prints the summary as a table, for ai-shell net-log.
+func WriteNetSummary(w io.Writer, summary []HostSummary) error {
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "HOST\tQUERIES\tCONNECTIONS\tDENIED\tPORTS")
+	for _, h := range summary {
+		ports := make([]string, len(h.Ports))
+		for i, p := range h.Ports {
+			ports[i] = strconv.Itoa(p)
+		}
+		fmt.Fprintf(tw, "%s\t%d\t%d\t%d\t%s\n", h.Host, h.Queries, h.Connections, h.Denied, strings.Join(ports, ","))
+	}
+	return tw.Flush()
+}
+
+// ParseDNSLog counts the queries per name in a dnsmasq log and maps the
+// addresses in the replies back to the names they were resolved from.
+func ParseDNSLog(r io.Reader) (map[string]int, map[string]string, error) {
+	queries := make(map[string]int)
+	names := make(map[string]string)
+
+	s := bufio.NewScanner(r)
+	for s.Scan() {
+		// Oct 18 15:30:00 dnsmasq[7]: query[A] github.com from 127.0.0.1
+		_, msg, ok := strings.Cut(s.Text(), "]: ")
+		if !ok {
+			continue
+		}
+		fields := strings.Fields(msg)
+		switch {
+		case len(fields) >= 2 && strings.HasPrefix(fields[0], "query["):
+			queries[strings.ToLower(fields[1])]++
+		case len(fields) == 4 && (fields[0] == "reply" || fields[0] == "cached") && fields[2] == "is":
+			if net.ParseIP(fields[3]) != nil {
+				names[fields[3]] = strings.ToLower(fields[1])
+			}
+		}
+	}
+	return queries, names, s.Err()
+}
+
+// ParseConnectionLog reads the outbound connection attempts from tcpdump
+// output (-n -tttt): TCP SYNs and UDP datagrams.
+func ParseConnectionLog(r io.Reader) ([]Connection, error) {
+	var conns []Connection
+	s := bufio.NewScanner(r)
+	for s.Scan() {
+		// 2026-10-18 15:30:00.123456 eth0 Out IP 10.88.0.5.43210 > 140.82.112.3.443: Flags [S], ...
+		line := s.Text()
+		if strings.Contains(line, " In ") {
+			continue
+		}
+		_, rest, ok := strings.Cut(line, " > ")
+		if !ok {
+			continue
+		}
+		dst, detail, ok := strings.Cut(rest, ": ")
+		if !ok {
+			continue
+		}
+		i := strings.LastIndex(dst, ".")
+		if i < 0 {
+			continue
+		}
+		port, err := strconv.Atoi(dst[i+1:])
+		if err != nil || net.ParseIP(dst[:i]) == nil {
+			continue
+		}
+		proto := "tcp"
+		if strings.HasPrefix(detail, "UDP") {
+			proto = "udp"
+		}
+		conns = append(conns, Connection{Proto: proto, Addr: dst[:i], Port: port})
+	}
+	return conns, s.Err()
+}
+
+// ProxyRequest is a request through the egress proxy.
+type ProxyRequest struct {
+	Host   string
+	Port   int
+	Denied bool
+}
+
+// ParseProxyLog reads squid's native access log, as written by podman's
+// k8s-file log driver.
+func ParseProxyLog(r io.Reader) ([]ProxyRequest, error) {
+	var reqs []ProxyRequest
+	s := bufio.NewScanner(r)
+	for s.Scan() {
+		// <time> stdout F 1697640000.123 45 10.89.0.3 TCP_TUNNEL/200 3900 CONNECT github.com:443 - HIER_DIRECT/140.82.112.3 -
+		fields := strings.Fields(s.Text())
+		if len(fields) > 3 && (fields[1] == "stdout" || fields[1] == "stderr") {
+			fields = fields[3:]
+		}
+		if len(fields) < 7 {
+			continue
+		}
+		req := ProxyRequest{Denied: strings.Contains(fields[3], "DENIED")}
+		host, port := fields[6], ""
+		if fields[5] == "CONNECT" {
+			if h, p, err := net.SplitHostPort(host); err == nil {
+				host, port = h, p
+			}
+		} else if u, err := url.Parse(host); err == nil && u.Host != "" {
+			host, port = u.Hostname(), u.Port()
+			if port == "" && u.Scheme == "http" {
+				port = "80"
+			}
+		} else {
+			continue
+		}
+		req.Host = strings.ToLower(host)
+		req.Port, _ = strconv.Atoi(port)
+		reqs = append(reqs, req)
+	}
+	return reqs, s.Err()
+}
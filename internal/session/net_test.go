@@ -0,0 +1,119 @@
+package session
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+const dnsLog = `Oct 18 15:30:00 dnsmasq[7]: started, version 2.90 cachesize 150
+Oct 18 15:30:01 dnsmasq[7]: query[A] GitHub.com from 127.0.0.1
+Oct 18 15:30:01 dnsmasq[7]: forwarded github.com to 10.89.0.1
+Oct 18 15:30:01 dnsmasq[7]: reply github.com is 140.82.112.3
+Oct 18 15:30:05 dnsmasq[7]: query[AAAA] github.com from 127.0.0.1
+Oct 18 15:30:05 dnsmasq[7]: cached github.com is NODATA-IPv6
+Oct 18 15:30:09 dnsmasq[7]: query[A] pypi.org from 127.0.0.1
+Oct 18 15:30:09 dnsmasq[7]: reply pypi.org is 151.101.0.223
+`
+
+const connectionLog = `2026-10-18 15:30:01.123456 eth0 Out IP 10.88.0.5.43210 > 140.82.112.3.443: Flags [S], seq 1, win 64240, length 0
+2026-10-18 15:30:02.123456 eth0 Out IP 10.88.0.5.43212 > 140.82.112.3.22: Flags [S], seq 1, win 64240, length 0
+2026-10-18 15:30:03.123456 eth0 Out IP 10.88.0.5.5353 > 192.0.2.10.123: UDP, length 48
+2026-10-18 15:30:04.123456 eth0 In  IP 192.0.2.10.123 > 10.88.0.5.5353: UDP, length 48
+tcpdump: listening on any
+`
+
+const proxyLog = `2026-10-18T15:30:01.000000000+00:00 stdout F 1697640000.123 45 10.89.0.3 TCP_TUNNEL/200 3900 CONNECT github.com:443 - HIER_DIRECT/140.82.112.3 -
+2026-10-18T15:30:02.000000000+00:00 stdout F 1697640001.123 0 10.89.0.3 TCP_DENIED/403 3900 CONNECT evil.example:443 - HIER_NONE/- text/html
+2026-10-18T15:30:03.000000000+00:00 stdout F 1697640002.123 10 10.89.0.3 TCP_MISS/200 512 GET http://Example.com/index.html - HIER_DIRECT/192.0.2.1 text/html
+`
+
+func TestParseDNSLog(t *testing.T) {
+	queries, names, err := ParseDNSLog(strings.NewReader(dnsLog))
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := map[string]int{"github.com": 2, "pypi.org": 1}
+	if !reflect.DeepEqual(queries, expected) {
+		t.Errorf("Expected: %v, Got: %v", expected, queries)
+	}
+	expectedNames := map[string]string{"140.82.112.3": "github.com", "151.101.0.223": "pypi.org"}
+	if !reflect.DeepEqual(names, expectedNames) {
+		t.Errorf("Expected: %v, Got: %v", expectedNames, names)
+	}
+}
+
+func TestParseConnectionLog(t *testing.T) {
+	conns, err := ParseConnectionLog(strings.NewReader(connectionLog))
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := []Connection{
+		{Proto: "tcp", Addr: "140.82.112.3", Port: 443},
+		{Proto: "tcp", Addr: "140.82.112.3", Port: 22},
+		{Proto: "udp", Addr: "192.0.2.10", Port: 123},
+	}
+	if !reflect.DeepEqual(conns, expected) {
+		t.Errorf("Expected: %v, Got: %v", expected, conns)
+	}
+}
+
+func TestParseProxyLog(t *testing.T) {
+	reqs, err := ParseProxyLog(strings.NewReader(proxyLog))
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := []ProxyRequest{
+		{Host: "github.com", Port: 443},
+		{Host: "evil.example", Port: 443, Denied: true},
+		{Host: "example.com", Port: 80},
+	}
+	if !reflect.DeepEqual(reqs, expected) {
+		t.Errorf("Expected: %v, Got: %v", expected, reqs)
+	}
+}
+
+func TestSummarizeNetwork(t *testing.T) {
+	tmpDir, err := os.MkdirTemp("", "ai-shell-session-test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() { _ = os.RemoveAll(tmpDir) }()
+
+	if _, err := SummarizeNetwork(tmpDir); err == nil {
+		t.Error("Expected an error without logs")
+	}
+
+	for name, data := range map[string]string{DNSLog: dnsLog, ConnectionLog: connectionLog, ProxyLog: proxyLog} {
+		if err := os.WriteFile(filepath.Join(tmpDir, name), []byte(data), 0600); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	summary, err := SummarizeNetwork(tmpDir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := []HostSummary{
+		// Connections to resolved addresses count for the name
+		{Host: "github.com", Queries: 2, Connections: 3, Ports: []int{22, 443}},
+		{Host: "192.0.2.10", Connections: 1, Ports: []int{123}},
+		{Host: "evil.example", Connections: 1, Denied: 1, Ports: []int{443}},
+		{Host: "example.com", Connections: 1, Ports: []int{80}},
+		{Host: "pypi.org", Queries: 1},
+	}
+	if !reflect.DeepEqual(summary, expected) {
+		t.Errorf("Expected: %+v, Got: %+v", expected, summary)
+	}
+
+	var out bytes.Buffer
+	if err := WriteNetSummary(&out, summary); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(out.String(), "github.com") || !strings.Contains(out.String(), "22,443") {
+		t.Errorf("Unexpected table:\n%s", out.String())
+	}
+}
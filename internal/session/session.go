@@ -0,0 +1,141 @@
+// Package session manages the per-session logs ai-shell records on the
+// host, below ~/.local/share/ai-shell/sessions/<project>/<session>/.
+package session
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"text/tabwriter"
+	"time"
+)
+
+// idFormat names session directories so that they sort by start time.
+const idFormat = "20060102-150405"
+
+// Root is the directory holding the sessions of all projects.
+func Root() string {
+	home, _ := os.UserHomeDir()
+	return filepath.Join(home, ".local", "share", "ai-shell", "sessions")
+}
+
+// ProjectDir is the directory holding the sessions of a project, named after
+// its container.
+func ProjectDir(project string) string {
+	return filepath.Join(Root(), project)
+}
+
+// New creates the directory of a new session and returns it.
+func New(project string) (string, error) {
+	id := time.Now().Format(idFormat)
+	dir := filepath.Join(ProjectDir(project), id)
+	// Two sessions in the same second get a suffix
+	for i := 2; ; i++ {
+		if _, err := os.Stat(dir); os.IsNotExist(err) {
+			break
+		}
+		dir = filepath.Join(ProjectDir(project), fmt.Sprintf("%s-%d", id, i))
+	}
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return "", fmt.Errorf("failed to create session directory: %w", err)
+	}
+	return dir, nil
+}
+
+// List returns the session IDs of a project, oldest first. Hidden
+// directories hold state of the project, not sessions.
+func List(project string) ([]string, error) {
+	entries, err := os.ReadDir(ProjectDir(project))
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var ids []string
+	for _, e := range entries {
+		if e.IsDir() && !strings.HasPrefix(e.Name(), ".") {
+			ids = append(ids, e.Name())
+		}
+	}
+	sort.Strings(ids)
+	return ids, nil
+}
+
+// Resolve returns the directory of a session; an empty id selects the latest.
+func Resolve(project, id string) (string, error) {
+	if id == "" {
+		ids, err := List(project)
+		if err != nil {
+			return "", err
+		}
+		if len(ids) == 0 {
+			return "", fmt.Errorf("no sessions recorded for %s", project)
+		}
+		id = ids[len(ids)-1]
+	}
+	dir := filepath.Join(ProjectDir(project), id)
+	if _, err := os.Stat(dir); err != nil {
+		return "", fmt.Errorf("session %s not found for %s", id, project)
+	}
+	return dir, nil
+}
+
+// Info describes a session.
+type Info struct {
+	ID    string
+	Start time.Time
+	// Duration is the length of the recording, zero without one
+	Duration time.Duration
+	// Files are the logs and the recording of the session
+	Files []string
+}
+
+// Describe returns what a session directory holds.
+func Describe(dir string) (Info, error) {
+	info := Info{ID: filepath.Base(dir)}
+	// Without the suffix of sessions started in the same second
+	if len(info.ID) >= len(idFormat) {
+		info.Start, _ = time.ParseInLocation(idFormat, info.ID[:len(idFormat)], time.Local)
+	}
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return info, err
+	}
+	for _, e := range entries {
+		info.Files = append(info.Files, e.Name())
+	}
+
+	f, err := os.Open(filepath.Join(dir, CastFile)) //nolint:gosec
+	if os.IsNotExist(err) {
+		return info, nil
+	}
+	if err != nil {
+		return info, err
+	}
+	defer func() { _ = f.Close() }()
+	if _, events, err := ReadCast(f); err == nil && len(events) > 0 {
+		info.Duration = time.Duration(events[len(events)-1].Time * float64(time.Second)).Round(time.Second)
+	}
+	return info, nil
+}
+
+// WriteList prints sessions as a table, for ai-shell sessions list.
+func WriteList(w io.Writer, sessions []Info) error {
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "SESSION\tSTARTED\tDURATION\tFILES")
+	for _, s := range sessions {
+		started, duration := "-", "-"
+		if !s.Start.IsZero() {
+			started = s.Start.Format("2006-01-02 15:04:05")
+		}
+		if s.Duration > 0 {
+			duration = s.Duration.String()
+		}
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%s\n", s.ID, started, duration, strings.Join(s.Files, ","))
+	}
+	return tw.Flush()
+}
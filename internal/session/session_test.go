@@ -0,0 +1,89 @@
+package session
+
+import (
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+	"time"
+)
+
+func TestNewAndResolve(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+
+	if _, err := Resolve("proj", ""); err == nil {
+		t.Error("Expected an error without sessions")
+	}
+
+	first, err := New("proj")
+	if err != nil {
+		t.Fatal(err)
+	}
+	second, err := New("proj")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if first == second {
+		t.Fatalf("Expected distinct sessions, Got: %s twice", first)
+	}
+
+	// Hidden directories are not sessions
+	if err := os.MkdirAll(filepath.Join(ProjectDir("proj"), ".spool"), 0700); err != nil {
+		t.Fatal(err)
+	}
+
+	ids, err := List("proj")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(ids) != 2 {
+		t.Fatalf("Expected 2 sessions, Got: %v", ids)
+	}
+
+	latest, err := Resolve("proj", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if latest != second {
+		t.Errorf("Expected latest: %s, Got: %s", second, latest)
+	}
+	if dir, err := Resolve("proj", filepath.Base(first)); err != nil || dir != first {
+		t.Errorf("Expected: %s, Got: %s (%v)", first, dir, err)
+	}
+	if _, err := Resolve("proj", "missing"); err == nil {
+		t.Error("Expected an error for an unknown session")
+	}
+}
+
+func TestDescribe(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+
+	dir, err := New("proj")
+	if err != nil {
+		t.Fatal(err)
+	}
+	cast := `{"version": 2, "width": 80, "height": 24}
+[1.5, "o", "$ "]
+[61.2, "o", "exit\r\n"]
+`
+	if err := os.WriteFile(filepath.Join(dir, CastFile), []byte(cast), 0600); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, DNSLog), nil, 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	info, err := Describe(dir)
+	if err != nil {
+		t.Fatalf("Describe failed: %v", err)
+	}
+	if info.ID != filepath.Base(dir) || info.Start.IsZero() {
+		t.Errorf("Unexpected session: %+v", info)
+	}
+	if info.Duration != 61*time.Second {
+		t.Errorf("Expected: 1m1s, Got: %s", info.Duration)
+	}
+	if !slices.Equal(info.Files, []string{DNSLog, CastFile}) {
+		t.Errorf("Unexpected files: %v", info.Files)
+	}
+}